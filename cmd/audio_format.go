@@ -0,0 +1,211 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/mp3"
+	"github.com/gopxl/beep/v2/wav"
+	"github.com/openai/openai-go"
+)
+
+// Saved audio formats accepted by --save-format and the per-call format argument.
+// An empty format keeps the provider's native output.
+const (
+	FormatAIFF = "aiff"
+	FormatWAV  = "wav"
+	FormatMP3  = "mp3"
+	FormatOpus = "opus"
+	FormatFLAC = "flac"
+	FormatAAC  = "aac"
+	FormatPCM  = "pcm"
+)
+
+// SaveFormats lists every format a provider can be asked to save.
+var SaveFormats = []string{FormatWAV, FormatMP3, FormatOpus, FormatFLAC, FormatAAC, FormatPCM, FormatAIFF}
+
+// providerSaveFormats lists the formats each provider can produce, either
+// natively or by decoding and re-encoding as WAV.
+var providerSaveFormats = map[string][]string{
+	ProviderSay:        {FormatAIFF, FormatWAV},
+	ProviderElevenLabs: {FormatMP3, FormatWAV, FormatPCM, FormatOpus},
+	ProviderGoogle:     {FormatWAV, FormatPCM},
+	ProviderOpenAI:     {FormatMP3, FormatWAV, FormatOpus, FormatFLAC, FormatAAC, FormatPCM},
+}
+
+// requestedFormatProviders ask their API for the saved format, so the audio
+// they play is in that format too. The others always receive their native
+// audio and convert only the saved copy.
+var requestedFormatProviders = []string{ProviderElevenLabs, ProviderOpenAI}
+
+// resolveSaveFormat returns the per-call format if set, otherwise --save-format.
+func resolveSaveFormat(requested *string) (string, error) {
+	format := saveFormat
+	if requested != nil && *requested != "" {
+		format = *requested
+	}
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format != "" && !slices.Contains(SaveFormats, format) {
+		return "", fmt.Errorf("unsupported audio format %q (supported: %s)", format, strings.Join(SaveFormats, ", "))
+	}
+	return format, nil
+}
+
+// isPlayableFormat reports whether audio saved in format can also be played.
+// Archive formats are requested natively from the provider and are never decoded.
+func isPlayableFormat(format string) bool {
+	switch format {
+	case "", FormatWAV, FormatMP3, FormatAIFF:
+		return true
+	default:
+		return false
+	}
+}
+
+// checkSaveFormat validates that providerID can save format under the current
+// play/save settings.
+func checkSaveFormat(providerID, format string) error {
+	if format == "" {
+		return nil
+	}
+	supported := providerSaveFormats[providerID]
+	if !slices.Contains(supported, format) {
+		return fmt.Errorf("%s cannot save %s audio (supported: %s)", providerID, format, strings.Join(supported, ", "))
+	}
+	if shouldPlay() && slices.Contains(requestedFormatProviders, providerID) && !isPlayableFormat(format) {
+		return fmt.Errorf("%s audio cannot be played locally; use --no-play to only save it", format)
+	}
+	return nil
+}
+
+// elevenLabsOutputFormat maps a save format to the ElevenLabs output_format query value.
+func elevenLabsOutputFormat(format string) string {
+	switch format {
+	case FormatPCM:
+		return "pcm_24000"
+	case FormatOpus:
+		return "opus_48000_128"
+	default:
+		return "mp3_44100_128"
+	}
+}
+
+// openAIResponseFormat maps a save format to the OpenAI response_format value.
+// WAV is transcoded locally from MP3 so playback keeps using the MP3 decoder.
+func openAIResponseFormat(format string) openai.AudioSpeechNewParamsResponseFormat {
+	switch format {
+	case FormatOpus, FormatFLAC, FormatAAC, FormatPCM:
+		return openai.AudioSpeechNewParamsResponseFormat(format)
+	default:
+		return openai.AudioSpeechNewParamsResponseFormatMP3
+	}
+}
+
+// saveAudio writes already-encoded audio data to the output directory.
+// Returns the full path to the saved file.
+func saveAudio(data []byte, ext, text string) (string, error) {
+	if !shouldSave() {
+		return "", nil
+	}
+	filename := generateFilename(text, ext)
+	fpath := filepath.Join(outputDir, filename)
+	if err := os.WriteFile(fpath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to save %s file: %w", strings.ToUpper(ext), err)
+	}
+	return fpath, nil
+}
+
+// saveStreamWAV encodes a decoded beep stream as 16-bit WAV in the output directory.
+func saveStreamWAV(streamer beep.Streamer, format beep.Format, text string) (string, error) {
+	if !shouldSave() {
+		return "", nil
+	}
+	filename := generateFilename(text, FormatWAV)
+	fpath := filepath.Join(outputDir, filename)
+
+	f, err := os.Create(fpath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create WAV file: %w", err)
+	}
+	defer f.Close()
+
+	format.Precision = 2
+	if err := wav.Encode(f, streamer, format); err != nil {
+		os.Remove(fpath)
+		return "", fmt.Errorf("failed to encode WAV file: %w", err)
+	}
+	return fpath, nil
+}
+
+// transcodeMP3ToWAV decodes MP3 data and saves it as WAV.
+func transcodeMP3ToWAV(data []byte, text string) (string, error) {
+	streamer, format, err := mp3.Decode(io.NopCloser(bytes.NewReader(data)))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode MP3 for transcoding: %w", err)
+	}
+	defer streamer.Close()
+	return saveStreamWAV(maybeTrimSilence(streamer, format.SampleRate), format, text)
+}
+
+// saveMP3Audio saves audio received from an MP3-native provider in the
+// requested format. Formats other than WAV were already requested natively.
+func saveMP3Audio(data []byte, format, text string) (string, error) {
+	switch format {
+	case "", FormatMP3:
+		return saveMP3(data, text)
+	case FormatWAV:
+		return transcodeMP3ToWAV(data, text)
+	default:
+		return saveAudio(data, format, text)
+	}
+}
+
+// saveFormatFor resolves and validates the saved audio format for a provider call.
+// Returns "" when audio is not being saved. A --save-format the provider
+// cannot produce falls back to its native format; only a per-call format is
+// an error.
+func saveFormatFor(providerID string, requested *string) (string, error) {
+	if !shouldSave() {
+		return "", nil
+	}
+	format, err := resolveSaveFormat(requested)
+	if err != nil {
+		return "", err
+	}
+	if err := checkSaveFormat(providerID, format); err != nil {
+		if requested != nil && *requested != "" {
+			return "", err
+		}
+		log.Debug("Saving in the provider's native format", "provider", providerID, "reason", err)
+		return "", nil
+	}
+	return format, nil
+}
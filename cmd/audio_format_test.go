@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/generators"
+	"github.com/gopxl/beep/v2/wav"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveSaveFormat(t *testing.T) {
+	origSaveFormat := saveFormat
+	defer func() { saveFormat = origSaveFormat }()
+
+	t.Run("defaults to native format", func(t *testing.T) {
+		saveFormat = ""
+		format, err := resolveSaveFormat(nil)
+		require.NoError(t, err)
+		assert.Empty(t, format)
+	})
+
+	t.Run("uses --save-format when no per-call format", func(t *testing.T) {
+		saveFormat = "WAV"
+		format, err := resolveSaveFormat(stringPtr(""))
+		require.NoError(t, err)
+		assert.Equal(t, FormatWAV, format)
+	})
+
+	t.Run("per-call format overrides flag", func(t *testing.T) {
+		saveFormat = FormatWAV
+		format, err := resolveSaveFormat(stringPtr("flac"))
+		require.NoError(t, err)
+		assert.Equal(t, FormatFLAC, format)
+	})
+
+	t.Run("rejects unknown formats", func(t *testing.T) {
+		saveFormat = ""
+		_, err := resolveSaveFormat(stringPtr("ogg"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unsupported audio format")
+	})
+}
+
+func TestCheckSaveFormat(t *testing.T) {
+	origOutputDir := outputDir
+	origNoPlay := noPlay
+	defer func() {
+		outputDir = origOutputDir
+		noPlay = origNoPlay
+	}()
+	outputDir = t.TempDir()
+
+	tests := []struct {
+		name     string
+		provider string
+		format   string
+		noPlay   bool
+		wantErr  string
+	}{
+		{"native format always allowed", ProviderGoogle, "", false, ""},
+		{"say can save wav", ProviderSay, FormatWAV, false, ""},
+		{"say cannot save mp3", ProviderSay, FormatMP3, false, "cannot save mp3"},
+		{"google cannot save mp3", ProviderGoogle, FormatMP3, false, "cannot save mp3"},
+		{"elevenlabs transcodes wav", ProviderElevenLabs, FormatWAV, false, ""},
+		{"openai flac needs no-play", ProviderOpenAI, FormatFLAC, false, "cannot be played"},
+		{"openai flac with no-play", ProviderOpenAI, FormatFLAC, true, ""},
+		{"elevenlabs opus with no-play", ProviderElevenLabs, FormatOpus, true, ""},
+		{"openai pcm needs no-play", ProviderOpenAI, FormatPCM, false, "cannot be played"},
+		{"elevenlabs pcm needs no-play", ProviderElevenLabs, FormatPCM, false, "cannot be played"},
+		{"google pcm plays and saves", ProviderGoogle, FormatPCM, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			noPlay = tt.noPlay
+			err := checkSaveFormat(tt.provider, tt.format)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestSaveFormatForWithoutOutputDir(t *testing.T) {
+	origOutputDir := outputDir
+	defer func() { outputDir = origOutputDir }()
+	outputDir = ""
+
+	format, err := saveFormatFor(ProviderSay, stringPtr("flac"))
+	require.NoError(t, err, "format is ignored when audio is not saved")
+	assert.Empty(t, format)
+}
+
+func TestSaveFormatForFallsBackFromGlobalFormat(t *testing.T) {
+	origOutputDir, origSaveFormat, origNoPlay := outputDir, saveFormat, noPlay
+	defer func() { outputDir, saveFormat, noPlay = origOutputDir, origSaveFormat, origNoPlay }()
+	outputDir, saveFormat, noPlay = t.TempDir(), FormatMP3, false
+
+	format, err := saveFormatFor(ProviderSay, nil)
+	require.NoError(t, err, "--save-format the provider cannot produce uses its native format")
+	assert.Empty(t, format)
+
+	format, err = saveFormatFor(ProviderOpenAI, nil)
+	require.NoError(t, err)
+	assert.Equal(t, FormatMP3, format)
+
+	_, err = saveFormatFor(ProviderSay, stringPtr(FormatMP3))
+	assert.ErrorContains(t, err, "cannot save mp3 audio", "a per-call format is still checked")
+}
+
+func TestProviderNativeFormats(t *testing.T) {
+	assert.Equal(t, "mp3_44100_128", elevenLabsOutputFormat(""))
+	assert.Equal(t, "mp3_44100_128", elevenLabsOutputFormat(FormatWAV))
+	assert.Equal(t, "pcm_24000", elevenLabsOutputFormat(FormatPCM))
+	assert.Equal(t, "opus_48000_128", elevenLabsOutputFormat(FormatOpus))
+
+	assert.Equal(t, openai.AudioSpeechNewParamsResponseFormatMP3, openAIResponseFormat(""))
+	assert.Equal(t, openai.AudioSpeechNewParamsResponseFormatMP3, openAIResponseFormat(FormatWAV))
+	assert.Equal(t, openai.AudioSpeechNewParamsResponseFormatFLAC, openAIResponseFormat(FormatFLAC))
+	assert.Equal(t, openai.AudioSpeechNewParamsResponseFormatPCM, openAIResponseFormat(FormatPCM))
+}
+
+func TestSaveStreamWAV(t *testing.T) {
+	origOutputDir := outputDir
+	defer func() { outputDir = origOutputDir }()
+	outputDir = t.TempDir()
+
+	sr := beep.SampleRate(24000)
+	tone, err := generators.SineTone(sr, 440)
+	require.NoError(t, err)
+
+	path, err := saveStreamWAV(beep.Take(sr.N(100*time.Millisecond), tone), beep.Format{SampleRate: sr, NumChannels: 1}, "tone")
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(path, ".wav"))
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	decoded, format, err := wav.Decode(f)
+	require.NoError(t, err)
+	assert.Equal(t, sr, format.SampleRate)
+	assert.Equal(t, 2, format.Precision)
+	assert.Equal(t, sr.N(100*time.Millisecond), decoded.Len())
+}
+
+func TestSaveMP3AudioNativeFormats(t *testing.T) {
+	origOutputDir := outputDir
+	defer func() { outputDir = origOutputDir }()
+	outputDir = t.TempDir()
+
+	path, err := saveMP3Audio([]byte("opus data"), FormatOpus, "archive")
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(path, ".opus"))
+
+	path, err = saveMP3Audio([]byte("mp3 data"), "", "native")
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(path, ".mp3"))
+
+	_, err = saveMP3Audio([]byte("not an mp3"), FormatWAV, "broken")
+	assert.Error(t, err, "invalid MP3 data cannot be transcoded")
+}
@@ -28,6 +28,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -54,15 +55,7 @@ func shouldPlay() bool {
 // saveMP3 saves MP3 audio data to the output directory.
 // Returns the full path to the saved file.
 func saveMP3(data []byte, text string) (string, error) {
-	if !shouldSave() {
-		return "", nil
-	}
-	filename := generateFilename(text, "mp3")
-	fpath := filepath.Join(outputDir, filename)
-	if err := os.WriteFile(fpath, data, 0644); err != nil {
-		return "", fmt.Errorf("failed to save MP3 file: %w", err)
-	}
-	return fpath, nil
+	return saveAudio(data, FormatMP3, text)
 }
 
 // saveWAV saves PCM audio data as a WAV file to the output directory.
@@ -149,20 +142,26 @@ func writeWAVHeader(w io.Writer, dataSize, sampleRate, channels, bitsPerSample i
 }
 
 // formatSaveResult formats the result message based on save and play modes.
-func formatSaveResult(text, savedPath string, played bool) string {
+// Any caption files written alongside the audio are listed after the saved path.
+func formatSaveResult(text, savedPath string, played bool, captionPaths ...string) string {
+	saved := fmt.Sprintf("Saved: %s", savedPath)
+	if len(captionPaths) > 0 {
+		saved += fmt.Sprintf("\nCaptions: %s", strings.Join(captionPaths, ", "))
+	}
+
 	if suppressSpeakingOutput {
 		if savedPath != "" && !played {
-			return fmt.Sprintf("Saved: %s", savedPath)
+			return saved
 		} else if savedPath != "" {
-			return fmt.Sprintf("Speech completed\nSaved: %s", savedPath)
+			return "Speech completed\n" + saved
 		}
 		return "Speech completed"
 	}
 
 	if savedPath != "" && !played {
-		return fmt.Sprintf("Saved: %s", savedPath)
+		return saved
 	} else if savedPath != "" {
-		return fmt.Sprintf("Speaking: %s\nSaved: %s", text, savedPath)
+		return fmt.Sprintf("Speaking: %s\n%s", text, saved)
 	}
 	return fmt.Sprintf("Speaking: %s", text)
 }
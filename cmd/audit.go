@@ -0,0 +1,358 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/spf13/cobra"
+)
+
+// auditLogPath is the --audit-log flag: the audit log file, "" to disable.
+var auditLogPath string
+
+// auditAnchor is the audit verify --anchor flag: a hash from an earlier
+// verify that the chain must still contain.
+var auditAnchor string
+
+// auditEntry is one line of the audit log. Hash is the SHA-256 of the entry's
+// JSON without the hash itself, and PrevHash links it to the entry before,
+// so editing, removing or reordering lines breaks the chain. The hashes are
+// not keyed: whoever can write the file can rebuild a consistent chain, so
+// the log is only tamper-evident against a last hash kept somewhere else.
+type auditEntry struct {
+	Seq           int64           `json:"seq"`
+	Time          time.Time       `json:"time"`
+	SessionID     string          `json:"session_id,omitempty"`
+	Client        string          `json:"client,omitempty"`
+	ClientVersion string          `json:"client_version,omitempty"`
+	Tool          string          `json:"tool"`
+	Arguments     json.RawMessage `json:"arguments,omitempty"`
+	Outcome       string          `json:"outcome"`
+	Error         string          `json:"error,omitempty"`
+	Provider      string          `json:"provider,omitempty"`
+	DurationMS    int64           `json:"duration_ms"`
+	PrevHash      string          `json:"prev_hash"`
+	Hash          string          `json:"hash,omitempty"`
+}
+
+// digest returns the hash of the entry with its Hash field cleared.
+func (e auditEntry) digest() (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// auditLog appends hash-chained entries to a JSONL file. Appends hold a
+// cross-process lock, so several servers can share one log.
+type auditLog struct {
+	mu   sync.Mutex
+	path string
+}
+
+// activeAuditLog records tool calls; nil when disabled.
+var activeAuditLog *auditLog
+
+func newAuditLog(path string) *auditLog {
+	if path == "" {
+		return nil
+	}
+	return &auditLog{path: path}
+}
+
+// append links an entry to the last one in the log and writes it.
+func (a *auditLog) append(ctx context.Context, entry auditEntry) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(a.path), 0755); err != nil {
+		return err
+	}
+	lockDir := a.path + ".lock.d"
+	lock := &ttsMutexFile{lockDir: lockDir, contentFile: filepath.Join(lockDir, "content.json")}
+	if err := lock.acquireLock(ctx); err != nil {
+		return err
+	}
+	defer lock.releaseLock()
+
+	f, err := os.OpenFile(a.path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	last, err := lastLine(f)
+	if err != nil {
+		return err
+	}
+	if len(last) > 0 {
+		var prev auditEntry
+		if err := json.Unmarshal(last, &prev); err != nil {
+			return fmt.Errorf("audit log %s ends with an unreadable entry: %w", a.path, err)
+		}
+		entry.Seq, entry.PrevHash = prev.Seq+1, prev.Hash
+	} else {
+		entry.Seq = 1
+	}
+	if entry.Hash, err = entry.digest(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// lastLine returns the last non-empty line of a file, reading backwards so
+// large logs are not read in full.
+func lastLine(f *os.File) ([]byte, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	const chunk = 64 * 1024
+	var tail []byte
+	for end := info.Size(); end > 0; {
+		start := max(end-chunk, 0)
+		buf := make([]byte, end-start)
+		if _, err := f.ReadAt(buf, start); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		tail = append(buf, tail...)
+		trimmed := bytes.TrimRight(tail, "\n")
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			return trimmed[i+1:], nil
+		}
+		if start == 0 {
+			return trimmed, nil
+		}
+		end = start
+	}
+	return nil, nil
+}
+
+// verifyAuditLog checks every hash and link in the chain and returns the
+// number of entries and the last hash. A non-empty anchor must be the hash
+// of one of the entries.
+func verifyAuditLog(r io.Reader, anchor string) (int, string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	var count int
+	var prev auditEntry
+	anchored := anchor == ""
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var entry auditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return count, "", fmt.Errorf("line %d: unreadable entry: %w", line, err)
+		}
+		digest, err := entry.digest()
+		if err != nil {
+			return count, "", fmt.Errorf("line %d: %w", line, err)
+		}
+		switch {
+		case entry.Hash != digest:
+			return count, "", fmt.Errorf("line %d: hash mismatch, the entry was modified", line)
+		case entry.PrevHash != prev.Hash:
+			return count, "", fmt.Errorf("line %d: previous hash mismatch, an entry before it was removed or reordered", line)
+		case entry.Seq != prev.Seq+1:
+			return count, "", fmt.Errorf("line %d: sequence %d follows %d", line, entry.Seq, prev.Seq)
+		}
+		prev = entry
+		count++
+		anchored = anchored || entry.Hash == anchor
+	}
+	if err := scanner.Err(); err != nil {
+		return count, "", err
+	}
+	if !anchored {
+		return count, "", fmt.Errorf("anchor %s is not in the chain, the log was rewritten or truncated", anchor)
+	}
+	return count, prev.Hash, nil
+}
+
+// auditCall collects what the tool handlers learn about a call, such as the
+// provider that finally spoke it.
+type auditCall struct {
+	mu       sync.Mutex
+	provider string
+}
+
+type auditCallKey struct{}
+
+// setAuditProvider records the provider speaking the current call. Calls
+// that fall through end up recording the fallback provider.
+func setAuditProvider(ctx context.Context, provider string) {
+	if call, ok := ctx.Value(auditCallKey{}).(*auditCall); ok {
+		call.mu.Lock()
+		call.provider = provider
+		call.mu.Unlock()
+	}
+}
+
+// auditMiddleware records every tools/call request in the audit log.
+func auditMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		callReq, ok := req.(*mcp.CallToolRequest)
+		if activeAuditLog == nil || method != "tools/call" || !ok {
+			return next(ctx, method, req)
+		}
+		call := &auditCall{}
+		start := time.Now()
+		// Record the arguments as the client sent them, before
+		// condenseMiddleware rewrites them
+		args := redactArguments(callReq.Params.Arguments)
+		result, err := next(context.WithValue(ctx, auditCallKey{}, call), method, req)
+
+		entry := auditEntry{
+			Time:       start.UTC(),
+			Tool:       callReq.Params.Name,
+			Arguments:  args,
+			DurationMS: time.Since(start).Milliseconds(),
+			Provider:   call.provider,
+		}
+		if callReq.Session != nil {
+			entry.SessionID = callReq.Session.ID()
+			if params := callReq.Session.InitializeParams(); params != nil && params.ClientInfo != nil {
+				entry.Client, entry.ClientVersion = params.ClientInfo.Name, params.ClientInfo.Version
+			}
+		}
+		toolResult, _ := result.(*mcp.CallToolResult)
+		entry.Outcome = requestStatus(ctx, toolResult, err)
+		switch {
+		case err != nil:
+			entry.Error = err.Error()
+		case toolResult != nil && toolResult.IsError:
+			entry.Error = activeRedactor.forLogs().redactString(resultText(toolResult))
+		}
+		// Record the call even if the client has already gone away
+		if err := activeAuditLog.append(context.WithoutCancel(ctx), entry); err != nil {
+			log.Error("Failed to write audit log", "path", activeAuditLog.path, "error", err)
+		}
+		return result, err
+	}
+}
+
+// forLogs returns a redactor that masks matches in any mode but off. Refuse
+// mode replaces matches with a bare "redacted"; logs get redact mode's
+// labels instead, so they still say what kind of value was masked.
+func (r *redactor) forLogs() *redactor {
+	if r == nil || r.mode != RedactModeRefuse {
+		return r
+	}
+	return &redactor{mode: RedactModeRedact, detectors: r.detectors}
+}
+
+func (r *redactor) redactString(text string) string {
+	text, _ = r.redact(text)
+	return text
+}
+
+// redactArguments applies the redaction policy to every string in the tool
+// arguments.
+func redactArguments(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return nil
+	}
+	var args any
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil
+	}
+	r := activeRedactor.forLogs()
+	var walk func(v any) any
+	walk = func(v any) any {
+		switch v := v.(type) {
+		case string:
+			return r.redactString(v)
+		case map[string]any:
+			for k, item := range v {
+				v[k] = walk(item)
+			}
+		case []any:
+			for i, item := range v {
+				v[i] = walk(item)
+			}
+		}
+		return v
+	}
+	data, err := json.Marshal(walk(args))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// auditCmd groups audit log commands.
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Inspect the tool call audit log",
+}
+
+// auditVerifyCmd checks the audit log's hash chain.
+var auditVerifyCmd = &cobra.Command{
+	Use:          "verify",
+	Short:        "Verify that the audit log has not been modified",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if auditLogPath == "" {
+			return fmt.Errorf("no audit log configured, set --audit-log or MCP_TTS_AUDIT_LOG")
+		}
+		f, err := os.Open(auditLogPath)
+		if err != nil {
+			return fmt.Errorf("failed to open audit log: %w", err)
+		}
+		defer f.Close()
+		count, last, err := verifyAuditLog(f, auditAnchor)
+		if err != nil {
+			return fmt.Errorf("%s: %w (%d entries verified before it)", auditLogPath, err, count)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s: %d entries verified, last hash %s\n", auditLogPath, count, last)
+		return nil
+	},
+}
+
+func init() {
+	auditVerifyCmd.Flags().StringVar(&auditAnchor, "anchor", "", "Hash printed by an earlier verify and stored outside the log; fail unless the chain still contains it")
+	auditCmd.AddCommand(auditVerifyCmd)
+	rootCmd.AddCommand(auditCmd)
+}
@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeAuditEntries appends n entries to a fresh audit log.
+func writeAuditEntries(t *testing.T, n int) *auditLog {
+	t.Helper()
+	a := newAuditLog(filepath.Join(t.TempDir(), "audit", "audit.jsonl"))
+	for i := range n {
+		require.NoError(t, a.append(context.Background(), auditEntry{
+			Time:    time.Date(2026, 1, 1, 12, i, 0, 0, time.UTC),
+			Tool:    "say_tts",
+			Outcome: "ok",
+		}))
+	}
+	return a
+}
+
+func readAuditLines(t *testing.T, a *auditLog) []string {
+	t.Helper()
+	data, err := os.ReadFile(a.path)
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestAuditLogChain(t *testing.T) {
+	a := writeAuditEntries(t, 3)
+	lines := readAuditLines(t, a)
+	require.Len(t, lines, 3)
+
+	var first, second auditEntry
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+	assert.Equal(t, int64(1), first.Seq)
+	assert.Empty(t, first.PrevHash)
+	assert.Len(t, first.Hash, 64)
+	assert.Equal(t, int64(2), second.Seq)
+	assert.Equal(t, first.Hash, second.PrevHash)
+
+	count, last, err := verifyAuditLog(strings.NewReader(strings.Join(lines, "\n")), "")
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.Contains(t, lines[2], last)
+
+	tampered := map[string][]string{
+		"hash mismatch":          {lines[0], strings.Replace(lines[1], `"say_tts"`, `"openai_tts"`, 1), lines[2]},
+		"previous hash mismatch": {lines[0], lines[2]},
+		"reordered":              {lines[1], lines[0], lines[2]},
+		"unreadable entry":       {lines[0], "{", lines[2]},
+	}
+	count, _, err = verifyAuditLog(strings.NewReader(strings.Join(lines, "\n")), last)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	// Rebuilding the chain without the first entry hides the removal from
+	// the hashes, but not from an anchor kept elsewhere
+	rebuilt := newAuditLog(filepath.Join(t.TempDir(), "audit.jsonl"))
+	for _, line := range lines[1:] {
+		var entry auditEntry
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entry.PrevHash = ""
+		require.NoError(t, rebuilt.append(context.Background(), entry))
+	}
+	rebuiltLines := readAuditLines(t, rebuilt)
+	_, _, err = verifyAuditLog(strings.NewReader(strings.Join(rebuiltLines, "\n")), "")
+	require.NoError(t, err)
+	_, _, err = verifyAuditLog(strings.NewReader(strings.Join(rebuiltLines, "\n")), last)
+	assert.ErrorContains(t, err, "anchor "+last+" is not in the chain")
+
+	for name, lines := range tampered {
+		t.Run(name, func(t *testing.T) {
+			count, _, err := verifyAuditLog(strings.NewReader(strings.Join(lines, "\n")), "")
+			assert.Error(t, err)
+			assert.Less(t, count, 3)
+		})
+	}
+}
+
+func TestAuditLogLargeEntries(t *testing.T) {
+	a := newAuditLog(filepath.Join(t.TempDir(), "audit.jsonl"))
+	args, err := json.Marshal(map[string]string{"text": strings.Repeat("long text ", 20000)})
+	require.NoError(t, err)
+	for range 3 {
+		require.NoError(t, a.append(context.Background(), auditEntry{Tool: "openai_tts", Arguments: args, Outcome: "ok"}))
+	}
+
+	f, err := os.Open(a.path)
+	require.NoError(t, err)
+	defer f.Close()
+	count, _, err := verifyAuditLog(f, "")
+	require.NoError(t, err, "entries longer than the read-back chunk still chain")
+	assert.Equal(t, 3, count)
+}
+
+func TestRedactArguments(t *testing.T) {
+	origRedactor := activeRedactor
+	defer func() { activeRedactor = origRedactor }()
+	secret := "ghp_" + strings.Repeat("a", 36)
+	raw := json.RawMessage(`{"text":"token ` + secret + `","speed":1.5,"tags":["` + secret + `"]}`)
+
+	var err error
+	activeRedactor, err = newRedactor(RedactModeRefuse, nil)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"text":"token API key","speed":1.5,"tags":["API key"]}`, string(redactArguments(raw)),
+		"refuse mode still masks what is written down")
+
+	activeRedactor, err = newRedactor(RedactModeOff, nil)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(raw), string(redactArguments(raw)))
+
+	assert.Nil(t, redactArguments(nil))
+}
+
+func TestAuditMiddleware(t *testing.T) {
+	origLog, origRedactor := activeAuditLog, activeRedactor
+	defer func() { activeAuditLog, activeRedactor = origLog, origRedactor }()
+	activeAuditLog = newAuditLog(filepath.Join(t.TempDir(), "audit.jsonl"))
+	activeRedactor, _ = newRedactor(RedactModeRedact, nil)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "mcp-tts"}, nil)
+	// Stands in for condenseMiddleware rewriting the text
+	rewrite := func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			if callReq, ok := req.(*mcp.CallToolRequest); ok && method == "tools/call" {
+				callReq.Params.Arguments = json.RawMessage(`{"text":"rewritten"}`)
+			}
+			return next(ctx, method, req)
+		}
+	}
+	server.AddReceivingMiddleware(auditMiddleware, rewrite)
+	var fail bool
+	mcp.AddTool(server, &mcp.Tool{Name: "elevenlabs_tts"}, func(ctx context.Context, req *mcp.CallToolRequest, input TTSParams) (*mcp.CallToolResult, any, error) {
+		// Falls through to OpenAI
+		setAuditProvider(ctx, "elevenlabs")
+		setAuditProvider(ctx, "openai")
+		if fail {
+			return errorResult("Error: OPENAI_API_KEY is not set"), nil, nil
+		}
+		return textResult("Speaking: " + input.Text), nil, nil
+	})
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	ctx := context.Background()
+	_, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "1.2.3"}, nil).Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	_, err = session.CallTool(ctx, &mcp.CallToolParams{Name: "elevenlabs_tts", Arguments: map[string]any{"text": "mail me at jane@example.com"}})
+	require.NoError(t, err)
+	fail = true
+	_, err = session.CallTool(ctx, &mcp.CallToolParams{Name: "elevenlabs_tts", Arguments: map[string]any{"text": "fail"}})
+	require.NoError(t, err)
+
+	lines := readAuditLines(t, activeAuditLog)
+	require.Len(t, lines, 2)
+	var ok, failed auditEntry
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &ok))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &failed))
+
+	assert.Equal(t, "elevenlabs_tts", ok.Tool)
+	assert.Equal(t, "test-client", ok.Client)
+	assert.Equal(t, "1.2.3", ok.ClientVersion)
+	assert.Equal(t, "openai", ok.Provider, "the provider that spoke is recorded")
+	assert.Equal(t, "ok", ok.Outcome)
+	assert.JSONEq(t, `{"text":"mail me at email address"}`, string(ok.Arguments), "the arguments are recorded as sent")
+	assert.Equal(t, "error", failed.Outcome)
+	assert.Equal(t, "Error: OPENAI_API_KEY is not set", failed.Error)
+
+	t.Run("verify command", func(t *testing.T) {
+		origPath := auditLogPath
+		defer func() { auditLogPath = origPath }()
+		auditLogPath = activeAuditLog.path
+
+		var out bytes.Buffer
+		auditVerifyCmd.SetOut(&out)
+		defer auditVerifyCmd.SetOut(nil)
+		require.NoError(t, auditVerifyCmd.RunE(auditVerifyCmd, nil))
+		assert.Contains(t, out.String(), "2 entries verified, last hash "+failed.Hash)
+
+		defer func() { auditAnchor = "" }()
+		auditAnchor = ok.Hash
+		require.NoError(t, auditVerifyCmd.RunE(auditVerifyCmd, nil))
+		auditAnchor = ""
+
+		require.NoError(t, os.WriteFile(auditLogPath, []byte(lines[1]+"\n"), 0600))
+		assert.ErrorContains(t, auditVerifyCmd.RunE(auditVerifyCmd, nil), "line 1: previous hash mismatch")
+
+		auditLogPath = ""
+		assert.ErrorContains(t, auditVerifyCmd.RunE(auditVerifyCmd, nil), "no audit log configured")
+	})
+}
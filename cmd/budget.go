@@ -0,0 +1,618 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/log"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// budgetFileName sits next to the global lock directory so every server
+// instance on the machine shares the same counters.
+const budgetFileName = "mcp-tts-budget.json"
+
+// providerLimits caps how fast and how much a provider may speak. Zero
+// values mean no limit.
+type providerLimits struct {
+	RequestsPerMinute int    `yaml:"requests_per_minute,omitempty"`
+	CharsPerMinute    int    `yaml:"chars_per_minute,omitempty"`
+	DailyChars        int    `yaml:"daily_chars,omitempty"`
+	MonthlyChars      int    `yaml:"monthly_chars,omitempty"`
+	Fallback          string `yaml:"fallback,omitempty"` // provider to use once a limit is hit
+}
+
+// limitFields are the config keys of provider limits, in display order.
+var limitFields = []string{"limits.requests_per_minute", "limits.chars_per_minute", "limits.daily_chars", "limits.monthly_chars", "limits.fallback"}
+
+// merge overlays the non-zero fields of src, recording source for each one.
+func (l *providerLimits) merge(src providerLimits, set func(field string)) {
+	if src.RequestsPerMinute != 0 {
+		l.RequestsPerMinute = src.RequestsPerMinute
+		set("limits.requests_per_minute")
+	}
+	if src.CharsPerMinute != 0 {
+		l.CharsPerMinute = src.CharsPerMinute
+		set("limits.chars_per_minute")
+	}
+	if src.DailyChars != 0 {
+		l.DailyChars = src.DailyChars
+		set("limits.daily_chars")
+	}
+	if src.MonthlyChars != 0 {
+		l.MonthlyChars = src.MonthlyChars
+		set("limits.monthly_chars")
+	}
+	if src.Fallback != "" {
+		l.Fallback = src.Fallback
+		set("limits.fallback")
+	}
+}
+
+// field returns a setting formatted for display, or "" when unset.
+func (l providerLimits) field(name string) string {
+	value := 0
+	switch name {
+	case "limits.requests_per_minute":
+		value = l.RequestsPerMinute
+	case "limits.chars_per_minute":
+		value = l.CharsPerMinute
+	case "limits.daily_chars":
+		value = l.DailyChars
+	case "limits.monthly_chars":
+		value = l.MonthlyChars
+	case "limits.fallback":
+		return l.Fallback
+	}
+	if value == 0 {
+		return ""
+	}
+	return strconv.Itoa(value)
+}
+
+// validate checks the limits configured for the provider with config key name.
+func (l providerLimits) validate(name string) error {
+	if l.RequestsPerMinute < 0 || l.CharsPerMinute < 0 || l.DailyChars < 0 || l.MonthlyChars < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	if l.Fallback == "" {
+		return nil
+	}
+	if _, ok := providerConfigKeys[l.Fallback]; !ok {
+		return fmt.Errorf("unknown fallback provider %q (supported: %s)", l.Fallback, strings.Join(slices.Sorted(maps.Keys(providerConfigKeys)), ", "))
+	}
+	if l.Fallback == name {
+		return fmt.Errorf("a provider cannot fall back to itself")
+	}
+	return nil
+}
+
+// budgeted reports whether daily or monthly character budgets apply.
+func (l providerLimits) budgeted() bool {
+	return l.DailyChars > 0 || l.MonthlyChars > 0
+}
+
+// limited reports whether any rate limit or budget applies.
+func (l providerLimits) limited() bool {
+	return l.budgeted() || l.RequestsPerMinute > 0 || l.CharsPerMinute > 0
+}
+
+// limitError explains why a rate limit or budget refused a call.
+type limitError struct {
+	msg string
+}
+
+func (e *limitError) Error() string { return e.msg }
+
+// tokenBucket refills at capacity tokens per minute.
+type tokenBucket struct {
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = min(b.capacity, b.tokens+now.Sub(b.last).Minutes()*b.capacity)
+	b.last = now
+}
+
+// wait returns how long until n tokens are available. A request larger
+// than the bucket only needs a full bucket, so it is slowed but never stuck.
+func (b *tokenBucket) wait(n float64) time.Duration {
+	n = min(n, b.capacity)
+	if b.tokens >= n {
+		return 0
+	}
+	return time.Duration((n - b.tokens) / b.capacity * float64(time.Minute))
+}
+
+func (b *tokenBucket) take(n float64) {
+	b.tokens = max(b.tokens-n, 0)
+}
+
+func (b *tokenBucket) give(n float64) {
+	b.tokens = min(b.tokens+n, b.capacity)
+}
+
+// rateLimiter holds per-process request and character buckets per provider,
+// and call buckets per MCP session.
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[any]*tokenBucket
+	now     func() time.Time
+}
+
+// activeRateLimiter throttles the tool handlers.
+var activeRateLimiter = newRateLimiter()
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{buckets: make(map[any]*tokenBucket), now: time.Now}
+}
+
+// bucket returns the refilled bucket for key, starting full and following
+// capacity changes (e.g. a per-call profile with other limits).
+func (r *rateLimiter) bucket(key any, perMinute int, now time.Time) *tokenBucket {
+	b, ok := r.buckets[key]
+	if !ok || b.capacity != float64(perMinute) {
+		b = &tokenBucket{capacity: float64(perMinute), tokens: float64(perMinute), last: now}
+		r.buckets[key] = b
+	}
+	b.refill(now)
+	return b
+}
+
+// take consumes one request and chars characters from the provider's
+// buckets, or takes nothing and explains how long to wait.
+func (r *rateLimiter) take(providerID string, limits providerLimits, chars int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	name := providerKey(providerID)
+
+	var requests, characters *tokenBucket
+	if limits.RequestsPerMinute > 0 {
+		requests = r.bucket(providerID+"/requests", limits.RequestsPerMinute, now)
+		if wait := requests.wait(1); wait > 0 {
+			return &limitError{fmt.Sprintf("Rate limit reached: %s allows %d requests per minute, try again in %s",
+				name, limits.RequestsPerMinute, wait.Truncate(time.Second)+time.Second)}
+		}
+	}
+	if limits.CharsPerMinute > 0 {
+		characters = r.bucket(providerID+"/chars", limits.CharsPerMinute, now)
+		if wait := characters.wait(float64(chars)); wait > 0 {
+			return &limitError{fmt.Sprintf("Rate limit reached: %s allows %d characters per minute, try again in %s",
+				name, limits.CharsPerMinute, wait.Truncate(time.Second)+time.Second)}
+		}
+	}
+	if requests != nil {
+		requests.take(1)
+	}
+	if characters != nil {
+		characters.take(float64(chars))
+	}
+	return nil
+}
+
+// refund gives back what take consumed for a call that was never synthesized.
+func (r *rateLimiter) refund(providerID string, limits providerLimits, chars int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	if limits.RequestsPerMinute > 0 {
+		r.bucket(providerID+"/requests", limits.RequestsPerMinute, now).give(1)
+	}
+	if limits.CharsPerMinute > 0 {
+		r.bucket(providerID+"/chars", limits.CharsPerMinute, now).give(float64(min(chars, limits.CharsPerMinute)))
+	}
+}
+
+// available returns the requests and characters the provider may use right now.
+func (r *rateLimiter) available(providerID string, limits providerLimits) (requests, chars int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	if limits.RequestsPerMinute > 0 {
+		requests = int(math.Floor(r.bucket(providerID+"/requests", limits.RequestsPerMinute, now).tokens))
+	}
+	if limits.CharsPerMinute > 0 {
+		chars = int(math.Floor(r.bucket(providerID+"/chars", limits.CharsPerMinute, now).tokens))
+	}
+	return requests, chars
+}
+
+// budgetUsage counts the characters a provider has spoken in the current
+// day and month.
+type budgetUsage struct {
+	Day        string `json:"day"`
+	DayChars   int    `json:"day_chars"`
+	Month      string `json:"month"`
+	MonthChars int    `json:"month_chars"`
+}
+
+// exceeds explains why chars more characters would go over a budget, or
+// returns nil.
+func (u budgetUsage) exceeds(name string, limits providerLimits, chars int) error {
+	if limits.DailyChars > 0 && u.DayChars+chars > limits.DailyChars {
+		return &limitError{fmt.Sprintf("Budget exceeded: %s has used %d of its %d daily characters and this call needs %d; the budget resets tomorrow",
+			name, u.DayChars, limits.DailyChars, chars)}
+	}
+	if limits.MonthlyChars > 0 && u.MonthChars+chars > limits.MonthlyChars {
+		return &limitError{fmt.Sprintf("Budget exceeded: %s has used %d of its %d monthly characters and this call needs %d; the budget resets next month",
+			name, u.MonthChars, limits.MonthlyChars, chars)}
+	}
+	return nil
+}
+
+// roll resets the counters when the day or month has changed.
+func (u *budgetUsage) roll(now time.Time) {
+	if day := now.Format(time.DateOnly); u.Day != day {
+		u.Day, u.DayChars = day, 0
+	}
+	if month := now.Format("2006-01"); u.Month != month {
+		u.Month, u.MonthChars = month, 0
+	}
+}
+
+// budgetReservationTTL bounds how long a call may hold budget it has not
+// been charged for yet, so a server that dies mid-call does not keep it.
+const budgetReservationTTL = 15 * time.Minute
+
+// budgetReservation is budget set aside for a call in progress.
+type budgetReservation struct {
+	Provider string    `json:"provider"`
+	Chars    int       `json:"chars"`
+	Expires  time.Time `json:"expires"`
+}
+
+// budgetState is the layout of the budget file.
+type budgetState struct {
+	Providers    map[string]*budgetUsage       `json:"providers"`
+	Reservations map[string]*budgetReservation `json:"reservations,omitempty"`
+}
+
+// provider returns the provider's usage rolled to now, adding it if missing.
+func (st *budgetState) provider(providerID string, now time.Time) *budgetUsage {
+	usage := st.Providers[providerID]
+	if usage == nil {
+		usage = &budgetUsage{}
+		st.Providers[providerID] = usage
+	}
+	usage.roll(now)
+	return usage
+}
+
+// committed returns the provider's usage plus the characters reserved for
+// its calls in progress.
+func (st *budgetState) committed(providerID string, now time.Time) budgetUsage {
+	usage := *st.provider(providerID, now)
+	for _, r := range st.Reservations {
+		if r.Provider == providerID {
+			usage.DayChars += r.Chars
+			usage.MonthChars += r.Chars
+		}
+	}
+	return usage
+}
+
+// budgetStore persists character budgets. Updates are serialized across
+// processes with a directory lock like the global speech lock, and calls in
+// progress reserve their characters in the file, so every server on the
+// machine counts them.
+type budgetStore struct {
+	path string
+	now  func() time.Time
+}
+
+// activeBudgetStore records usage for providers with budgets.
+var activeBudgetStore = newBudgetStore(filepath.Join(filepath.Dir(globalLockDir()), budgetFileName))
+
+func newBudgetStore(path string) *budgetStore {
+	return &budgetStore{path: path, now: time.Now}
+}
+
+func (s *budgetStore) load() (*budgetState, error) {
+	state := &budgetState{Providers: make(map[string]*budgetUsage)}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return state, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.path, err)
+	}
+	if state.Providers == nil {
+		state.Providers = make(map[string]*budgetUsage)
+	}
+	return state, nil
+}
+
+// update applies fn to the budget file under the cross-process lock, after
+// dropping expired reservations, and writes the result back.
+func (s *budgetStore) update(ctx context.Context, fn func(state *budgetState, now time.Time) error) error {
+	return withFileLock(ctx, s.path, func() error {
+		state, err := s.load()
+		if err != nil {
+			return err
+		}
+		now := s.now()
+		for id, r := range state.Reservations {
+			if !now.Before(r.Expires) {
+				delete(state.Reservations, id)
+			}
+		}
+		if err := fn(state, now); err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(state, "", "  ")
+		if err != nil {
+			return err
+		}
+		return writeFileAtomic(s.path, data)
+	})
+}
+
+// charge adds chars to the provider's daily and monthly usage, unless that
+// would exceed a budget.
+func (s *budgetStore) charge(ctx context.Context, providerID string, limits providerLimits, chars int) error {
+	return s.update(ctx, func(state *budgetState, now time.Time) error {
+		if err := state.committed(providerID, now).exceeds(providerKey(providerID), limits, chars); err != nil {
+			return err
+		}
+		usage := state.provider(providerID, now)
+		usage.DayChars += chars
+		usage.MonthChars += chars
+		return nil
+	})
+}
+
+// usage returns the provider's current counters.
+func (s *budgetStore) usage(providerID string) (budgetUsage, error) {
+	state, err := s.load()
+	if err != nil {
+		return budgetUsage{}, err
+	}
+	var usage budgetUsage
+	if u := state.Providers[providerID]; u != nil {
+		usage = *u
+	}
+	usage.roll(s.now())
+	return usage, nil
+}
+
+// reserve sets chars aside for a call in progress, unless together with the
+// characters used and reserved by every server they would exceed a budget.
+// It returns the reservation's ID.
+func (s *budgetStore) reserve(ctx context.Context, providerID string, limits providerLimits, chars int) (string, error) {
+	id := rand.Text()
+	err := s.update(ctx, func(state *budgetState, now time.Time) error {
+		if err := state.committed(providerID, now).exceeds(providerKey(providerID), limits, chars); err != nil {
+			return err
+		}
+		if state.Reservations == nil {
+			state.Reservations = make(map[string]*budgetReservation)
+		}
+		state.Reservations[id] = &budgetReservation{Provider: providerID, Chars: chars, Expires: now.Add(budgetReservationTTL)}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// settle turns a reservation into a charge. The audio exists by then, so it
+// is charged even if the reservation has expired or the budget is used up.
+func (s *budgetStore) settle(ctx context.Context, id, providerID string, chars int) error {
+	return s.update(ctx, func(state *budgetState, now time.Time) error {
+		delete(state.Reservations, id)
+		usage := state.provider(providerID, now)
+		usage.DayChars += chars
+		usage.MonthChars += chars
+		return nil
+	})
+}
+
+// unreserve drops a reservation without charging it.
+func (s *budgetStore) unreserve(ctx context.Context, id string) error {
+	return s.update(ctx, func(state *budgetState, now time.Time) error {
+		delete(state.Reservations, id)
+		return nil
+	})
+}
+
+// limitReservation is a call's claim on a provider's rate limits and
+// budgets. Rate limit tokens are taken up front and given back if the call
+// ends without audio; budget characters are reserved in the budget file and
+// only charged once the provider has produced audio.
+type limitReservation struct {
+	providerID string
+	limits     providerLimits
+	chars      int
+	budgetID   string // reservation in the budget file, "" if none
+	done       bool
+}
+
+// reserveLimits claims a call's share of the provider's limits, or returns
+// a *limitError explaining which limit it would exceed.
+func reserveLimits(ctx context.Context, providerID string, limits providerLimits, chars int) (*limitReservation, error) {
+	if err := activeRateLimiter.take(providerID, limits, chars); err != nil {
+		return nil, err
+	}
+	r := &limitReservation{providerID: providerID, limits: limits, chars: chars}
+	if !limits.budgeted() {
+		return r, nil
+	}
+	id, err := activeBudgetStore.reserve(ctx, providerID, limits, chars)
+	var limitErr *limitError
+	if errors.As(err, &limitErr) {
+		activeRateLimiter.refund(providerID, limits, chars)
+		return nil, err
+	}
+	if err != nil {
+		// Never let a broken budget file silence the server
+		log.Warn("Failed to check usage budget, allowing the call", "provider", providerID, "error", err)
+	}
+	r.budgetID = id
+	return r, nil
+}
+
+// commit charges the call to the provider's budgets once it has produced
+// audio.
+func (r *limitReservation) commit(ctx context.Context) {
+	if r == nil || r.done {
+		return
+	}
+	r.done = true
+	if !r.limits.budgeted() {
+		return
+	}
+	if err := activeBudgetStore.settle(context.WithoutCancel(ctx), r.budgetID, r.providerID, r.chars); err != nil {
+		log.Warn("Failed to record usage budget", "provider", r.providerID, "error", err)
+	}
+}
+
+// release gives back the limits of a call that ended without audio, e.g.
+// because it was cancelled, declined or failed. It does nothing after commit.
+func (r *limitReservation) release() {
+	if r == nil || r.done {
+		return
+	}
+	r.done = true
+	activeRateLimiter.refund(r.providerID, r.limits, r.chars)
+	if r.budgetID == "" {
+		return
+	}
+	if err := activeBudgetStore.unreserve(context.Background(), r.budgetID); err != nil {
+		log.Warn("Failed to release usage budget", "provider", r.providerID, "error", err)
+	}
+}
+
+// fallthroughKey marks a call forwarded by a provider that hit a limit. Its
+// value lists the providers already tried.
+type fallthroughKey struct{}
+
+// fallingThrough reports whether the call was forwarded from another provider.
+func fallingThrough(ctx context.Context) bool {
+	return ctx.Value(fallthroughKey{}) != nil
+}
+
+// admitSpeech reserves a call's share of the provider's rate limits and
+// budgets. When a limit is hit it returns the result to send instead: the
+// answer of the configured fallback provider, or an error explaining the
+// limit. The fallback gets the call's text, markup and output options.
+func admitSpeech(ctx context.Context, req *mcp.CallToolRequest, providerID, profile string, input TTSParams) (*limitReservation, *mcp.CallToolResult) {
+	limits := providerDefaults(providerID, profile).Limits
+	if !limits.limited() {
+		return nil, nil
+	}
+	reservation, err := reserveLimits(ctx, providerID, limits, utf8.RuneCountInString(input.Text))
+	if err == nil {
+		return reservation, nil
+	}
+	log.Warn("Provider limit reached", "provider", providerID, "reason", err)
+	ttsMetrics.limited.inc(providerKey(providerID))
+
+	tried, _ := ctx.Value(fallthroughKey{}).([]string)
+	tried = append(slices.Clone(tried), providerID)
+	fallback := providerConfigKeys[limits.Fallback]
+	handler, ok := speechHandlers[fallback]
+	if !ok || slices.Contains(tried, fallback) {
+		return nil, errorResult("Error: " + err.Error())
+	}
+
+	log.Info("Falling through to fallback provider", "from", providerID, "to", fallback)
+	// Voices and other settings chosen for this provider mean nothing to the
+	// fallback, which uses its own category, project and configured defaults
+	input.Settings = nil
+	result, err2 := handler(context.WithValue(ctx, fallthroughKey{}, tried), req, input)
+	if err2 != nil {
+		return nil, errorResult(fmt.Sprintf("Error: %v", err2))
+	}
+	if len(result.Content) > 0 {
+		if text, ok := result.Content[0].(*mcp.TextContent); ok && !result.IsError {
+			text.Text = fmt.Sprintf("%s. Used %s instead.\n%s", err, limits.Fallback, text.Text)
+		}
+	}
+	return nil, result
+}
+
+// usageReport describes each limited provider's remaining rate and budget.
+func usageReport(profile string) string {
+	var lines []string
+	for _, name := range slices.Sorted(maps.Keys(providerConfigKeys)) {
+		providerID := providerConfigKeys[name]
+		limits := providerDefaults(providerID, profile).Limits
+		if !limits.limited() {
+			continue
+		}
+
+		var parts []string
+		if limits.budgeted() {
+			usage, err := activeBudgetStore.usage(providerID)
+			if err != nil {
+				parts = append(parts, fmt.Sprintf("budget unavailable (%v)", err))
+			} else {
+				if limits.DailyChars > 0 {
+					parts = append(parts, fmt.Sprintf("today %d of %d characters used, %d left",
+						usage.DayChars, limits.DailyChars, max(limits.DailyChars-usage.DayChars, 0)))
+				}
+				if limits.MonthlyChars > 0 {
+					parts = append(parts, fmt.Sprintf("this month %d of %d characters used, %d left",
+						usage.MonthChars, limits.MonthlyChars, max(limits.MonthlyChars-usage.MonthChars, 0)))
+				}
+			}
+		}
+		requests, chars := activeRateLimiter.available(providerID, limits)
+		if limits.RequestsPerMinute > 0 {
+			parts = append(parts, fmt.Sprintf("%d of %d requests per minute available", requests, limits.RequestsPerMinute))
+		}
+		if limits.CharsPerMinute > 0 {
+			parts = append(parts, fmt.Sprintf("%d of %d characters per minute available", chars, limits.CharsPerMinute))
+		}
+		if limits.Fallback != "" {
+			parts = append(parts, "falls back to "+limits.Fallback)
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", name, strings.Join(parts, "; ")))
+	}
+	if len(lines) == 0 {
+		return "No rate limits or budgets are configured."
+	}
+	return strings.Join(lines, "\n")
+}
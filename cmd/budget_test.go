@@ -0,0 +1,287 @@
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useTestLimits points the limiter, budget store and config at test state.
+func useTestLimits(t *testing.T, config string) *budgetStore {
+	t.Helper()
+	origConfig, origStore, origLimiter, origHandlers := activeConfig, activeBudgetStore, activeRateLimiter, speechHandlers
+	t.Cleanup(func() {
+		activeConfig, activeBudgetStore, activeRateLimiter, speechHandlers = origConfig, origStore, origLimiter, origHandlers
+	})
+	cfg, err := loadConfig(writeTestFile(t, t.TempDir(), configFileName, config), "")
+	require.NoError(t, err)
+	activeConfig = cfg
+	activeBudgetStore = newBudgetStore(filepath.Join(t.TempDir(), budgetFileName))
+	activeRateLimiter = newRateLimiter()
+	speechHandlers = map[string]speechHandler{}
+	return activeBudgetStore
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	r := newRateLimiter()
+	r.now = func() time.Time { return now }
+
+	requests := providerLimits{RequestsPerMinute: 2}
+	require.NoError(t, r.take(ProviderOpenAI, requests, 10))
+	require.NoError(t, r.take(ProviderOpenAI, requests, 10))
+	err := r.take(ProviderOpenAI, requests, 10)
+	assert.EqualError(t, err, "Rate limit reached: openai allows 2 requests per minute, try again in 31s")
+	now = now.Add(30 * time.Second)
+	assert.NoError(t, r.take(ProviderOpenAI, requests, 10), "half a minute refills one request")
+
+	chars := providerLimits{CharsPerMinute: 100}
+	require.NoError(t, r.take(ProviderGoogle, chars, 80))
+	assert.ErrorContains(t, r.take(ProviderGoogle, chars, 30), "100 characters per minute")
+	now = now.Add(10 * time.Second)
+	assert.NoError(t, r.take(ProviderGoogle, chars, 30))
+
+	t.Run("text longer than the bucket needs a full bucket", func(t *testing.T) {
+		require.NoError(t, r.take(ProviderSay, chars, 500))
+		assert.Error(t, r.take(ProviderSay, chars, 500))
+		now = now.Add(time.Minute)
+		assert.NoError(t, r.take(ProviderSay, chars, 500))
+	})
+
+	t.Run("a refused call takes nothing", func(t *testing.T) {
+		both := providerLimits{RequestsPerMinute: 5, CharsPerMinute: 10}
+		require.NoError(t, r.take(ProviderElevenLabs, both, 10))
+		require.Error(t, r.take(ProviderElevenLabs, both, 5))
+		requests, chars := r.available(ProviderElevenLabs, both)
+		assert.Equal(t, 4, requests)
+		assert.Zero(t, chars)
+	})
+}
+
+func TestBudgetStore(t *testing.T) {
+	now := time.Date(2026, 3, 30, 9, 0, 0, 0, time.Local)
+	path := filepath.Join(t.TempDir(), budgetFileName)
+	store := newBudgetStore(path)
+	store.now = func() time.Time { return now }
+	limits := providerLimits{DailyChars: 100, MonthlyChars: 150}
+	ctx := context.Background()
+
+	require.NoError(t, store.charge(ctx, ProviderElevenLabs, limits, 60))
+	err := store.charge(ctx, ProviderElevenLabs, limits, 50)
+	assert.EqualError(t, err, "Budget exceeded: elevenlabs has used 60 of its 100 daily characters and this call needs 50; the budget resets tomorrow")
+
+	now = now.Add(24 * time.Hour)
+	require.NoError(t, store.charge(ctx, ProviderElevenLabs, limits, 50))
+	assert.ErrorContains(t, store.charge(ctx, ProviderElevenLabs, limits, 50), "110 of its 150 monthly characters")
+
+	other := newBudgetStore(path)
+	other.now = store.now
+	usage, err := other.usage(ProviderElevenLabs)
+	require.NoError(t, err)
+	assert.Equal(t, budgetUsage{Day: "2026-03-31", DayChars: 50, Month: "2026-03", MonthChars: 110}, usage, "usage is persisted")
+
+	now = now.Add(24 * time.Hour)
+	usage, err = other.usage(ProviderElevenLabs)
+	require.NoError(t, err)
+	assert.Zero(t, usage.MonthChars, "a new month starts over")
+}
+
+func TestBudgetStoreSharedAcrossInstances(t *testing.T) {
+	path := filepath.Join(t.TempDir(), budgetFileName)
+	stores := []*budgetStore{newBudgetStore(path), newBudgetStore(path)}
+	limits := providerLimits{DailyChars: 1000}
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Go(func() {
+			assert.NoError(t, stores[i%2].charge(context.Background(), ProviderOpenAI, limits, 5))
+		})
+	}
+	wg.Wait()
+
+	usage, err := stores[0].usage(ProviderOpenAI)
+	require.NoError(t, err)
+	assert.Equal(t, 100, usage.DayChars)
+}
+
+func TestBudgetReservationsSharedAcrossInstances(t *testing.T) {
+	now := time.Date(2026, 3, 30, 9, 0, 0, 0, time.Local)
+	path := filepath.Join(t.TempDir(), budgetFileName)
+	first, second := newBudgetStore(path), newBudgetStore(path)
+	first.now = func() time.Time { return now }
+	second.now = first.now
+	limits := providerLimits{DailyChars: 10}
+	ctx := context.Background()
+
+	id, err := first.reserve(ctx, ProviderOpenAI, limits, 8)
+	require.NoError(t, err)
+	_, err = second.reserve(ctx, ProviderOpenAI, limits, 5)
+	assert.ErrorContains(t, err, "has used 8 of its 10 daily characters", "another server's call in progress counts")
+
+	require.NoError(t, first.unreserve(ctx, id))
+	id, err = second.reserve(ctx, ProviderOpenAI, limits, 5)
+	require.NoError(t, err)
+	require.NoError(t, second.settle(ctx, id, ProviderOpenAI, 5))
+	usage, err := first.usage(ProviderOpenAI)
+	require.NoError(t, err)
+	assert.Equal(t, 5, usage.DayChars)
+
+	_, err = first.reserve(ctx, ProviderOpenAI, limits, 5)
+	require.NoError(t, err)
+	_, err = second.reserve(ctx, ProviderOpenAI, limits, 1)
+	assert.Error(t, err)
+	now = now.Add(budgetReservationTTL)
+	_, err = second.reserve(ctx, ProviderOpenAI, limits, 5)
+	assert.NoError(t, err, "a crashed server's reservation expires")
+
+	t.Run("concurrent reservations never overspend", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), budgetFileName)
+		stores := []*budgetStore{newBudgetStore(path), newBudgetStore(path)}
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		granted := 0
+		for i := range 20 {
+			wg.Go(func() {
+				if _, err := stores[i%2].reserve(ctx, ProviderOpenAI, providerLimits{DailyChars: 50}, 5); err == nil {
+					mu.Lock()
+					granted++
+					mu.Unlock()
+				}
+			})
+		}
+		wg.Wait()
+		assert.Equal(t, 10, granted)
+	})
+}
+
+func TestAdmitSpeech(t *testing.T) {
+	useTestLimits(t, `
+providers:
+  elevenlabs:
+    limits:
+      daily_chars: 10
+      fallback: openai
+  openai:
+    limits:
+      daily_chars: 15
+      fallback: elevenlabs
+  google:
+    limits:
+      requests_per_minute: 1
+`)
+	var forwarded TTSParams
+	speechHandlers[ProviderOpenAI] = func(ctx context.Context, req *mcp.CallToolRequest, input TTSParams) (*mcp.CallToolResult, error) {
+		reservation, result := admitSpeech(ctx, req, ProviderOpenAI, "", input)
+		if result != nil {
+			return result, nil
+		}
+		reservation.commit(ctx)
+		require.True(t, fallingThrough(ctx))
+		forwarded = input
+		return textResult("Speaking: " + input.Text), nil
+	}
+	ctx := context.Background()
+
+	reservation, result := admitSpeech(ctx, nil, ProviderElevenLabs, "", TTSParams{Text: "short"})
+	assert.Nil(t, result)
+	require.NotNil(t, reservation)
+	reservation.commit(ctx)
+
+	ssml, format := "ssml", FormatWAV
+	_, result = admitSpeech(ctx, nil, ProviderElevenLabs, "", TTSParams{Text: "a bit longer", Markup: &ssml, Format: &format, Settings: map[string]any{"voice": "x"}})
+	require.NotNil(t, result)
+	text := result.Content[0].(*mcp.TextContent).Text
+	assert.True(t, strings.HasPrefix(text, "Budget exceeded: elevenlabs has used 5 of its 10 daily characters"), text)
+	assert.True(t, strings.HasSuffix(text, ". Used openai instead.\nSpeaking: a bit longer"), text)
+	assert.Equal(t, TTSParams{Text: "a bit longer", Markup: &ssml, Format: &format}, forwarded, "the fallback gets the call's options but not its provider settings")
+
+	t.Run("fallback chains do not loop", func(t *testing.T) {
+		_, result := admitSpeech(ctx, nil, ProviderElevenLabs, "", TTSParams{Text: "far too long for both"})
+		require.NotNil(t, result)
+		assert.True(t, result.IsError)
+		assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, "Error: Budget exceeded: openai")
+	})
+
+	t.Run("no fallback returns the limit", func(t *testing.T) {
+		_, result := admitSpeech(ctx, nil, ProviderGoogle, "", TTSParams{Text: "one"})
+		require.Nil(t, result)
+		_, result = admitSpeech(ctx, nil, ProviderGoogle, "", TTSParams{Text: "two"})
+		require.NotNil(t, result)
+		assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, "Error: Rate limit reached: google allows 1 requests per minute")
+	})
+
+	t.Run("unlimited providers are not tracked", func(t *testing.T) {
+		reservation, result := admitSpeech(ctx, nil, ProviderSay, "", TTSParams{Text: "anything"})
+		assert.Nil(t, reservation)
+		assert.Nil(t, result)
+	})
+}
+
+func TestLimitReservation(t *testing.T) {
+	store := useTestLimits(t, `
+providers:
+  openai:
+    limits:
+      daily_chars: 10
+      requests_per_minute: 1
+`)
+	ctx := context.Background()
+	limits := providerDefaults(ProviderOpenAI, "").Limits
+
+	reservation, err := reserveLimits(ctx, ProviderOpenAI, limits, 8)
+	require.NoError(t, err)
+	_, err = reserveLimits(ctx, ProviderOpenAI, providerLimits{DailyChars: 10}, 5)
+	assert.ErrorContains(t, err, "has used 8 of its 10 daily characters", "calls in progress count against the budget")
+
+	reservation.release()
+	reservation.commit(ctx)
+	usage, err := store.usage(ProviderOpenAI)
+	require.NoError(t, err)
+	assert.Zero(t, usage.DayChars, "a released call is not charged")
+	requests, _ := activeRateLimiter.available(ProviderOpenAI, limits)
+	assert.Equal(t, 1, requests, "a released call gives back its request")
+
+	reservation, err = reserveLimits(ctx, ProviderOpenAI, limits, 8)
+	require.NoError(t, err)
+	reservation.commit(ctx)
+	reservation.release()
+	usage, err = store.usage(ProviderOpenAI)
+	require.NoError(t, err)
+	assert.Equal(t, 8, usage.DayChars, "a synthesized call is charged")
+	requests, _ = activeRateLimiter.available(ProviderOpenAI, limits)
+	assert.Zero(t, requests)
+}
+
+func TestUsageReport(t *testing.T) {
+	assert.Equal(t, "No rate limits or budgets are configured.", func() string {
+		useTestLimits(t, "")
+		return usageReport("")
+	}())
+
+	store := useTestLimits(t, `
+providers:
+  elevenlabs:
+    limits:
+      daily_chars: 100
+      monthly_chars: 1000
+      requests_per_minute: 10
+      fallback: say
+`)
+	require.NoError(t, store.charge(context.Background(), ProviderElevenLabs, providerLimits{DailyChars: 100}, 40))
+	assert.Equal(t, "elevenlabs: today 40 of 100 characters used, 60 left; this month 40 of 1000 characters used, 960 left; "+
+		"10 of 10 requests per minute available; falls back to say", usageReport(""))
+}
+
+func TestProviderLimitsValidation(t *testing.T) {
+	assert.EqualError(t, providerLimits{Fallback: "polly"}.validate("openai"), `unknown fallback provider "polly" (supported: elevenlabs, google, openai, say)`)
+	assert.EqualError(t, providerLimits{Fallback: "openai"}.validate("openai"), "a provider cannot fall back to itself")
+	assert.EqualError(t, providerLimits{DailyChars: -1}.validate("openai"), "limits must not be negative")
+	assert.NoError(t, providerLimits{DailyChars: 10, Fallback: "say"}.validate("openai"))
+}
@@ -0,0 +1,378 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/charmbracelet/log"
+	"github.com/gopxl/beep/v2/mp3"
+)
+
+const (
+	// maxCueChars keeps each caption to roughly two 42-character lines.
+	maxCueChars = 84
+	// defaultWordsPerMinute is used to estimate duration when no audio can be measured.
+	defaultWordsPerMinute = 160
+)
+
+// captionCue is a single timed caption.
+type captionCue struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+// captionsEnabled reports whether captions should be written for a call.
+// The per-call option overrides --captions; captions need a saved audio file.
+func captionsEnabled(requested *bool) bool {
+	enabled := writeCaptions
+	if requested != nil {
+		enabled = *requested
+	}
+	return enabled && shouldSave()
+}
+
+// segmentRunes splits text into sentence-sized [start, end) ranges, breaking
+// long sentences at word boundaries so no cue exceeds maxCueChars.
+// Surrounding whitespace is excluded from each range.
+func segmentRunes(runes []rune) [][2]int {
+	var ranges [][2]int
+	flush := func(start, end int) {
+		for start < end && unicode.IsSpace(runes[start]) {
+			start++
+		}
+		for end > start && unicode.IsSpace(runes[end-1]) {
+			end--
+		}
+		if start < end {
+			ranges = append(ranges, [2]int{start, end})
+		}
+	}
+
+	start, lastSpace := 0, -1
+	for i, r := range runes {
+		if unicode.IsSpace(r) {
+			if i > start && (r == '\n' || strings.ContainsRune(".!?", runes[i-1])) {
+				flush(start, i)
+				start = i + 1
+				continue
+			}
+			lastSpace = i
+		}
+		if i-start+1 > maxCueChars && lastSpace > start {
+			flush(start, lastSpace)
+			start = lastSpace + 1
+		}
+	}
+	flush(start, len(runes))
+	return ranges
+}
+
+// cuesFromText spreads sentence segments across the total duration in
+// proportion to their length. Used when the provider returns no alignment.
+func cuesFromText(text string, total time.Duration) []captionCue {
+	runes := []rune(text)
+	ranges := segmentRunes(runes)
+	weight := 0
+	for _, r := range ranges {
+		weight += r[1] - r[0]
+	}
+	if weight == 0 || total <= 0 {
+		return nil
+	}
+
+	cues := make([]captionCue, 0, len(ranges))
+	elapsed := 0
+	for _, r := range ranges {
+		start := total * time.Duration(elapsed) / time.Duration(weight)
+		elapsed += r[1] - r[0]
+		end := total * time.Duration(elapsed) / time.Duration(weight)
+		cues = append(cues, captionCue{Start: start, End: end, Text: string(runes[r[0]:r[1]])})
+	}
+	return cues
+}
+
+// cuesFromAlignment builds cues from ElevenLabs character timings.
+func cuesFromAlignment(a *elevenLabsAlignment) []captionCue {
+	if a == nil {
+		return nil
+	}
+	var runes []rune
+	var starts, ends []float64
+	for i, ch := range a.Characters {
+		if i >= len(a.StartTimes) || i >= len(a.EndTimes) {
+			break
+		}
+		for _, r := range ch {
+			runes = append(runes, r)
+			starts = append(starts, a.StartTimes[i])
+			ends = append(ends, a.EndTimes[i])
+		}
+	}
+
+	seconds := func(s float64) time.Duration {
+		return time.Duration(s * float64(time.Second))
+	}
+	var cues []captionCue
+	for _, r := range segmentRunes(runes) {
+		cues = append(cues, captionCue{
+			Start: seconds(starts[r[0]]),
+			End:   seconds(ends[r[1]-1]),
+			Text:  string(runes[r[0]:r[1]]),
+		})
+	}
+	return cues
+}
+
+// appendAlignment adds a streamed chunk's timings. Chunks whose timings
+// restart from zero are shifted to follow the previous chunk.
+func (a *elevenLabsAlignment) appendAlignment(chunk *elevenLabsAlignment) {
+	if chunk == nil || len(chunk.StartTimes) == 0 {
+		return
+	}
+	offset := 0.0
+	if n := len(a.EndTimes); n > 0 && chunk.StartTimes[0] < a.EndTimes[n-1] {
+		offset = a.EndTimes[n-1]
+	}
+	a.Characters = append(a.Characters, chunk.Characters...)
+	for _, s := range chunk.StartTimes {
+		a.StartTimes = append(a.StartTimes, s+offset)
+	}
+	for _, e := range chunk.EndTimes {
+		a.EndTimes = append(a.EndTimes, e+offset)
+	}
+}
+
+// timestampedAudioReader turns a /stream/with-timestamps response into raw
+// audio bytes, collecting the character alignment as chunks arrive.
+type timestampedAudioReader struct {
+	dec       *json.Decoder
+	pending   []byte
+	alignment elevenLabsAlignment
+}
+
+func newTimestampedAudioReader(r io.Reader) *timestampedAudioReader {
+	return &timestampedAudioReader{dec: json.NewDecoder(r)}
+}
+
+func (t *timestampedAudioReader) Read(p []byte) (int, error) {
+	for len(t.pending) == 0 {
+		var chunk elevenLabsTimestampChunk
+		if err := t.dec.Decode(&chunk); err != nil {
+			return 0, err
+		}
+		audio, err := base64.StdEncoding.DecodeString(chunk.AudioBase64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decode audio chunk: %w", err)
+		}
+		t.pending = audio
+		t.alignment.appendAlignment(chunk.Alignment)
+	}
+	n := copy(p, t.pending)
+	t.pending = t.pending[n:]
+	return n, nil
+}
+
+// elevenLabsCues prefers the streamed character alignment and falls back to
+// sentence-length timings over the decoded MP3 duration. The alignment
+// follows the text sent to ElevenLabs, so when the lexicon or markup
+// rewrote it only its overall length is used for the displayed text.
+func elevenLabsCues(text string, timestamped *timestampedAudioReader, audio []byte, format string) []captionCue {
+	var cues []captionCue
+	if timestamped != nil {
+		a := &timestamped.alignment
+		if strings.Join(a.Characters, "") == text {
+			cues = cuesFromAlignment(a)
+		} else if n := len(a.EndTimes); n > 0 {
+			cues = cuesFromText(text, time.Duration(a.EndTimes[n-1]*float64(time.Second)))
+		}
+	}
+	if len(cues) == 0 {
+		var duration time.Duration
+		if isPlayableFormat(format) {
+			duration = mp3Duration(audio)
+		}
+		if duration <= 0 {
+			duration = estimateSpeechDuration(text, defaultWordsPerMinute)
+		}
+		cues = cuesFromText(text, duration)
+	}
+	return transcodedCues(cues, audio, format)
+}
+
+// transcodedCues moves cues timed against MP3 audio onto the WAV
+// transcoded from it, which loses its leading and trailing silence. Saved
+// MP3 and other native formats are kept as synthesized.
+func transcodedCues(cues []captionCue, audio []byte, format string) []captionCue {
+	if format != FormatWAV || !trimSilenceEnabled || len(cues) == 0 {
+		return cues
+	}
+	streamer, f, err := mp3.Decode(io.NopCloser(bytes.NewReader(audio)))
+	if err != nil {
+		return cues
+	}
+	defer streamer.Close()
+	var samples [][2]float64
+	buf := make([][2]float64, 4096)
+	for {
+		n, ok := streamer.Stream(buf)
+		samples = append(samples, buf[:n]...)
+		if !ok {
+			break
+		}
+	}
+	start, end := silenceBounds(len(samples), f.SampleRate.N(silenceMinDuration), func(i int) bool {
+		return !isSilent(samples[i], silenceThreshold)
+	})
+	return shiftCues(cues, f.SampleRate.D(start), f.SampleRate.D(end))
+}
+
+// shiftCues re-times cues for audio that kept only [start, end) of the
+// audio they were timed against, dropping cues that fall outside it.
+func shiftCues(cues []captionCue, start, end time.Duration) []captionCue {
+	shifted := make([]captionCue, 0, len(cues))
+	for _, cue := range cues {
+		cue.Start = min(max(cue.Start, start), end) - start
+		cue.End = min(max(cue.End, start), end) - start
+		if cue.End > cue.Start {
+			shifted = append(shifted, cue)
+		}
+	}
+	return shifted
+}
+
+// estimateSpeechDuration approximates how long text takes to speak.
+func estimateSpeechDuration(text string, wordsPerMinute float64) time.Duration {
+	if wordsPerMinute <= 0 {
+		wordsPerMinute = defaultWordsPerMinute
+	}
+	words := len(strings.Fields(text))
+	return time.Duration(float64(words) / wordsPerMinute * float64(time.Minute))
+}
+
+// mp3Duration decodes MP3 data to measure its length. Returns 0 if the data
+// cannot be decoded.
+func mp3Duration(data []byte) time.Duration {
+	streamer, format, err := mp3.Decode(io.NopCloser(bytes.NewReader(data)))
+	if err != nil {
+		return 0
+	}
+	defer streamer.Close()
+	samples := 0
+	buf := make([][2]float64, 4096)
+	for {
+		n, ok := streamer.Stream(buf)
+		samples += n
+		if !ok {
+			break
+		}
+	}
+	return format.SampleRate.D(samples)
+}
+
+// pcmDuration returns the length of 16-bit mono PCM at the given sample rate.
+func pcmDuration(data []byte, sampleRate int) time.Duration {
+	return time.Duration(len(data)/2) * time.Second / time.Duration(sampleRate)
+}
+
+// formatCueTimestamp formats d as HH:MM:SS<sep>mmm.
+func formatCueTimestamp(d time.Duration, sep string) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// writeSRT writes cues in SubRip format.
+func writeSRT(w io.Writer, cues []captionCue) error {
+	for i, cue := range cues {
+		if _, err := fmt.Fprintf(w, "%d\n%s --> %s\n%s\n\n", i+1,
+			formatCueTimestamp(cue.Start, ","), formatCueTimestamp(cue.End, ","), cue.Text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeVTT writes cues in WebVTT format.
+func writeVTT(w io.Writer, cues []captionCue) error {
+	if _, err := io.WriteString(w, "WEBVTT\n\n"); err != nil {
+		return err
+	}
+	for _, cue := range cues {
+		if _, err := fmt.Fprintf(w, "%s --> %s\n%s\n\n",
+			formatCueTimestamp(cue.Start, "."), formatCueTimestamp(cue.End, "."), cue.Text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveCaptions writes .srt and .vtt files next to the saved audio file.
+// Returns the paths of the written caption files.
+func saveCaptions(audioPath string, cues []captionCue) ([]string, error) {
+	if audioPath == "" || len(cues) == 0 {
+		return nil, nil
+	}
+	base := strings.TrimSuffix(audioPath, filepath.Ext(audioPath))
+	writers := []struct {
+		ext   string
+		write func(io.Writer, []captionCue) error
+	}{
+		{"srt", writeSRT},
+		{"vtt", writeVTT},
+	}
+
+	var paths []string
+	for _, w := range writers {
+		var buf bytes.Buffer
+		if err := w.write(&buf, cues); err != nil {
+			return paths, fmt.Errorf("failed to format %s captions: %w", strings.ToUpper(w.ext), err)
+		}
+		fpath := base + "." + w.ext
+		if err := os.WriteFile(fpath, buf.Bytes(), 0644); err != nil {
+			return paths, fmt.Errorf("failed to save %s captions: %w", strings.ToUpper(w.ext), err)
+		}
+		paths = append(paths, fpath)
+	}
+	return paths, nil
+}
+
+// writeCaptionFiles saves captions for a call, logging instead of failing the
+// request on error (like audio saving).
+func writeCaptionFiles(audioPath string, cues []captionCue) []string {
+	paths, err := saveCaptions(audioPath, cues)
+	if err != nil {
+		log.Error("Failed to save captions", "error", err)
+	} else if len(paths) > 0 {
+		log.Info("Captions saved", "paths", paths)
+	}
+	return paths
+}
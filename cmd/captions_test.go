@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCuesFromText(t *testing.T) {
+	t.Run("splits sentences and spreads duration by length", func(t *testing.T) {
+		cues := cuesFromText("Build done. Tests passed!", 10*time.Second)
+		require.Len(t, cues, 2)
+		assert.Equal(t, "Build done.", cues[0].Text)
+		assert.Equal(t, "Tests passed!", cues[1].Text)
+		assert.Equal(t, time.Duration(0), cues[0].Start)
+		assert.Equal(t, cues[0].End, cues[1].Start)
+		assert.Equal(t, 10*time.Second, cues[1].End)
+		// 11 of 24 characters belong to the first sentence
+		assert.Equal(t, 10*time.Second*11/24, cues[0].End)
+	})
+
+	t.Run("long sentences break at word boundaries", func(t *testing.T) {
+		text := strings.Repeat("word ", 40)
+		cues := cuesFromText(text, time.Minute)
+		require.Greater(t, len(cues), 1)
+		for _, cue := range cues {
+			assert.LessOrEqual(t, len(cue.Text), maxCueChars)
+			assert.False(t, strings.HasPrefix(cue.Text, " "))
+			assert.False(t, strings.HasSuffix(cue.Text, " "))
+		}
+	})
+
+	t.Run("decimal numbers do not end a sentence", func(t *testing.T) {
+		cues := cuesFromText("Version 1.2 shipped.", time.Second)
+		require.Len(t, cues, 1)
+	})
+
+	t.Run("empty text or duration yields no cues", func(t *testing.T) {
+		assert.Empty(t, cuesFromText("   ", time.Second))
+		assert.Empty(t, cuesFromText("hello", 0))
+	})
+}
+
+func TestCuesFromAlignment(t *testing.T) {
+	text := "Hi. Bye."
+	a := &elevenLabsAlignment{}
+	for i, ch := range text {
+		a.Characters = append(a.Characters, string(ch))
+		a.StartTimes = append(a.StartTimes, float64(i)*0.1)
+		a.EndTimes = append(a.EndTimes, float64(i+1)*0.1)
+	}
+
+	cues := cuesFromAlignment(a)
+	require.Len(t, cues, 2)
+	assert.Equal(t, "Hi.", cues[0].Text)
+	assert.Equal(t, time.Duration(0), cues[0].Start)
+	assert.InDelta(t, 300*time.Millisecond, cues[0].End, float64(time.Millisecond))
+	assert.Equal(t, "Bye.", cues[1].Text)
+	assert.InDelta(t, 400*time.Millisecond, cues[1].Start, float64(time.Millisecond))
+	assert.InDelta(t, 800*time.Millisecond, cues[1].End, float64(time.Millisecond))
+
+	assert.Empty(t, cuesFromAlignment(nil))
+}
+
+func TestElevenLabsCues(t *testing.T) {
+	alignment := func(text string) *timestampedAudioReader {
+		r := &timestampedAudioReader{}
+		for i, ch := range text {
+			r.alignment.Characters = append(r.alignment.Characters, string(ch))
+			r.alignment.StartTimes = append(r.alignment.StartTimes, float64(i)*0.1)
+			r.alignment.EndTimes = append(r.alignment.EndTimes, float64(i+1)*0.1)
+		}
+		return r
+	}
+
+	cues := elevenLabsCues("Hi. Bye.", alignment("Hi. Bye."), nil, FormatMP3)
+	require.Len(t, cues, 2)
+	assert.InDelta(t, 400*time.Millisecond, cues[1].Start, float64(time.Millisecond), "matching text uses the character timings")
+
+	// The lexicon expanded "SQL" to "sequel" before synthesis
+	cues = elevenLabsCues("SQL. Done.", alignment("sequel. Done."), nil, FormatMP3)
+	require.Len(t, cues, 2)
+	assert.Equal(t, "SQL.", cues[0].Text)
+	assert.Equal(t, "Done.", cues[1].Text)
+	assert.InDelta(t, 1300*time.Millisecond, cues[1].End, float64(time.Millisecond), "rewritten text keeps the aligned duration")
+}
+
+func TestShiftCues(t *testing.T) {
+	cues := []captionCue{
+		{Start: 0, End: 500 * time.Millisecond, Text: "a"},
+		{Start: 600 * time.Millisecond, End: 1200 * time.Millisecond, Text: "b"},
+		{Start: 1900 * time.Millisecond, End: 2000 * time.Millisecond, Text: "c"},
+	}
+	shifted := shiftCues(cues, 200*time.Millisecond, 1800*time.Millisecond)
+	assert.Equal(t, []captionCue{
+		{Start: 0, End: 300 * time.Millisecond, Text: "a"},
+		{Start: 400 * time.Millisecond, End: 1000 * time.Millisecond, Text: "b"},
+	}, shifted, "cues follow the trimmed lead and stop at the trimmed end")
+
+	assert.Equal(t, cues, transcodedCues(cues, nil, FormatMP3), "saved MP3 is not trimmed")
+}
+
+func TestTimestampedAudioReader(t *testing.T) {
+	var stream bytes.Buffer
+	enc := json.NewEncoder(&stream)
+	require.NoError(t, enc.Encode(elevenLabsTimestampChunk{
+		AudioBase64: base64.StdEncoding.EncodeToString([]byte("abc")),
+		Alignment: &elevenLabsAlignment{
+			Characters: []string{"H", "i"},
+			StartTimes: []float64{0, 0.1},
+			EndTimes:   []float64{0.1, 0.2},
+		},
+	}))
+	// Second chunk restarts its timings at zero
+	require.NoError(t, enc.Encode(elevenLabsTimestampChunk{
+		AudioBase64: base64.StdEncoding.EncodeToString([]byte("def")),
+		Alignment: &elevenLabsAlignment{
+			Characters: []string{"!"},
+			StartTimes: []float64{0},
+			EndTimes:   []float64{0.1},
+		},
+	}))
+
+	r := newTimestampedAudioReader(&stream)
+	audio, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "abcdef", string(audio))
+	assert.Equal(t, []string{"H", "i", "!"}, r.alignment.Characters)
+	assert.InDeltaSlice(t, []float64{0, 0.1, 0.2}, r.alignment.StartTimes, 1e-9)
+	assert.InDeltaSlice(t, []float64{0.1, 0.2, 0.3}, r.alignment.EndTimes, 1e-9)
+
+	t.Run("invalid audio is an error", func(t *testing.T) {
+		r := newTimestampedAudioReader(strings.NewReader(`{"audio_base64":"%%%"}`))
+		_, err := io.ReadAll(r)
+		require.Error(t, err)
+	})
+}
+
+func TestCaptionFormats(t *testing.T) {
+	cues := []captionCue{
+		{Start: 0, End: 1500 * time.Millisecond, Text: "Hello."},
+		{Start: 1500 * time.Millisecond, End: time.Hour + 2*time.Minute + 3*time.Second + 45*time.Millisecond, Text: "World."},
+	}
+
+	var srt bytes.Buffer
+	require.NoError(t, writeSRT(&srt, cues))
+	assert.Equal(t, "1\n00:00:00,000 --> 00:00:01,500\nHello.\n\n"+
+		"2\n00:00:01,500 --> 01:02:03,045\nWorld.\n\n", srt.String())
+
+	var vtt bytes.Buffer
+	require.NoError(t, writeVTT(&vtt, cues))
+	assert.Equal(t, "WEBVTT\n\n"+
+		"00:00:00.000 --> 00:00:01.500\nHello.\n\n"+
+		"00:00:01.500 --> 01:02:03.045\nWorld.\n\n", vtt.String())
+}
+
+func TestSaveCaptions(t *testing.T) {
+	dir := t.TempDir()
+	audioPath := filepath.Join(dir, "tts_1_abcd.mp3")
+	cues := []captionCue{{Start: 0, End: time.Second, Text: "Hello."}}
+
+	paths, err := saveCaptions(audioPath, cues)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "tts_1_abcd.srt"),
+		filepath.Join(dir, "tts_1_abcd.vtt"),
+	}, paths)
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "Hello.")
+	}
+
+	t.Run("nothing saved without audio or cues", func(t *testing.T) {
+		paths, err := saveCaptions("", cues)
+		require.NoError(t, err)
+		assert.Empty(t, paths)
+		paths, err = saveCaptions(audioPath, nil)
+		require.NoError(t, err)
+		assert.Empty(t, paths)
+	})
+}
+
+func TestCaptionsEnabled(t *testing.T) {
+	origOutputDir := outputDir
+	origWriteCaptions := writeCaptions
+	defer func() {
+		outputDir = origOutputDir
+		writeCaptions = origWriteCaptions
+	}()
+	yes, no := true, false
+
+	outputDir = ""
+	writeCaptions = true
+	assert.False(t, captionsEnabled(&yes), "captions need saved audio")
+
+	outputDir = t.TempDir()
+	assert.True(t, captionsEnabled(nil))
+	assert.False(t, captionsEnabled(&no))
+	writeCaptions = false
+	assert.True(t, captionsEnabled(&yes))
+}
+
+func TestEstimateSpeechDuration(t *testing.T) {
+	assert.Equal(t, 3*time.Second, estimateSpeechDuration("one two three four five six seven eight", 160))
+	assert.Equal(t, estimateSpeechDuration("a b", defaultWordsPerMinute), estimateSpeechDuration("a b", 0))
+	assert.Equal(t, time.Second, pcmDuration(make([]byte, 48000), 24000))
+}
+
+func TestFormatSaveResultWithCaptions(t *testing.T) {
+	origSuppress := suppressSpeakingOutput
+	defer func() { suppressSpeakingOutput = origSuppress }()
+	suppressSpeakingOutput = false
+
+	result := formatSaveResult("hi", "/tmp/a.mp3", false, "/tmp/a.srt", "/tmp/a.vtt")
+	assert.Equal(t, "Saved: /tmp/a.mp3\nCaptions: /tmp/a.srt, /tmp/a.vtt", result)
+}
@@ -0,0 +1,383 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/mp3"
+	"github.com/gopxl/beep/v2/speaker"
+	"github.com/gopxl/beep/v2/wav"
+)
+
+// Message categories accepted by the category tool argument. Each maps to a
+// voice, speed, speaking style, chime and queue priority.
+const (
+	CategoryInfo     = "info"
+	CategorySuccess  = "success"
+	CategoryWarning  = "warning"
+	CategoryError    = "error"
+	CategorySummary  = "summary"
+	CategoryQuestion = "question"
+)
+
+// Categories lists the accepted values of the category tool argument.
+var Categories = []string{CategoryInfo, CategorySuccess, CategoryWarning, CategoryError, CategorySummary, CategoryQuestion}
+
+// Queue priorities. Waiting speech with a higher priority is spoken first.
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+)
+
+// Priorities lists the accepted priorities, lowest first.
+var Priorities = []string{PriorityLow, PriorityNormal, PriorityHigh}
+
+// Built-in chimes, synthesized rather than read from a file.
+const (
+	ChimeNone  = "none"
+	ChimeSoft  = "soft"
+	ChimeAlert = "alert"
+)
+
+// chimeSampleRate matches the OpenAI and Google output so chimes rarely force resampling.
+const chimeSampleRate = beep.SampleRate(24000)
+
+// categorySettings describe how messages of one category are delivered.
+type categorySettings struct {
+	Voices   map[string]string `yaml:"voices,omitempty"` // provider name -> voice
+	Speed    float64           `yaml:"speed,omitempty"`  // OpenAI speed; multiplies the say rate
+	Style    string            `yaml:"style,omitempty"`  // OpenAI instructions and Gemini style prompt
+	Chime    string            `yaml:"chime,omitempty"`  // soft, alert, none or a .wav/.mp3 file
+	Priority string            `yaml:"priority,omitempty"`
+	Confirm  bool              `yaml:"confirm,omitempty"` // ask the user before speaking
+
+	// builtinStyle is set when Style is the built-in one, which gives way to
+	// OpenAI instructions set in the environment or a config file
+	builtinStyle bool
+}
+
+// builtinCategories are the category settings used when nothing else is configured.
+var builtinCategories = map[string]categorySettings{
+	CategoryInfo: {
+		Style:    "Speak in a clear, neutral tone.",
+		Priority: PriorityNormal,
+	},
+	CategorySuccess: {
+		Style:    "Speak warmly and upbeat, like celebrating a job well done.",
+		Chime:    ChimeSoft,
+		Priority: PriorityNormal,
+	},
+	CategoryWarning: {
+		Style:    "Speak with calm urgency, flagging something that needs attention.",
+		Chime:    ChimeAlert,
+		Priority: PriorityHigh,
+	},
+	CategoryError: {
+		Style:    "Speak seriously and directly about the problem that was found.",
+		Chime:    ChimeAlert,
+		Priority: PriorityHigh,
+	},
+	CategorySummary: {
+		Style:    "Speak with quiet satisfaction, wrapping up completed work.",
+		Priority: PriorityNormal,
+	},
+	CategoryQuestion: {
+		Style:    "Speak in a curious, inviting tone, as if asking for input.",
+		Chime:    ChimeSoft,
+		Priority: PriorityHigh,
+	},
+}
+
+// merge overlays the non-zero fields of src, recording source for each one.
+func (c *categorySettings) merge(src categorySettings, source string, sources map[string]string) {
+	set := func(field string) {
+		if sources != nil {
+			sources[field] = source
+		}
+	}
+	for provider, voice := range src.Voices {
+		if c.Voices == nil {
+			c.Voices = make(map[string]string)
+		}
+		c.Voices[provider] = voice
+		set("voices." + provider)
+	}
+	if src.Speed != 0 {
+		c.Speed = src.Speed
+		set("speed")
+	}
+	if src.Style != "" {
+		c.Style = src.Style
+		set("style")
+	}
+	if src.Chime != "" {
+		c.Chime = src.Chime
+		set("chime")
+	}
+	if src.Priority != "" {
+		c.Priority = src.Priority
+		set("priority")
+	}
+	if src.Confirm {
+		c.Confirm = true
+		set("confirm")
+	}
+}
+
+// validate checks the settings configured for a category.
+func (c categorySettings) validate() error {
+	for provider := range c.Voices {
+		if _, ok := providerConfigKeys[provider]; !ok {
+			return fmt.Errorf("unknown provider %q in voices", provider)
+		}
+	}
+	if c.Speed < 0 {
+		return fmt.Errorf("speed must be positive")
+	}
+	if c.Priority != "" && !slices.Contains(Priorities, c.Priority) {
+		return fmt.Errorf("unknown priority %q (supported: %s)", c.Priority, strings.Join(Priorities, ", "))
+	}
+	switch c.Chime {
+	case "", ChimeNone, ChimeSoft, ChimeAlert:
+	default:
+		if ext := strings.ToLower(filepath.Ext(c.Chime)); ext != ".wav" && ext != ".mp3" {
+			return fmt.Errorf("chime must be %s, %s, %s or a .wav/.mp3 file", ChimeSoft, ChimeAlert, ChimeNone)
+		}
+	}
+	return nil
+}
+
+// voice returns the category's voice for a provider, or "".
+func (c categorySettings) voice(providerID string) string {
+	return c.Voices[providerKey(providerID)]
+}
+
+// priorityRank orders priorities for the speech queue.
+func (c categorySettings) priorityRank() int {
+	if i := slices.Index(Priorities, c.Priority); i >= 0 {
+		return i
+	}
+	return slices.Index(Priorities, PriorityNormal)
+}
+
+// categorySettings resolves a category under a profile, with the source of each field.
+func (c *appConfig) categorySettings(category, profile string) (categorySettings, map[string]string) {
+	sources := make(map[string]string)
+	var settings categorySettings
+	settings.merge(builtinCategories[category], sourceDefault, sources)
+	for _, layer := range c.settingLayers(profile) {
+		settings.merge(layer.File.Categories[category], layer.Name, sources)
+	}
+	return settings, sources
+}
+
+// categoryFor resolves the delivery settings for a call. Calls without a
+// category get zero settings, which change nothing.
+func categoryFor(category *string, profile string) (categorySettings, error) {
+	if category == nil || *category == "" {
+		return categorySettings{}, nil
+	}
+	if !slices.Contains(Categories, *category) {
+		return categorySettings{}, fmt.Errorf("unknown category %q (supported: %s)", *category, strings.Join(Categories, ", "))
+	}
+	settings, sources := activeConfig.categorySettings(*category, profile)
+	settings.builtinStyle = sources["style"] == sourceDefault
+	return settings, nil
+}
+
+// applySay fills omitted say settings from the category. Its speed scales
+// the default rate.
+func (c categorySettings) applySay(input *SayTTSParams, defaults providerSettings) {
+	if input.Voice == nil {
+		if v := c.voice(ProviderSay); v != "" {
+			input.Voice = &v
+		}
+	}
+	if input.Rate == nil && c.Speed != 0 {
+		rate := int(math.Round(float64(defaults.Rate) * c.Speed))
+		input.Rate = &rate
+	}
+}
+
+// applyGoogle fills an omitted Google voice from the category.
+func (c categorySettings) applyGoogle(input *GoogleTTSParams) {
+	if input.Voice == nil {
+		if v := c.voice(ProviderGoogle); v != "" {
+			input.Voice = &v
+		}
+	}
+}
+
+// applyOpenAI fills omitted OpenAI settings from the category. The style
+// becomes the instructions unless the model takes none (tts-1) or the
+// built-in style meets instructions from the configured defaults.
+func (c categorySettings) applyOpenAI(input *OpenAITTSParams, defaults providerSettings) {
+	if input.Voice == nil {
+		if v := c.voice(ProviderOpenAI); v != "" {
+			input.Voice = &v
+		}
+	}
+	if input.Speed == nil && c.Speed != 0 {
+		input.Speed = &c.Speed
+	}
+	model := defaults.Model
+	if input.Model != nil && *input.Model != "" {
+		model = *input.Model
+	}
+	switch {
+	case input.Instructions != nil || c.Style == "":
+	case strings.HasPrefix(model, "tts-1"):
+	case c.builtinStyle && defaults.Instructions != "":
+	default:
+		input.Instructions = &c.Style
+	}
+}
+
+// playChime plays the category's chime before speech. Failures are logged,
+// never returned, so a missing sound file does not block the message.
+func playChime(ctx context.Context, chime string) {
+	if chime == "" || chime == ChimeNone || !shouldPlay() {
+		return
+	}
+	streamer, sampleRate, err := chimeStreamer(chime)
+	if err != nil {
+		log.Warn("Failed to load chime", "chime", chime, "error", err)
+		return
+	}
+	if err := initSpeaker(sampleRate); err != nil {
+		log.Warn("Failed to initialize speaker for chime", "error", err)
+		return
+	}
+	done := make(chan struct{})
+	speaker.Play(beep.Seq(resampleToSpeaker(streamer, sampleRate), beep.Callback(func() {
+		close(done)
+	})))
+	select {
+	case <-done:
+	case <-ctx.Done():
+		speaker.Clear()
+	}
+}
+
+// chimeStreamer returns a built-in chime or decodes a chime file.
+func chimeStreamer(chime string) (beep.Streamer, beep.SampleRate, error) {
+	switch chime {
+	case ChimeSoft:
+		return beep.Seq(
+			chimeTone(chimeSampleRate, 880, 110*time.Millisecond),
+			chimeTone(chimeSampleRate, 1320, 160*time.Millisecond),
+		), chimeSampleRate, nil
+	case ChimeAlert:
+		return beep.Seq(
+			chimeTone(chimeSampleRate, 660, 120*time.Millisecond),
+			chimeTone(chimeSampleRate, 440, 120*time.Millisecond),
+			chimeTone(chimeSampleRate, 660, 120*time.Millisecond),
+			chimeTone(chimeSampleRate, 440, 160*time.Millisecond),
+		), chimeSampleRate, nil
+	}
+
+	path := chime
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, rest)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	var streamer beep.StreamSeekCloser
+	var format beep.Format
+	if strings.EqualFold(filepath.Ext(path), ".mp3") {
+		streamer, format, err = mp3.Decode(io.NopCloser(bytes.NewReader(data)))
+	} else {
+		streamer, format, err = wav.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return streamer, format.SampleRate, nil
+}
+
+// chimeTone is a quiet sine tone with a short fade in and out to avoid clicks.
+func chimeTone(sampleRate beep.SampleRate, freq float64, d time.Duration) beep.Streamer {
+	const amplitude = 0.25
+	total := sampleRate.N(d)
+	fade := sampleRate.N(10 * time.Millisecond)
+	pos := 0
+	return beep.StreamerFunc(func(samples [][2]float64) (int, bool) {
+		if pos >= total {
+			return 0, false
+		}
+		n := min(len(samples), total-pos)
+		for i := range n {
+			gain := amplitude
+			if p := pos + i; p < fade {
+				gain *= float64(p) / float64(fade)
+			} else if p > total-fade {
+				gain *= float64(total-p) / float64(fade)
+			}
+			v := gain * math.Sin(2*math.Pi*freq*float64(pos+i)/float64(sampleRate))
+			samples[i] = [2]float64{v, v}
+		}
+		pos += n
+		return n, true
+	})
+}
+
+// field returns a setting formatted for display, or "" when unset.
+func (c categorySettings) field(name string) string {
+	if provider, ok := strings.CutPrefix(name, "voices."); ok {
+		return c.Voices[provider]
+	}
+	switch name {
+	case "speed":
+		if c.Speed != 0 {
+			return strconv.FormatFloat(c.Speed, 'f', -1, 64)
+		}
+	case "style":
+		return c.Style
+	case "chime":
+		return c.Chime
+	case "priority":
+		return c.Priority
+	case "confirm":
+		if c.Confirm {
+			return "true"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/gopxl/beep/v2/wav"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCategoryFor(t *testing.T) {
+	oldConfig := activeConfig
+	defer func() { activeConfig = oldConfig }()
+	activeConfig = &appConfig{}
+
+	settings, err := categoryFor(nil, "")
+	require.NoError(t, err)
+	assert.Equal(t, categorySettings{}, settings, "calls without a category are unchanged")
+	assert.Equal(t, 1, settings.priorityRank(), "no category is normal priority")
+
+	settings, err = categoryFor(stringPtr(CategoryError), "")
+	require.NoError(t, err)
+	assert.Equal(t, ChimeAlert, settings.Chime)
+	assert.Equal(t, PriorityHigh, settings.Priority)
+	assert.NotEmpty(t, settings.Style)
+	assert.True(t, settings.builtinStyle)
+
+	_, err = categoryFor(stringPtr("gossip"), "")
+	assert.ErrorContains(t, err, `unknown category "gossip"`)
+
+	for _, category := range Categories {
+		assert.Contains(t, builtinCategories, category)
+		assert.Contains(t, categoryVoiceRoles, category)
+	}
+}
+
+func TestConfigCategorySettings(t *testing.T) {
+	dir := t.TempDir()
+	user := writeTestFile(t, dir, configFileName, `
+categories:
+  error:
+    chime: none
+    speed: 1.2
+    voices:
+      openai: onyx
+profiles:
+  demo:
+    categories:
+      error:
+        priority: low
+        voices:
+          google: Fenrir
+`)
+	cfg, err := loadConfig(user, "")
+	require.NoError(t, err)
+
+	settings, sources := cfg.categorySettings(CategoryError, "demo")
+	assert.Equal(t, ChimeNone, settings.Chime)
+	assert.Equal(t, 1.2, settings.Speed)
+	assert.Equal(t, PriorityLow, settings.Priority)
+	assert.Equal(t, "onyx", settings.voice(ProviderOpenAI))
+	assert.Equal(t, "Fenrir", settings.voice(ProviderGoogle))
+	assert.Equal(t, builtinCategories[CategoryError].Style, settings.Style)
+
+	assert.Equal(t, "user config", sources["chime"])
+	assert.Equal(t, "profile demo (user config)", sources["priority"])
+	assert.Equal(t, "profile demo (user config)", sources["voices.google"])
+	assert.Equal(t, sourceDefault, sources["style"])
+
+	t.Run("invalid settings are rejected", func(t *testing.T) {
+		for content, want := range map[string]string{
+			"categories:\n  gossip: {chime: soft}\n":          `unknown category "gossip"`,
+			"categories:\n  error: {priority: urgent}\n":      `category error: unknown priority "urgent"`,
+			"categories:\n  error: {chime: beep.ogg}\n":       "chime must be",
+			"categories:\n  error: {voices: {polly: Joanna}}": `unknown provider "polly" in voices`,
+		} {
+			bad := writeTestFile(t, t.TempDir(), configFileName, content)
+			_, err := loadConfig(bad, "")
+			assert.ErrorContains(t, err, want)
+		}
+	})
+}
+
+func TestCategoryApply(t *testing.T) {
+	settings := categorySettings{
+		Voices: map[string]string{"say": "Zoe (Premium)", "openai": "onyx"},
+		Speed:  1.25,
+		Style:  "Be urgent.",
+	}
+
+	say := SayTTSParams{Text: "hi"}
+	settings.applySay(&say, providerSettings{Rate: DefaultSayRate})
+	assert.Equal(t, "Zoe (Premium)", *say.Voice)
+	assert.Equal(t, 250, *say.Rate, "speed scales the default rate")
+
+	openAI := OpenAITTSParams{Text: "hi", Speed: float64Ptr(0.8)}
+	settings.applyOpenAI(&openAI, providerSettings{Model: DefaultOpenAIModel})
+	assert.Equal(t, "onyx", *openAI.Voice)
+	assert.Equal(t, 0.8, *openAI.Speed, "explicit arguments are kept")
+	assert.Equal(t, "Be urgent.", *openAI.Instructions)
+
+	tts1 := OpenAITTSParams{Text: "hi", Model: stringPtr("tts-1-hd")}
+	settings.applyOpenAI(&tts1, providerSettings{Model: DefaultOpenAIModel})
+	assert.Nil(t, tts1.Instructions, "tts-1 models take no instructions")
+
+	configured := OpenAITTSParams{Text: "hi"}
+	settings.applyOpenAI(&configured, providerSettings{Model: DefaultOpenAIModel, Instructions: "Be calm."})
+	assert.Equal(t, "Be urgent.", *configured.Instructions, "a configured style wins over provider instructions")
+
+	builtin := settings
+	builtin.builtinStyle = true
+	configured = OpenAITTSParams{Text: "hi"}
+	builtin.applyOpenAI(&configured, providerSettings{Model: DefaultOpenAIModel, Instructions: "Be calm."})
+	assert.Nil(t, configured.Instructions, "instructions from the environment or config win over the built-in style")
+	fillOpenAIParams(&configured, providerSettings{Instructions: "Be calm."})
+	assert.Equal(t, "Be calm.", *configured.Instructions)
+
+	google := GoogleTTSParams{Text: "hi"}
+	settings.applyGoogle(&google)
+	assert.Nil(t, google.Voice, "no Google voice configured")
+}
+
+func TestGoogleInputStyle(t *testing.T) {
+	oldLexicon := activeLexicon
+	defer func() { activeLexicon = oldLexicon }()
+	activeLexicon = nil
+
+	assert.Equal(t, "Speak calmly. Read the following text aloud:\nhello", googleInput("hello", nil, "Speak calmly."))
+	assert.Equal(t, "hello", googleInput("hello", nil, ""))
+}
+
+func TestChimes(t *testing.T) {
+	tone := chimeTone(chimeSampleRate, 440, 100*time.Millisecond)
+	samples := make([][2]float64, chimeSampleRate.N(time.Second))
+	n, ok := tone.Stream(samples)
+	assert.True(t, ok)
+	assert.Equal(t, chimeSampleRate.N(100*time.Millisecond), n)
+	assert.Zero(t, samples[0][0], "tones fade in")
+	for _, s := range samples[:n] {
+		assert.LessOrEqual(t, s[0], 0.25)
+	}
+	_, ok = tone.Stream(samples)
+	assert.False(t, ok)
+
+	for _, chime := range []string{ChimeSoft, ChimeAlert} {
+		_, sampleRate, err := chimeStreamer(chime)
+		require.NoError(t, err)
+		assert.Equal(t, chimeSampleRate, sampleRate)
+	}
+
+	t.Run("wav files", func(t *testing.T) {
+		fpath := filepath.Join(t.TempDir(), "ding.wav")
+		f, err := os.Create(fpath)
+		require.NoError(t, err)
+		format := beep.Format{SampleRate: 22050, NumChannels: 2, Precision: 2}
+		require.NoError(t, wav.Encode(f, chimeTone(22050, 880, 50*time.Millisecond), format))
+		require.NoError(t, f.Close())
+
+		_, sampleRate, err := chimeStreamer(fpath)
+		require.NoError(t, err)
+		assert.Equal(t, beep.SampleRate(22050), sampleRate)
+	})
+
+	t.Run("missing files are an error", func(t *testing.T) {
+		_, _, err := chimeStreamer(filepath.Join(t.TempDir(), "nope.wav"))
+		assert.Error(t, err)
+	})
+}
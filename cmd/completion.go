@@ -0,0 +1,144 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxCompletions is the most values a completion may return
+const maxCompletions = 100
+
+// completionProviders returns the providers named by a "provider" argument,
+// or all of them, keeping those whose tool accepts the argument.
+func completionProviders(provider, argument string) []string {
+	providers := []string{ProviderSay, ProviderElevenLabs, ProviderGoogle, ProviderOpenAI}
+	if id, ok := providerConfigKeys[provider]; ok {
+		providers = []string{id}
+	} else if slices.Contains(providers, provider) {
+		providers = []string{provider}
+	}
+	return slices.DeleteFunc(providers, func(id string) bool {
+		return !slices.Contains(promptToolArguments[id], argument)
+	})
+}
+
+// voiceCompletions lists the voices a provider offers.
+func voiceCompletions(providerID string) []string {
+	switch providerID {
+	case ProviderSay:
+		voices, err := getInstalledVoices()
+		if err != nil {
+			return nil
+		}
+		return slices.Sorted(maps.Keys(voices))
+	case ProviderGoogle:
+		return GoogleVoices
+	case ProviderOpenAI:
+		return OpenAIVoices
+	}
+	return nil
+}
+
+// modelCompletions lists the models a provider offers.
+func modelCompletions(providerID string) []string {
+	switch providerID {
+	case ProviderGoogle:
+		return GoogleModels
+	case ProviderOpenAI:
+		return OpenAIModels
+	}
+	return nil
+}
+
+// argumentCompletions lists every known value of an argument. A provider
+// among the already resolved arguments narrows voices and models to it.
+func argumentCompletions(name string, resolved map[string]string) []string {
+	var values []string
+	switch name {
+	case "voice":
+		for _, id := range completionProviders(resolved["provider"], name) {
+			values = append(values, voiceCompletions(id)...)
+		}
+	case "model":
+		for _, id := range completionProviders(resolved["provider"], name) {
+			values = append(values, modelCompletions(id)...)
+		}
+	case "profile":
+		values = activeConfig.ProfileNames()
+	case "category":
+		values = Categories
+	case "provider":
+		values = slices.Sorted(maps.Keys(providerConfigKeys))
+	}
+	return values
+}
+
+// filterCompletions keeps the values matching what the user has typed so
+// far, ignoring case: prefix matches first, then any other match.
+func filterCompletions(values []string, typed string) []string {
+	typed = strings.ToLower(typed)
+	var prefix, contains []string
+	seen := make(map[string]bool)
+	for _, v := range values {
+		lower := strings.ToLower(v)
+		switch {
+		case seen[v]:
+		case strings.HasPrefix(lower, typed):
+			prefix = append(prefix, v)
+		case strings.Contains(lower, typed):
+			contains = append(contains, v)
+		default:
+			continue
+		}
+		seen[v] = true
+	}
+	return append(prefix, contains...)
+}
+
+// completeArgument answers completion/complete for the voice, model,
+// profile, category and provider arguments of the server's prompts. The
+// server has no resource templates, so other references complete nothing.
+func completeArgument(ctx context.Context, req *mcp.CompleteRequest) (*mcp.CompleteResult, error) {
+	var resolved map[string]string
+	if req.Params.Context != nil {
+		resolved = req.Params.Context.Arguments
+	}
+	var matches []string
+	if ref := req.Params.Ref; ref != nil && ref.Type == "ref/prompt" && promptTakes(ref.Name, req.Params.Argument.Name) {
+		matches = filterCompletions(argumentCompletions(req.Params.Argument.Name, resolved), req.Params.Argument.Value)
+	}
+	result := &mcp.CompleteResult{Completion: mcp.CompletionResultDetails{Values: matches, Total: len(matches)}}
+	if len(matches) > maxCompletions {
+		result.Completion.Values = matches[:maxCompletions]
+		result.Completion.HasMore = true
+	}
+	if result.Completion.Values == nil {
+		result.Completion.Values = []string{}
+	}
+	return result, nil
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterCompletions(t *testing.T) {
+	values := []string{"Kore", "Puck", "Sadachbia", "Sadaltager", "Achernar", "kore"}
+	assert.Equal(t, []string{"Sadachbia", "Sadaltager"}, filterCompletions(values, "sad"))
+	assert.Equal(t, []string{"Achernar", "Sadachbia"}, filterCompletions(values, "ach"), "prefix matches come first")
+	assert.Equal(t, []string{"Kore", "kore"}, filterCompletions(values, "KO"))
+	assert.Equal(t, values, filterCompletions(values, ""))
+	assert.Empty(t, filterCompletions(values, "zzz"))
+}
+
+func TestCompleteArgument(t *testing.T) {
+	useTestLimits(t, `
+profiles:
+  quiet: {}
+  demo: {}
+`)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "mcp-tts"}, &mcp.ServerOptions{CompletionHandler: completeArgument})
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	ctx := context.Background()
+	_, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "test-client"}, nil).Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	completeRef := func(ref *mcp.CompleteReference, name, value string, resolved map[string]string) mcp.CompletionResultDetails {
+		t.Helper()
+		result, err := session.Complete(ctx, &mcp.CompleteParams{
+			Ref:      ref,
+			Argument: mcp.CompleteParamsArgument{Name: name, Value: value},
+			Context:  &mcp.CompleteContext{Arguments: resolved},
+		})
+		require.NoError(t, err)
+		return result.Completion
+	}
+	complete := func(name, value string, resolved map[string]string) mcp.CompletionResultDetails {
+		t.Helper()
+		return completeRef(&mcp.CompleteReference{Type: "ref/prompt", Name: "speak_text"}, name, value, resolved)
+	}
+
+	assert.Equal(t, []string{"Fenrir"}, complete("voice", "fen", map[string]string{"provider": "google"}).Values)
+	assert.Empty(t, complete("voice", "", map[string]string{"provider": "elevenlabs"}).Values, "the elevenlabs tool takes no voice")
+	assert.Equal(t, []string{"onyx"}, complete("voice", "ony", nil).Values, "voices of every provider without one")
+	assert.Empty(t, complete("voice", "fen", map[string]string{"provider": "openai_tts"}).Values, "provider tool names work too")
+	assert.Equal(t, []string{"tts-1", "tts-1-hd", "gpt-4o-mini-tts-2025-12-15"}, complete("model", "tts", map[string]string{"provider": "openai"}).Values)
+	assert.Empty(t, complete("model", "eleven_v", nil).Values, "the elevenlabs tool takes no model")
+	assert.Empty(t, complete("model", "", map[string]string{"provider": "say"}).Values)
+	assert.Equal(t, []string{"demo", "quiet"}, complete("profile", "", nil).Values)
+	assert.Equal(t, []string{"success", "summary", "question"}, complete("category", "s", nil).Values)
+	assert.Equal(t, []string{"google"}, complete("provider", "go", nil).Values)
+
+	unknown := complete("text", "hello", nil)
+	assert.NotNil(t, unknown.Values)
+	assert.Empty(t, unknown.Values)
+
+	assert.Equal(t, []string{"google"}, completeRef(&mcp.CompleteReference{Type: "ref/prompt", Name: "announce_summary"}, "provider", "go", nil).Values)
+	assert.Empty(t, completeRef(&mcp.CompleteReference{Type: "ref/prompt", Name: "announce_summary"}, "voice", "ony", nil).Values, "the prompt takes no voice")
+	assert.Empty(t, completeRef(&mcp.CompleteReference{Type: "ref/prompt", Name: "nope"}, "voice", "ony", nil).Values)
+	assert.Empty(t, completeRef(&mcp.CompleteReference{Type: "ref/resource", URI: "tts://voices/{voice}"}, "voice", "ony", nil).Values)
+
+	all := complete("voice", "", map[string]string{"provider": "google"})
+	assert.Len(t, all.Values, len(GoogleVoices))
+	assert.Equal(t, len(GoogleVoices), all.Total)
+	assert.False(t, all.HasMore)
+}
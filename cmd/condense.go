@@ -0,0 +1,227 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/log"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// DefaultCondenseWords is the length condensed text aims for, about 20
+// seconds of speech.
+const DefaultCondenseWords = 60
+
+var (
+	// condenseByDefault condenses calls that do not set condense
+	condenseByDefault bool
+	// condenseWords is the word count condensed text aims for
+	condenseWords = DefaultCondenseWords
+)
+
+// Ways text is condensed, reported in the result.
+const (
+	condensedBySampling = "summarized by the client's model"
+	condensedByTruncate = "shortened at a sentence boundary"
+)
+
+// condenseSystemPrompt asks the client's model for a spoken summary.
+const condenseSystemPrompt = "Rewrite the user's text as a short spoken summary of at most %d words. " +
+	"Use plain conversational sentences with no markdown, lists, code, URLs or long IDs. " +
+	"Keep the key facts and any action the listener must take. Reply with the summary only."
+
+// canSample reports whether the session's client accepts sampling requests.
+func canSample(session *mcp.ServerSession) bool {
+	if session == nil {
+		return false
+	}
+	params := session.InitializeParams()
+	return params != nil && params.Capabilities != nil && params.Capabilities.Sampling != nil
+}
+
+// condenseText shortens text to about words words, asking the client's model
+// for a summary when it supports sampling and cutting at a sentence boundary
+// otherwise. It returns how the text was condensed, or "" if it was already
+// short enough.
+func condenseText(ctx context.Context, session *mcp.ServerSession, text string, words int) (string, string) {
+	if len(strings.Fields(text)) <= words {
+		return text, ""
+	}
+	if canSample(session) {
+		ctx, span := startSpan(ctx, "condense")
+		summary, err := sampleSummary(ctx, session, text, words)
+		endSpan(span, err)
+		if err == nil && summary != "" {
+			// Models do not always keep to the word count
+			short, _ := truncateSentences(summary, words+words/2)
+			return short, condensedBySampling
+		}
+		log.Warn("Failed to summarize text with sampling, truncating instead", "error", err)
+	}
+	return truncateSentences(text, words)
+}
+
+// sampleSummary asks the client's model to summarize text for listening.
+func sampleSummary(ctx context.Context, session *mcp.ServerSession, text string, words int) (string, error) {
+	result, err := session.CreateMessage(ctx, &mcp.CreateMessageParams{
+		Messages:     []*mcp.SamplingMessage{{Role: "user", Content: &mcp.TextContent{Text: text}}},
+		SystemPrompt: fmt.Sprintf(condenseSystemPrompt, words),
+		MaxTokens:    int64(words * 3),
+		ModelPreferences: &mcp.ModelPreferences{
+			SpeedPriority: 0.8,
+			CostPriority:  0.8,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	content, ok := result.Content.(*mcp.TextContent)
+	if !ok {
+		return "", fmt.Errorf("sampling returned %T, not text", result.Content)
+	}
+	return strings.TrimSpace(content.Text), nil
+}
+
+// truncateSentences keeps the leading sentences of text that fit in words
+// words. A first sentence longer than that is cut at a word boundary.
+func truncateSentences(text string, words int) (string, string) {
+	fields := strings.Fields(text)
+	if len(fields) <= words {
+		return text, ""
+	}
+	var kept []string
+	var sentence []string
+	for _, f := range fields {
+		sentence = append(sentence, f)
+		if !endsSentence(f) {
+			continue
+		}
+		if len(kept)+len(sentence) > words {
+			break
+		}
+		kept = append(kept, sentence...)
+		sentence = nil
+	}
+	if len(kept) == 0 {
+		kept = append(fields[:words:words], "…")
+	}
+	return strings.Join(kept, " "), condensedByTruncate
+}
+
+// endsSentence reports whether a word ends a sentence.
+func endsSentence(word string) bool {
+	word = strings.TrimRight(word, `"')”’`)
+	if word == "" {
+		return false
+	}
+	runes := []rune(word)
+	last := runes[len(runes)-1]
+	return last == '…' || unicode.Is(unicode.Sentence_Terminal, last)
+}
+
+// condenseArguments returns the call's arguments with the condense argument
+// removed and, when the call asks for it, its text condensed. raw is nil when
+// the arguments are unchanged; how is "" when the text was not condensed.
+func condenseArguments(ctx context.Context, req *mcp.CallToolRequest) (raw json.RawMessage, text, how string) {
+	if req.Params == nil || len(req.Params.Arguments) == 0 {
+		return nil, "", ""
+	}
+	var args map[string]json.RawMessage
+	if err := json.Unmarshal(req.Params.Arguments, &args); err != nil {
+		return nil, "", ""
+	}
+	condense := condenseByDefault
+	v, found := args["condense"]
+	if found {
+		if err := json.Unmarshal(v, &condense); err != nil {
+			return nil, "", ""
+		}
+		// Drop the argument so the provider tools never see it
+		delete(args, "condense")
+		raw, _ = json.Marshal(args)
+	}
+	if err := json.Unmarshal(args["text"], &text); err != nil || !condense {
+		return raw, "", ""
+	}
+	text, how = condenseText(ctx, req.Session, text, condenseWords)
+	if how == "" {
+		return raw, "", ""
+	}
+	args["text"], _ = json.Marshal(text)
+	raw, _ = json.Marshal(args)
+	return raw, text, how
+}
+
+// condenseMiddleware condenses the text of speech calls that ask for it
+// before the input limits see it, and reports the text that was spoken.
+func condenseMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		callReq, ok := req.(*mcp.CallToolRequest)
+		if !ok || method != "tools/call" {
+			return next(ctx, method, req)
+		}
+		raw, text, how := condenseArguments(ctx, callReq)
+		if raw != nil {
+			callReq.Params.Arguments = raw
+		}
+		result, err := next(ctx, method, req)
+		if how == "" || err != nil {
+			return result, err
+		}
+		log.Info("Condensed text before speaking", "tool", callReq.Params.Name, "how", how, "text", logText(text))
+		if r, ok := result.(*mcp.CallToolResult); ok && !r.IsError {
+			reportCondensed(r, text, how)
+		}
+		return result, err
+	}
+}
+
+// reportCondensed notes in a result that its text was condensed, including
+// the spoken text unless the result already shows it.
+func reportCondensed(r *mcp.CallToolResult, text, how string) {
+	note := fmt.Sprintf("Condensed (%s) to: %s", how, text)
+	if len(r.Content) == 0 {
+		r.Content = []mcp.Content{&mcp.TextContent{Text: note}}
+		return
+	}
+	c, ok := r.Content[0].(*mcp.TextContent)
+	if !ok {
+		return
+	}
+	if strings.Contains(c.Text, text) {
+		note = fmt.Sprintf("Condensed (%s)", how)
+	}
+	c.Text += "\n" + note
+}
+
+// condenseSchemaProperty describes the condense argument.
+func condenseSchemaProperty() map[string]any {
+	return map[string]any{
+		"type":        "boolean",
+		"description": fmt.Sprintf("Summarize long text to about %d words before speaking it; the result reports the text that was spoken", condenseWords),
+	}
+}
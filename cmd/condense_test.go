@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useTestCondense sets the condense defaults for a test.
+func useTestCondense(t *testing.T, byDefault bool, words int) {
+	t.Helper()
+	origDefault, origWords := condenseByDefault, condenseWords
+	t.Cleanup(func() { condenseByDefault, condenseWords = origDefault, origWords })
+	condenseByDefault, condenseWords = byDefault, words
+}
+
+// condenseClient connects a client to a server whose say_tts tool echoes the
+// text it was given. summary, when set, answers sampling requests.
+func condenseClient(t *testing.T, summary string, sampled *string) *mcp.ClientSession {
+	t.Helper()
+	server := mcp.NewServer(&mcp.Implementation{Name: "mcp-tts"}, nil)
+	server.AddReceivingMiddleware(condenseMiddleware)
+	mcp.AddTool(server, &mcp.Tool{Name: "say_tts"}, func(ctx context.Context, req *mcp.CallToolRequest, input SayTTSParams) (*mcp.CallToolResult, any, error) {
+		return textResult("Spoke " + input.Text), nil, nil
+	})
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	_, err := server.Connect(context.Background(), serverTransport, nil)
+	require.NoError(t, err)
+	opts := &mcp.ClientOptions{}
+	if summary != "" {
+		opts.CreateMessageHandler = func(ctx context.Context, req *mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+			*sampled = req.Params.Messages[0].Content.(*mcp.TextContent).Text
+			return &mcp.CreateMessageResult{Role: "assistant", Content: &mcp.TextContent{Text: summary}}, nil
+		}
+	}
+	client := mcp.NewClient(&mcp.Implementation{Name: "test"}, opts)
+	session, err := client.Connect(context.Background(), clientTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { session.Close() })
+	return session
+}
+
+func TestTruncateSentences(t *testing.T) {
+	text := "The build passed. All 42 tests ran in nine seconds! Deploy is next."
+
+	short, how := truncateSentences(text, 20)
+	assert.Equal(t, text, short)
+	assert.Empty(t, how)
+
+	short, how = truncateSentences(text, 10)
+	assert.Equal(t, "The build passed. All 42 tests ran in nine seconds!", short)
+	assert.Equal(t, condensedByTruncate, how)
+
+	short, _ = truncateSentences("one two three four five six", 3)
+	assert.Equal(t, "one two three …", short)
+
+	short, _ = truncateSentences(`He said "stop." Then he left the room quietly.`, 4)
+	assert.Equal(t, `He said "stop."`, short)
+}
+
+func TestCondenseArguments(t *testing.T) {
+	useTestCondense(t, false, 3)
+	call := func(args string) *mcp.CallToolRequest {
+		return &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{Name: "say_tts", Arguments: []byte(args)}}
+	}
+
+	raw, _, how := condenseArguments(context.Background(), call(`{"text":"one. two. three. four."}`))
+	assert.Nil(t, raw)
+	assert.Empty(t, how)
+
+	raw, _, how = condenseArguments(context.Background(), call(`{"text":"one. two. three. four.","condense":false}`))
+	assert.JSONEq(t, `{"text":"one. two. three. four."}`, string(raw))
+	assert.Empty(t, how)
+
+	raw, text, how := condenseArguments(context.Background(), call(`{"text":"one. two. three. four.","condense":true}`))
+	assert.JSONEq(t, `{"text":"one. two. three."}`, string(raw))
+	assert.Equal(t, "one. two. three.", text)
+	assert.Equal(t, condensedByTruncate, how)
+
+	condenseByDefault = true
+	_, text, _ = condenseArguments(context.Background(), call(`{"text":"one. two. three. four."}`))
+	assert.Equal(t, "one. two. three.", text)
+}
+
+func TestCondenseWithSampling(t *testing.T) {
+	useTestCondense(t, false, 5)
+	var sampled string
+	session := condenseClient(t, "Short summary here.", &sampled)
+
+	long := "This is a long report. It has many sentences. Nobody wants to hear all of it."
+	got := callTool(t, session, "say_tts", map[string]any{"text": long, "condense": true})
+	assert.Equal(t, long, sampled)
+	assert.Contains(t, got, "Spoke Short summary here.")
+	assert.Contains(t, got, "Condensed ("+condensedBySampling+")")
+
+	sampled = ""
+	got = callTool(t, session, "say_tts", map[string]any{"text": "Already short.", "condense": true})
+	assert.Empty(t, sampled)
+	assert.Equal(t, "Spoke Already short.", got)
+}
+
+func TestCondenseWithoutSampling(t *testing.T) {
+	useTestCondense(t, true, 5)
+	session := condenseClient(t, "", nil)
+
+	got := callTool(t, session, "say_tts", map[string]any{"text": "First part is here. Second part is longer than that."})
+	assert.True(t, strings.HasPrefix(got, "Spoke First part is here."))
+	assert.Contains(t, got, "Condensed ("+condensedByTruncate+")")
+	assert.NotContains(t, got, "Second part")
+}
+
+func TestReportCondensed(t *testing.T) {
+	r := textResult("Audio saved")
+	reportCondensed(r, "Short text.", condensedByTruncate)
+	assert.Equal(t, "Audio saved\nCondensed ("+condensedByTruncate+") to: Short text.", resultText(r))
+}
@@ -0,0 +1,699 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileName is the config file looked up in the user and project config directories.
+const configFileName = "config.yaml"
+
+// Sources reported by `mcp-tts config show`.
+const (
+	sourceDefault = "default"
+	sourceFlag    = "flag"
+)
+
+// providerConfigKeys maps the provider names used in config files to tool IDs.
+var providerConfigKeys = map[string]string{
+	"say":        ProviderSay,
+	"elevenlabs": ProviderElevenLabs,
+	"google":     ProviderGoogle,
+	"openai":     ProviderOpenAI,
+}
+
+// flagEnvVars maps flags to the environment variable that can also set them.
+// Config settings never override a flag or environment variable.
+var flagEnvVars = map[string]string{
+	"profile":                  "MCP_TTS_PROFILE",
+	"suppress-speaking-output": "MCP_TTS_SUPPRESS_SPEAKING_OUTPUT",
+	"sequential-tts":           "MCP_TTS_ALLOW_CONCURRENT",
+	"output-dir":               "MCP_TTS_OUTPUT_DIR",
+	"no-play":                  "MCP_TTS_NO_PLAY",
+	"save-format":              "MCP_TTS_SAVE_FORMAT",
+	"captions":                 "MCP_TTS_CAPTIONS",
+	"project-voices":           "MCP_TTS_PROJECT_VOICES",
+	"lexicon":                  "MCP_TTS_LEXICON",
+	"redact":                   "MCP_TTS_REDACT",
+	"redact-pattern":           "MCP_TTS_REDACT_PATTERNS",
+	"log-text":                 "MCP_TTS_LOG_TEXT",
+	"log-format":               "MCP_TTS_LOG_FORMAT",
+	"log-file":                 "MCP_TTS_LOG_FILE",
+	"trim-silence":             "MCP_TTS_TRIM_SILENCE",
+	"silence-threshold":        "MCP_TTS_SILENCE_THRESHOLD",
+	"silence-min-duration":     "MCP_TTS_SILENCE_MIN_DURATION",
+	"utterance-gap":            "MCP_TTS_UTTERANCE_GAP",
+	"usage-ledger":             "MCP_TTS_USAGE_LEDGER",
+	"metrics-addr":             "MCP_TTS_METRICS_ADDR",
+	"audit-log":                "MCP_TTS_AUDIT_LOG",
+	"max-chars":                "MCP_TTS_MAX_CHARS",
+	"max-audio-duration":       "MCP_TTS_MAX_AUDIO_DURATION",
+	"max-calls-per-minute":     "MCP_TTS_MAX_CALLS_PER_MINUTE",
+	"deny-phrase":              "MCP_TTS_DENY_PHRASES",
+	"allow-phrase":             "MCP_TTS_ALLOW_PHRASES",
+	"confirm-chars":            "MCP_TTS_CONFIRM_CHARS",
+	"confirm-cost":             "MCP_TTS_CONFIRM_COST",
+	"persist-preferences":      "MCP_TTS_PERSIST_PREFERENCES",
+	"tts-mode":                 "MCP_TTS_TTS_MODE",
+	"condense":                 "MCP_TTS_CONDENSE",
+	"condense-words":           "MCP_TTS_CONDENSE_WORDS",
+}
+
+// providerEnvVars maps provider settings to the environment variables that override them.
+var providerEnvVars = map[string]map[string]string{
+	ProviderElevenLabs: {"voice": "ELEVENLABS_VOICE_ID", "model": "ELEVENLABS_MODEL_ID"},
+	ProviderOpenAI:     {"instructions": "OPENAI_TTS_INSTRUCTIONS"},
+}
+
+// builtinProviderSettings are the provider defaults used when nothing else is configured.
+var builtinProviderSettings = map[string]providerSettings{
+	ProviderSay:        {Rate: DefaultSayRate},
+	ProviderElevenLabs: {Voice: DefaultElevenLabsVoiceID, Model: DefaultElevenLabsModel, Retry: defaultRetryPolicy},
+	ProviderGoogle:     {Voice: DefaultGoogleVoice, Model: DefaultGoogleModel, Retry: defaultRetryPolicy},
+	ProviderOpenAI:     {Voice: DefaultOpenAIVoice, Model: DefaultOpenAIModel, Speed: DefaultOpenAISpeed, Retry: defaultRetryPolicy},
+}
+
+// providerSettings are the defaults applied when a tool call omits a setting.
+type providerSettings struct {
+	Voice        string         `yaml:"voice,omitempty"`
+	Model        string         `yaml:"model,omitempty"`
+	Speed        float64        `yaml:"speed,omitempty"`
+	Rate         int            `yaml:"rate,omitempty"`
+	Instructions string         `yaml:"instructions,omitempty"`
+	Retry        retryPolicy    `yaml:"retry,omitempty"`
+	Limits       providerLimits `yaml:"limits,omitempty"`
+}
+
+// merge overlays the non-zero fields of src, recording source for each one.
+func (p *providerSettings) merge(src providerSettings, source string, sources map[string]string) {
+	set := func(field string) {
+		if sources != nil {
+			sources[field] = source
+		}
+	}
+	if src.Voice != "" {
+		p.Voice = src.Voice
+		set("voice")
+	}
+	if src.Model != "" {
+		p.Model = src.Model
+		set("model")
+	}
+	if src.Speed != 0 {
+		p.Speed = src.Speed
+		set("speed")
+	}
+	if src.Rate != 0 {
+		p.Rate = src.Rate
+		set("rate")
+	}
+	if src.Instructions != "" {
+		p.Instructions = src.Instructions
+		set("instructions")
+	}
+	p.Retry.merge(src.Retry, set)
+	p.Limits.merge(src.Limits, set)
+}
+
+// field returns a setting formatted for display, or "" when unset.
+func (p providerSettings) field(name string) string {
+	switch name {
+	case "voice":
+		return p.Voice
+	case "model":
+		return p.Model
+	case "speed":
+		if p.Speed != 0 {
+			return strconv.FormatFloat(p.Speed, 'f', -1, 64)
+		}
+	case "rate":
+		if p.Rate != 0 {
+			return strconv.Itoa(p.Rate)
+		}
+	case "instructions":
+		return p.Instructions
+	}
+	if strings.HasPrefix(name, "limits.") {
+		return p.Limits.field(name)
+	}
+	return p.Retry.field(name)
+}
+
+// configProfile holds the settings a config file or named profile can set.
+type configProfile struct {
+	ProviderOrder []string                         `yaml:"provider_order,omitempty"`
+	Settings      map[string]any                   `yaml:"settings,omitempty"`
+	Providers     map[string]providerSettings      `yaml:"providers,omitempty"`
+	Categories    map[string]categorySettings      `yaml:"categories,omitempty"`
+	Prices        map[string]map[string]modelPrice `yaml:"prices,omitempty"`
+}
+
+// configFile is the layout of config.yaml.
+type configFile struct {
+	Profile       string `yaml:"profile,omitempty"`
+	configProfile `yaml:",inline"`
+	Profiles      map[string]configProfile `yaml:"profiles,omitempty"`
+}
+
+// configLayer is one loaded config file.
+type configLayer struct {
+	Name string // "user config" or "project config"
+	Path string
+	File configFile
+}
+
+// appConfig is the merged user and project configuration.
+type appConfig struct {
+	Layers  []configLayer // in merge order: user, then project
+	Profile string        // profile selected at startup, "" for none
+
+	profileSource  string
+	settingSources map[string]string
+}
+
+// activeConfig is the configuration loaded at startup.
+var activeConfig = &appConfig{}
+
+// configFiles returns the user and project config paths, whether or not they exist.
+func configFiles(projectDir string) (user, project string) {
+	if dir, err := mcpTTSConfigDir(); err == nil {
+		user = filepath.Join(dir, configFileName)
+	}
+	if projectDir != "" {
+		project = filepath.Join(projectDir, projectConfigDir, configFileName)
+	}
+	return user, project
+}
+
+// loadConfig reads the user and project config files. Missing files are skipped.
+func loadConfig(userPath, projectPath string) (*appConfig, error) {
+	cfg := &appConfig{settingSources: make(map[string]string)}
+	for _, layer := range []configLayer{{Name: "user config", Path: userPath}, {Name: "project config", Path: projectPath}} {
+		if layer.Path == "" {
+			continue
+		}
+		data, err := os.ReadFile(layer.Path)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		if err := yaml.Unmarshal(data, &layer.File); err != nil {
+			return nil, fmt.Errorf("%s: %w", layer.Path, err)
+		}
+		if err := layer.File.validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", layer.Path, err)
+		}
+		cfg.Layers = append(cfg.Layers, layer)
+	}
+	return cfg, nil
+}
+
+// validate checks provider names in the file and its profiles.
+func (f configFile) validate() error {
+	check := func(p configProfile, where string) error {
+		for _, name := range p.ProviderOrder {
+			if _, ok := providerConfigKeys[name]; !ok {
+				return fmt.Errorf("%sunknown provider %q in provider_order (supported: %s)", where, name, strings.Join(slices.Sorted(maps.Keys(providerConfigKeys)), ", "))
+			}
+		}
+		for name, s := range p.Providers {
+			if _, ok := providerConfigKeys[name]; !ok {
+				return fmt.Errorf("%sunknown provider %q (supported: %s)", where, name, strings.Join(slices.Sorted(maps.Keys(providerConfigKeys)), ", "))
+			}
+			if err := s.Retry.validate(); err != nil {
+				return fmt.Errorf("%sprovider %s: %w", where, name, err)
+			}
+			if err := s.Limits.validate(name); err != nil {
+				return fmt.Errorf("%sprovider %s: %w", where, name, err)
+			}
+		}
+		for name, models := range p.Prices {
+			if _, ok := providerConfigKeys[name]; !ok {
+				return fmt.Errorf("%sunknown provider %q in prices (supported: %s)", where, name, strings.Join(slices.Sorted(maps.Keys(providerConfigKeys)), ", "))
+			}
+			for model, price := range models {
+				if price.PerMillionChars < 0 || price.PerMinute < 0 {
+					return fmt.Errorf("%sprices %s %s: prices must not be negative", where, name, model)
+				}
+			}
+		}
+		for name, c := range p.Categories {
+			if !slices.Contains(Categories, name) {
+				return fmt.Errorf("%sunknown category %q (supported: %s)", where, name, strings.Join(Categories, ", "))
+			}
+			if err := c.validate(); err != nil {
+				return fmt.Errorf("%scategory %s: %w", where, name, err)
+			}
+		}
+		return nil
+	}
+	if err := check(f.configProfile, ""); err != nil {
+		return err
+	}
+	for name, p := range f.Profiles {
+		if err := check(p, fmt.Sprintf("profile %q: ", name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProfileNames returns the names of all profiles defined in any config file.
+func (c *appConfig) ProfileNames() []string {
+	names := make(map[string]bool)
+	for _, layer := range c.Layers {
+		for name := range layer.File.Profiles {
+			names[name] = true
+		}
+	}
+	return slices.Sorted(maps.Keys(names))
+}
+
+// hasProfile reports whether a profile is defined in any config file.
+func (c *appConfig) hasProfile(name string) bool {
+	return slices.Contains(c.ProfileNames(), name)
+}
+
+// profileLayers returns the named profile from each config file that defines it.
+func (c *appConfig) profileLayers(name string) []configLayer {
+	var layers []configLayer
+	if name == "" {
+		return nil
+	}
+	for _, layer := range c.Layers {
+		if p, ok := layer.File.Profiles[name]; ok {
+			layers = append(layers, configLayer{
+				Name: fmt.Sprintf("profile %s (%s)", name, layer.Name),
+				Path: layer.Path,
+				File: configFile{configProfile: p},
+			})
+		}
+	}
+	return layers
+}
+
+// settingLayers returns the layers that apply for a profile, lowest precedence first.
+func (c *appConfig) settingLayers(profile string) []configLayer {
+	return append(slices.Clone(c.Layers), c.profileLayers(profile)...)
+}
+
+// selectProfile picks the startup profile: --profile, then the project's
+// `profile:` key, then the user's.
+func (c *appConfig) selectProfile(flag *pflag.Flag) error {
+	name, source := "", ""
+	for _, layer := range c.Layers {
+		if layer.File.Profile != "" {
+			name, source = layer.File.Profile, layer.Name
+		}
+	}
+	if flag != nil && flag.Value.String() != "" {
+		name, source = flag.Value.String(), flagSource(flag)
+	}
+	if name != "" && !c.hasProfile(name) {
+		return fmt.Errorf("unknown profile %q (defined: %s)", name, strings.Join(c.ProfileNames(), ", "))
+	}
+	c.Profile, c.profileSource = name, source
+	return nil
+}
+
+// flagSource reports whether a flag was set on the command line or from the
+// environment. A set variable wins over the config files even when its value
+// is the flag's default.
+func flagSource(flag *pflag.Flag) string {
+	if flag.Changed {
+		return sourceFlag
+	}
+	if env := flagEnvVars[flag.Name]; env != "" {
+		if _, ok := os.LookupEnv(env); ok {
+			return "env " + env
+		}
+	}
+	return ""
+}
+
+// applySettings sets flags from the config files and selected profile. Flags
+// given on the command line or through the environment are left alone.
+func (c *appConfig) applySettings(flags *pflag.FlagSet) error {
+	values := make(map[string]any)
+	for _, layer := range c.settingLayers(c.Profile) {
+		for name, value := range layer.File.Settings {
+			if flags.Lookup(name) == nil || name == "profile" {
+				return fmt.Errorf("%s: unknown setting %q", layer.Path, name)
+			}
+			values[name] = value
+			c.settingSources[name] = layer.Name
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		flag := flags.Lookup(name)
+		if source := flagSource(flag); source != "" {
+			c.settingSources[name] = source
+			continue
+		}
+		if err := setFlagValue(flag, values[name]); err != nil {
+			return fmt.Errorf("invalid setting %s from %s: %w", name, c.settingSources[name], err)
+		}
+	}
+	return nil
+}
+
+// setFlagValue sets a flag from a YAML value. Lists set repeatable flags.
+// Setting through the Value keeps Changed false, so sources stay accurate.
+func setFlagValue(flag *pflag.Flag, value any) error {
+	items, ok := value.([]any)
+	if !ok {
+		items = []any{value}
+	}
+	for _, item := range items {
+		s := fmt.Sprint(item)
+		if rest, ok := strings.CutPrefix(s, "~/"); ok {
+			if home, err := os.UserHomeDir(); err == nil {
+				s = filepath.Join(home, rest)
+			}
+		}
+		if err := flag.Value.Set(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// settingSource returns where a flag's effective value came from.
+func (c *appConfig) settingSource(flag *pflag.Flag) string {
+	if source := flagSource(flag); source != "" {
+		return source
+	}
+	if source, ok := c.settingSources[flag.Name]; ok {
+		return source
+	}
+	return sourceDefault
+}
+
+// providerSettings resolves the defaults for a provider under a profile, with
+// the source of each field. Precedence: env > profile > project > user > built-in.
+func (c *appConfig) providerSettings(providerID, profile string) (providerSettings, map[string]string) {
+	sources := make(map[string]string)
+	var settings providerSettings
+	settings.merge(builtinProviderSettings[providerID], sourceDefault, sources)
+
+	key := providerKey(providerID)
+	for _, layer := range c.settingLayers(profile) {
+		settings.merge(layer.File.Providers[key], layer.Name, sources)
+	}
+	for field, env := range providerEnvVars[providerID] {
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+		switch field {
+		case "voice":
+			settings.Voice = value
+		case "model":
+			settings.Model = value
+		case "instructions":
+			settings.Instructions = value
+		}
+		sources[field] = "env " + env
+	}
+	return settings, sources
+}
+
+// ProviderOrder returns the configured provider order for the startup profile as tool IDs.
+func (c *appConfig) ProviderOrder() []string {
+	var order []string
+	for _, layer := range c.settingLayers(c.Profile) {
+		if len(layer.File.ProviderOrder) > 0 {
+			order = layer.File.ProviderOrder
+		}
+	}
+	ids := make([]string, 0, len(order))
+	for _, name := range order {
+		ids = append(ids, providerConfigKeys[name])
+	}
+	return ids
+}
+
+// profileSetting returns a setting defined by a profile, project files winning.
+func (c *appConfig) profileSetting(profile, name string) (any, bool) {
+	var value any
+	var found bool
+	for _, layer := range c.profileLayers(profile) {
+		if v, ok := layer.File.Settings[name]; ok {
+			value, found = v, true
+		}
+	}
+	return value, found
+}
+
+// providerKey returns the config file name of a provider tool ID.
+func providerKey(providerID string) string {
+	for key, id := range providerConfigKeys {
+		if id == providerID {
+			return key
+		}
+	}
+	return providerID
+}
+
+// callProfile resolves the profile for a tool call: the requested one, or the
+// startup profile.
+func callProfile(requested *string) (string, error) {
+	if requested == nil || *requested == "" {
+		return activeConfig.Profile, nil
+	}
+	if !activeConfig.hasProfile(*requested) {
+		return "", fmt.Errorf("unknown profile %q", *requested)
+	}
+	return *requested, nil
+}
+
+// perCallSettings are the settings a profile requested by a tool call
+// applies. The rest configure the whole server and only apply to the startup
+// profile.
+var perCallSettings = []string{"save-format", "captions"}
+
+// ignoredProfileSettings lists the settings of a profile requested by a tool
+// call that it cannot apply to the call.
+func ignoredProfileSettings(profile string) []string {
+	if profile == activeConfig.Profile {
+		return nil
+	}
+	var ignored []string
+	for _, layer := range activeConfig.profileLayers(profile) {
+		for name := range layer.File.Settings {
+			if !slices.Contains(perCallSettings, name) && !slices.Contains(ignored, name) {
+				ignored = append(ignored, name)
+			}
+		}
+	}
+	slices.Sort(ignored)
+	return ignored
+}
+
+// applyProfileOutput fills the per-call format and captions options from a
+// profile requested by the call. The startup profile already set the flags.
+func applyProfileOutput(profile string, format **string, captions **bool) {
+	if profile == activeConfig.Profile {
+		return
+	}
+	if *format == nil {
+		if v, ok := activeConfig.profileSetting(profile, "save-format"); ok {
+			s := fmt.Sprint(v)
+			*format = &s
+		}
+	}
+	if *captions == nil {
+		if v, ok := activeConfig.profileSetting(profile, "captions"); ok {
+			if b, err := strconv.ParseBool(fmt.Sprint(v)); err == nil {
+				*captions = &b
+			}
+		}
+	}
+}
+
+// providerDefaults returns the configured defaults for a provider under a profile.
+func providerDefaults(providerID, profile string) providerSettings {
+	settings, _ := activeConfig.providerSettings(providerID, profile)
+	return settings
+}
+
+// fillSayParams sets omitted say settings from the configured defaults.
+func fillSayParams(input *SayTTSParams, d providerSettings) {
+	if input.Voice == nil && d.Voice != "" {
+		input.Voice = &d.Voice
+	}
+	if input.Rate == nil && d.Rate != 0 {
+		input.Rate = &d.Rate
+	}
+}
+
+// fillGoogleParams sets omitted Google settings from the configured defaults.
+func fillGoogleParams(input *GoogleTTSParams, d providerSettings) {
+	if input.Voice == nil && d.Voice != "" {
+		input.Voice = &d.Voice
+	}
+	if input.Model == nil && d.Model != "" {
+		input.Model = &d.Model
+	}
+}
+
+// fillOpenAIParams sets omitted OpenAI settings from the configured defaults.
+func fillOpenAIParams(input *OpenAITTSParams, d providerSettings) {
+	if input.Voice == nil && d.Voice != "" {
+		input.Voice = &d.Voice
+	}
+	if input.Model == nil && d.Model != "" {
+		input.Model = &d.Model
+	}
+	if input.Speed == nil && d.Speed != 0 {
+		input.Speed = &d.Speed
+	}
+	if input.Instructions == nil && d.Instructions != "" {
+		input.Instructions = &d.Instructions
+	}
+}
+
+// setupConfig loads the config files for the working directory, selects the
+// startup profile and applies its settings to the flags.
+func setupConfig(flags *pflag.FlagSet) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+	cfg, err := loadConfig(configFiles(cwd))
+	if err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	if err := cfg.selectProfile(flags.Lookup("profile")); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	if err := cfg.applySettings(flags); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	activeConfig = cfg
+	return nil
+}
+
+// writeConfig prints the effective configuration with the source of each value.
+func (c *appConfig) writeConfig(w io.Writer, flags *pflag.FlagSet, userPath, projectPath string) error {
+	found := func(path string) string {
+		for _, layer := range c.Layers {
+			if layer.Path == path {
+				return path
+			}
+		}
+		return path + " (not found)"
+	}
+	fmt.Fprintf(w, "User config:    %s\n", found(userPath))
+	if projectPath != "" {
+		fmt.Fprintf(w, "Project config: %s\n", found(projectPath))
+	}
+	if c.Profile != "" {
+		fmt.Fprintf(w, "Profile:        %s (%s)\n", c.Profile, c.profileSource)
+	} else {
+		fmt.Fprintln(w, "Profile:        none")
+	}
+	if names := c.ProfileNames(); len(names) > 0 {
+		fmt.Fprintf(w, "Profiles:       %s\n", strings.Join(names, ", "))
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nSETTING\tVALUE\tSOURCE")
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Name == "help" || flag.Name == "profile" {
+			return
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", flag.Name, flag.Value.String(), c.settingSource(flag))
+	})
+
+	fmt.Fprintln(tw, "\nPROVIDER SETTING\tVALUE\tSOURCE")
+	for _, key := range slices.Sorted(maps.Keys(providerConfigKeys)) {
+		settings, sources := c.providerSettings(providerConfigKeys[key], c.Profile)
+		for _, field := range slices.Concat([]string{"voice", "model", "speed", "rate", "instructions"}, retryFields, limitFields) {
+			if value := settings.field(field); value != "" {
+				fmt.Fprintf(tw, "%s.%s\t%s\t%s\n", key, field, value, sources[field])
+			}
+		}
+	}
+
+	fmt.Fprintln(tw, "\nCATEGORY SETTING\tVALUE\tSOURCE")
+	for _, category := range Categories {
+		settings, sources := c.categorySettings(category, c.Profile)
+		for _, field := range slices.Sorted(maps.Keys(sources)) {
+			fmt.Fprintf(tw, "%s.%s\t%s\t%s\n", category, field, settings.field(field), sources[field])
+		}
+	}
+
+	order := "default"
+	if ids := c.ProviderOrder(); len(ids) > 0 {
+		names := make([]string, 0, len(ids))
+		for _, id := range ids {
+			names = append(names, providerKey(id))
+		}
+		order = strings.Join(names, ", ")
+	}
+	fmt.Fprintf(tw, "\nprovider_order\t%s\t\n", order)
+	return tw.Flush()
+}
+
+// configCmd groups configuration commands.
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration",
+}
+
+// configShowCmd prints the effective merged configuration.
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration and where each value comes from",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		userPath, projectPath := configFiles(cwd)
+		return activeConfig.writeConfig(cmd.OutOrStdout(), rootCmd.PersistentFlags(), userPath, projectPath)
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configShowCmd)
+	rootCmd.AddCommand(configCmd)
+}
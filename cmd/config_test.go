@@ -0,0 +1,335 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testUserConfig = `
+profile: quiet-office
+provider_order: [openai, google]
+settings:
+  save-format: mp3
+  captions: true
+providers:
+  openai:
+    voice: nova
+    instructions: Speak calmly.
+  google:
+    voice: Puck
+profiles:
+  quiet-office:
+    settings:
+      save-format: wav
+    providers:
+      openai:
+        speed: 0.9
+        retry:
+          max_attempts: 5
+          base_delay: 1s
+  demo:
+    provider_order: [elevenlabs]
+    providers:
+      elevenlabs:
+        voice: demo-voice
+`
+
+const testProjectConfig = `
+settings:
+  utterance-gap: 1s
+providers:
+  google:
+    voice: Charon
+profiles:
+  quiet-office:
+    providers:
+      openai:
+        voice: sage
+`
+
+func writeTestConfigs(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	user := writeTestFile(t, filepath.Join(dir, "user"), configFileName, testUserConfig)
+	project := writeTestFile(t, filepath.Join(dir, "project", projectConfigDir), configFileName, testProjectConfig)
+	return user, project
+}
+
+func testFlags() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.String("profile", "", "")
+	flags.String("save-format", "", "")
+	flags.Bool("captions", false, "")
+	flags.Duration("utterance-gap", DefaultUtteranceGap, "")
+	flags.String("output-dir", "", "")
+	return flags
+}
+
+func TestLoadConfig(t *testing.T) {
+	user, project := writeTestConfigs(t)
+
+	cfg, err := loadConfig(user, project)
+	require.NoError(t, err)
+	require.Len(t, cfg.Layers, 2)
+	assert.Equal(t, []string{"demo", "quiet-office"}, cfg.ProfileNames())
+
+	t.Run("missing files are skipped", func(t *testing.T) {
+		cfg, err := loadConfig(filepath.Join(t.TempDir(), "nope.yaml"), "")
+		require.NoError(t, err)
+		assert.Empty(t, cfg.Layers)
+	})
+
+	t.Run("unknown providers are rejected", func(t *testing.T) {
+		bad := writeTestFile(t, t.TempDir(), configFileName, "provider_order: [polly]\n")
+		_, err := loadConfig(bad, "")
+		assert.ErrorContains(t, err, `unknown provider "polly"`)
+	})
+
+	t.Run("profiles are validated", func(t *testing.T) {
+		bad := writeTestFile(t, t.TempDir(), configFileName, "profiles:\n  x:\n    providers:\n      polly: {voice: a}\n")
+		_, err := loadConfig(bad, "")
+		assert.ErrorContains(t, err, `profile "x": unknown provider "polly"`)
+	})
+
+	t.Run("retry policies are validated", func(t *testing.T) {
+		bad := writeTestFile(t, t.TempDir(), configFileName, "providers:\n  openai:\n    retry: {jitter: 2}\n")
+		_, err := loadConfig(bad, "")
+		assert.ErrorContains(t, err, "provider openai: retry jitter must be between 0 and 1")
+
+		bad = writeTestFile(t, t.TempDir(), configFileName, "providers:\n  openai:\n    retry: {max_attempts: -1}\n")
+		_, err = loadConfig(bad, "")
+		assert.ErrorContains(t, err, "retry max_attempts must not be negative")
+	})
+}
+
+func TestConfigProfileSelection(t *testing.T) {
+	user, project := writeTestConfigs(t)
+	cfg, err := loadConfig(user, project)
+	require.NoError(t, err)
+
+	flags := testFlags()
+	require.NoError(t, cfg.selectProfile(flags.Lookup("profile")))
+	assert.Equal(t, "quiet-office", cfg.Profile, "the config file's profile key applies without a flag")
+
+	require.NoError(t, flags.Set("profile", "demo"))
+	require.NoError(t, cfg.selectProfile(flags.Lookup("profile")))
+	assert.Equal(t, "demo", cfg.Profile)
+	assert.Equal(t, sourceFlag, cfg.profileSource)
+
+	require.NoError(t, flags.Set("profile", "loud"))
+	assert.ErrorContains(t, cfg.selectProfile(flags.Lookup("profile")), `unknown profile "loud"`)
+}
+
+func TestConfigApplySettings(t *testing.T) {
+	user, project := writeTestConfigs(t)
+
+	t.Run("profile overrides project overrides user", func(t *testing.T) {
+		cfg, err := loadConfig(user, project)
+		require.NoError(t, err)
+		flags := testFlags()
+		require.NoError(t, cfg.selectProfile(flags.Lookup("profile")))
+		require.NoError(t, cfg.applySettings(flags))
+
+		assert.Equal(t, "wav", flags.Lookup("save-format").Value.String())
+		assert.Equal(t, "true", flags.Lookup("captions").Value.String())
+		assert.Equal(t, "1s", flags.Lookup("utterance-gap").Value.String())
+
+		assert.Equal(t, "profile quiet-office (user config)", cfg.settingSource(flags.Lookup("save-format")))
+		assert.Equal(t, "user config", cfg.settingSource(flags.Lookup("captions")))
+		assert.Equal(t, "project config", cfg.settingSource(flags.Lookup("utterance-gap")))
+		assert.Equal(t, sourceDefault, cfg.settingSource(flags.Lookup("output-dir")))
+	})
+
+	t.Run("flags win over config", func(t *testing.T) {
+		cfg, err := loadConfig(user, project)
+		require.NoError(t, err)
+		flags := testFlags()
+		require.NoError(t, flags.Set("save-format", "aiff"))
+		require.NoError(t, cfg.applySettings(flags))
+		assert.Equal(t, "aiff", flags.Lookup("save-format").Value.String())
+		assert.Equal(t, sourceFlag, cfg.settingSource(flags.Lookup("save-format")))
+	})
+
+	t.Run("env wins over config", func(t *testing.T) {
+		t.Setenv("MCP_TTS_SAVE_FORMAT", "pcm")
+		cfg, err := loadConfig(user, project)
+		require.NoError(t, err)
+		flags := testFlags()
+		// init() copies the environment into the flag variable
+		require.NoError(t, flags.Lookup("save-format").Value.Set("pcm"))
+		require.NoError(t, cfg.applySettings(flags))
+		assert.Equal(t, "pcm", flags.Lookup("save-format").Value.String())
+		assert.Equal(t, "env MCP_TTS_SAVE_FORMAT", cfg.settingSource(flags.Lookup("save-format")))
+	})
+
+	t.Run("env set to the default still wins over config", func(t *testing.T) {
+		t.Setenv("MCP_TTS_SAVE_FORMAT", "")
+		cfg, err := loadConfig(user, project)
+		require.NoError(t, err)
+		flags := testFlags()
+		require.NoError(t, cfg.selectProfile(flags.Lookup("profile")))
+		require.NoError(t, cfg.applySettings(flags))
+		assert.Empty(t, flags.Lookup("save-format").Value.String())
+		assert.Equal(t, "env MCP_TTS_SAVE_FORMAT", cfg.settingSource(flags.Lookup("save-format")))
+	})
+
+	t.Run("unknown settings are rejected", func(t *testing.T) {
+		bad := writeTestFile(t, t.TempDir(), configFileName, "settings:\n  volume: 11\n")
+		cfg, err := loadConfig(bad, "")
+		require.NoError(t, err)
+		assert.ErrorContains(t, cfg.applySettings(testFlags()), `unknown setting "volume"`)
+	})
+
+	t.Run("invalid values are rejected", func(t *testing.T) {
+		bad := writeTestFile(t, t.TempDir(), configFileName, "settings:\n  captions: maybe\n")
+		cfg, err := loadConfig(bad, "")
+		require.NoError(t, err)
+		assert.ErrorContains(t, cfg.applySettings(testFlags()), "invalid setting captions from user config")
+	})
+}
+
+func TestConfigProviderSettings(t *testing.T) {
+	user, project := writeTestConfigs(t)
+	cfg, err := loadConfig(user, project)
+	require.NoError(t, err)
+
+	settings, sources := cfg.providerSettings(ProviderOpenAI, "quiet-office")
+	assert.Equal(t, providerSettings{Voice: "sage", Model: DefaultOpenAIModel, Speed: 0.9, Instructions: "Speak calmly.", Retry: retryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   time.Second,
+		Jitter:      DefaultRetryJitter,
+		MaxElapsed:  DefaultRetryMaxElapsed,
+	}}, settings)
+	assert.Equal(t, "profile quiet-office (project config)", sources["voice"])
+	assert.Equal(t, sourceDefault, sources["model"])
+	assert.Equal(t, "profile quiet-office (user config)", sources["speed"])
+	assert.Equal(t, "user config", sources["instructions"])
+	assert.Equal(t, "profile quiet-office (user config)", sources["retry.base_delay"])
+	assert.Equal(t, sourceDefault, sources["retry.jitter"])
+
+	settings, sources = cfg.providerSettings(ProviderGoogle, "")
+	assert.Equal(t, "Charon", settings.Voice, "project config overrides user config")
+	assert.Equal(t, "project config", sources["voice"])
+
+	t.Run("env overrides config", func(t *testing.T) {
+		t.Setenv("ELEVENLABS_VOICE_ID", "env-voice")
+		settings, sources := cfg.providerSettings(ProviderElevenLabs, "demo")
+		assert.Equal(t, "env-voice", settings.Voice)
+		assert.Equal(t, "env ELEVENLABS_VOICE_ID", sources["voice"])
+		assert.Equal(t, DefaultElevenLabsModel, settings.Model)
+	})
+
+	t.Run("no config uses built-in defaults", func(t *testing.T) {
+		settings, _ := (&appConfig{}).providerSettings(ProviderSay, "")
+		assert.Equal(t, providerSettings{Rate: DefaultSayRate}, settings)
+	})
+}
+
+func TestFillParamsFromConfig(t *testing.T) {
+	defaults := providerSettings{Voice: "nova", Model: "tts-1", Speed: 1.5, Instructions: "Be brief."}
+	input := OpenAITTSParams{Text: "hi", Voice: stringPtr("echo")}
+	fillOpenAIParams(&input, defaults)
+	assert.Equal(t, "echo", *input.Voice, "explicit arguments are kept")
+	assert.Equal(t, "tts-1", *input.Model)
+	assert.Equal(t, 1.5, *input.Speed)
+	assert.Equal(t, "Be brief.", *input.Instructions)
+
+	sayInput := SayTTSParams{Text: "hi"}
+	fillSayParams(&sayInput, providerSettings{Rate: DefaultSayRate})
+	assert.Nil(t, sayInput.Voice, "an unset voice keeps the system voice")
+	assert.Equal(t, DefaultSayRate, *sayInput.Rate)
+}
+
+func TestApplyProfileOutput(t *testing.T) {
+	user, project := writeTestConfigs(t)
+	cfg, err := loadConfig(user, project)
+	require.NoError(t, err)
+	cfg.Profile = "demo"
+
+	oldConfig := activeConfig
+	defer func() { activeConfig = oldConfig }()
+	activeConfig = cfg
+
+	var format *string
+	var captions *bool
+	applyProfileOutput("quiet-office", &format, &captions)
+	require.NotNil(t, format)
+	assert.Equal(t, "wav", *format)
+	assert.Nil(t, captions)
+
+	format = stringPtr("mp3")
+	applyProfileOutput("quiet-office", &format, &captions)
+	assert.Equal(t, "mp3", *format, "explicit arguments are kept")
+
+	profile, err := callProfile(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "demo", profile)
+	_, err = callProfile(stringPtr("loud"))
+	assert.ErrorContains(t, err, `unknown profile "loud"`)
+}
+
+func TestReportIgnoredSettings(t *testing.T) {
+	useTestLimits(t, `
+profiles:
+  loud:
+    settings:
+      captions: true
+      max-chars: 100
+      trim-silence: false
+`)
+	handler := reportIgnoredSettings(func(ctx context.Context, req *mcp.CallToolRequest, input OpenAITTSParams) (*mcp.CallToolResult, any, error) {
+		return textResult("Speaking: hi"), nil, nil
+	})
+
+	assert.Equal(t, []string{"max-chars", "trim-silence"}, ignoredProfileSettings("loud"))
+	result, _, err := handler(context.Background(), nil, OpenAITTSParams{Text: "hi", Profile: stringPtr("loud")})
+	require.NoError(t, err)
+	assert.Equal(t, "Speaking: hi\nProfile \"loud\" settings not applied to this call (select the profile at startup to use them): max-chars, trim-silence", resultText(result))
+
+	result, _, err = handler(context.Background(), nil, OpenAITTSParams{Text: "hi"})
+	require.NoError(t, err)
+	assert.Equal(t, "Speaking: hi", resultText(result), "the startup profile applies everything")
+
+	activeConfig.Profile = "loud"
+	assert.Empty(t, ignoredProfileSettings("loud"))
+}
+
+func TestOrderProviders(t *testing.T) {
+	providers := []providerOption{
+		{ProviderSay, "macOS Say"},
+		{ProviderElevenLabs, "ElevenLabs"},
+		{ProviderGoogle, "Google Gemini"},
+		{ProviderOpenAI, "OpenAI"},
+	}
+	ordered := orderProviders(providers, []string{ProviderOpenAI, ProviderGoogle})
+	var ids []string
+	for _, p := range ordered {
+		ids = append(ids, p.ID)
+	}
+	assert.Equal(t, []string{ProviderOpenAI, ProviderGoogle, ProviderSay, ProviderElevenLabs}, ids)
+}
+
+func TestWriteConfig(t *testing.T) {
+	user, project := writeTestConfigs(t)
+	cfg, err := loadConfig(user, project)
+	require.NoError(t, err)
+	flags := testFlags()
+	require.NoError(t, cfg.selectProfile(flags.Lookup("profile")))
+	require.NoError(t, cfg.applySettings(flags))
+
+	var buf bytes.Buffer
+	require.NoError(t, cfg.writeConfig(&buf, flags, user, project))
+	out := buf.String()
+	assert.Contains(t, out, "Profile:        quiet-office (user config)")
+	assert.Regexp(t, `save-format\s+wav\s+profile quiet-office \(user config\)`, out)
+	assert.Regexp(t, `openai\.voice\s+sage\s+profile quiet-office \(project config\)`, out)
+	assert.Regexp(t, `provider_order\s+openai, google`, out)
+}
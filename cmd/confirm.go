@@ -0,0 +1,124 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/log"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// confirmPreviewChars is how much of the text a confirmation shows.
+const confirmPreviewChars = 200
+
+var (
+	// Calls over either threshold ask the user before speaking, 0 for never
+	confirmChars int
+	confirmCost  float64
+)
+
+// speechEstimate is what a call is expected to cost before it is made.
+type speechEstimate struct {
+	Provider string
+	Model    string
+	Chars    int
+	Duration time.Duration
+	Cost     float64
+}
+
+// estimateSpeech prices text with the configured prices. A nil or empty
+// model means the provider's configured default.
+func estimateSpeech(providerID string, model *string, profile, text string) speechEstimate {
+	est := speechEstimate{Provider: providerID, Model: providerDefaults(providerID, profile).Model}
+	if model != nil && *model != "" {
+		est.Model = *model
+	}
+	est.Chars = utf8.RuneCountInString(text)
+	est.Duration = estimateSpeechDuration(text, defaultWordsPerMinute)
+	est.Cost = activeConfig.price(providerID, est.Model, profile).cost(est.Chars, est.Duration)
+	return est
+}
+
+// confirmReason returns why a call needs the user's go-ahead, or "".
+func confirmReason(est speechEstimate, category categorySettings) string {
+	switch {
+	case category.Confirm:
+		return "its category asks for confirmation"
+	case confirmChars > 0 && est.Chars > confirmChars:
+		return fmt.Sprintf("it is over %d characters", confirmChars)
+	case confirmCost > 0 && est.Cost > confirmCost:
+		return fmt.Sprintf("it is estimated to cost over $%.2f", confirmCost)
+	}
+	return ""
+}
+
+// confirmMessage shows the user what is about to be spoken and what it costs.
+// from names the provider that handed the call over after hitting a limit.
+func confirmMessage(est speechEstimate, reason, text, from string) string {
+	preview := text
+	if runes := []rune(text); len(runes) > confirmPreviewChars {
+		preview = strings.TrimSpace(string(runes[:confirmPreviewChars])) + "…"
+	}
+	provider := providerKey(est.Provider)
+	if est.Model != "" {
+		provider += " (" + est.Model + ")"
+	}
+	if from != "" {
+		provider += ", instead of " + providerKey(from) + ", which reached a limit"
+	}
+	return fmt.Sprintf(
+		"Speak this text? Confirming because %s.\n\n%q\n\nProvider: %s\nLength: %d characters, about %s\nEstimated cost: $%.4f",
+		reason, preview, provider, est.Chars, est.Duration.Round(time.Second), est.Cost,
+	)
+}
+
+// confirmSpeech asks the user to accept long, expensive or sensitive calls
+// before anything is synthesized. When the user declines, it returns the
+// result to send instead. Clients without elicitation go ahead unasked. Calls
+// forwarded by a provider that hit a limit are checked against the
+// fallback's own price.
+func confirmSpeech(ctx context.Context, req *mcp.CallToolRequest, providerID string, model *string, profile string, category categorySettings, text string) (*mcp.CallToolResult, bool) {
+	est := estimateSpeech(providerID, model, profile, text)
+	reason := confirmReason(est, category)
+	if reason == "" {
+		return nil, false
+	}
+	if !canElicit(req) {
+		log.Debug("Speaking without confirmation, client cannot elicit", "provider", providerID, "reason", reason)
+		return nil, false
+	}
+
+	var from string
+	if tried, _ := ctx.Value(fallthroughKey{}).([]string); len(tried) > 0 {
+		from = tried[len(tried)-1]
+	}
+	result := elicitForm(ctx, req.Session, confirmMessage(est, reason, text, from), map[string]any{
+		"type":       "object",
+		"properties": map[string]any{},
+	})
+	return elicitationStopResult(result, "confirm speech")
+}
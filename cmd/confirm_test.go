@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useTestConfirm sets the confirmation thresholds for a test.
+func useTestConfirm(t *testing.T, chars int, cost float64) {
+	t.Helper()
+	origChars, origCost := confirmChars, confirmCost
+	t.Cleanup(func() { confirmChars, confirmCost = origChars, origCost })
+	confirmChars, confirmCost = chars, cost
+}
+
+func TestEstimateSpeech(t *testing.T) {
+	useTestLimits(t, `
+prices:
+  openai:
+    tts-1: {per_minute: 0.6}
+providers:
+  openai:
+    model: tts-1
+`)
+	text := strings.Repeat("word ", 80) // 30s at 160 wpm
+
+	est := estimateSpeech(ProviderOpenAI, nil, "", text)
+	assert.Equal(t, "tts-1", est.Model, "the configured model is priced")
+	assert.Equal(t, 400, est.Chars)
+	assert.Equal(t, 30*time.Second, est.Duration)
+	assert.InDelta(t, 0.3, est.Cost, 1e-9)
+
+	model := "tts-1-hd"
+	assert.Equal(t, "tts-1-hd", estimateSpeech(ProviderOpenAI, &model, "", text).Model)
+	assert.Zero(t, estimateSpeech(ProviderSay, nil, "", text).Cost)
+}
+
+func TestConfirmReason(t *testing.T) {
+	useTestConfirm(t, 100, 0.5)
+	est := speechEstimate{Chars: 50, Cost: 0.1}
+
+	assert.Empty(t, confirmReason(est, categorySettings{}))
+	assert.Equal(t, "its category asks for confirmation", confirmReason(est, categorySettings{Confirm: true}))
+	assert.Equal(t, "it is over 100 characters", confirmReason(speechEstimate{Chars: 101}, categorySettings{}))
+	assert.Equal(t, "it is estimated to cost over $0.50", confirmReason(speechEstimate{Cost: 0.51}, categorySettings{}))
+
+	confirmChars, confirmCost = 0, 0
+	assert.Empty(t, confirmReason(speechEstimate{Chars: 100000, Cost: 100}, categorySettings{}), "0 never asks")
+}
+
+func TestConfirmMessage(t *testing.T) {
+	est := speechEstimate{Provider: ProviderElevenLabs, Model: "eleven_v3", Chars: 250, Duration: 95 * time.Second, Cost: 0.0413}
+	msg := confirmMessage(est, "it is over 200 characters", strings.Repeat("a", 250), "")
+
+	assert.Contains(t, msg, "Confirming because it is over 200 characters.")
+	assert.Contains(t, msg, `"`+strings.Repeat("a", confirmPreviewChars)+`…"`, "long text is previewed")
+	assert.Contains(t, msg, "Provider: elevenlabs (eleven_v3)")
+	assert.Contains(t, msg, "Length: 250 characters, about 1m35s")
+	assert.Contains(t, msg, "Estimated cost: $0.0413")
+}
+
+func TestConfirmSpeech(t *testing.T) {
+	useTestLimits(t, `
+categories:
+  question:
+    confirm: true
+`)
+	useTestConfirm(t, 20, 0)
+
+	var asked []string
+	answer := "accept"
+	server := mcp.NewServer(&mcp.Implementation{Name: "mcp-tts"}, nil)
+	var spoken int
+	mcp.AddTool(server, &mcp.Tool{Name: "say_tts"}, func(ctx context.Context, req *mcp.CallToolRequest, input TTSParams) (*mcp.CallToolResult, any, error) {
+		category, err := categoryFor(input.Category, "")
+		require.NoError(t, err)
+		if result, stop := confirmSpeech(ctx, req, ProviderSay, nil, "", category, input.Text); stop {
+			return result, nil, nil
+		}
+		spoken++
+		return textResult("Speaking: " + input.Text), nil, nil
+	})
+	// openai_tts answers as the fallback of a provider that hit its limit
+	mcp.AddTool(server, &mcp.Tool{Name: "openai_tts"}, func(ctx context.Context, req *mcp.CallToolRequest, input TTSParams) (*mcp.CallToolResult, any, error) {
+		ctx = context.WithValue(ctx, fallthroughKey{}, []string{ProviderElevenLabs})
+		if result, stop := confirmSpeech(ctx, req, ProviderOpenAI, nil, "", categorySettings{}, input.Text); stop {
+			return result, nil, nil
+		}
+		spoken++
+		return textResult("Speaking: " + input.Text), nil, nil
+	})
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	ctx := context.Background()
+	_, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client"}, &mcp.ClientOptions{
+		ElicitationHandler: func(ctx context.Context, req *mcp.ElicitRequest) (*mcp.ElicitResult, error) {
+			asked = append(asked, req.Params.Message)
+			return &mcp.ElicitResult{Action: answer}, nil
+		},
+	})
+	session, err := client.Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	defer session.Close()
+	speakWith := func(tool string, args map[string]any) string {
+		result, err := session.CallTool(ctx, &mcp.CallToolParams{Name: tool, Arguments: args})
+		require.NoError(t, err)
+		return resultText(result)
+	}
+	speak := func(args map[string]any) string { return speakWith("say_tts", args) }
+
+	assert.Equal(t, "Speaking: short", speak(map[string]any{"text": "short"}))
+	assert.Empty(t, asked, "short calls are not confirmed")
+
+	long := "this text is longer than twenty characters"
+	assert.Equal(t, "Speaking: "+long, speak(map[string]any{"text": long}))
+	require.Len(t, asked, 1)
+	assert.Contains(t, asked[0], "Provider: say")
+
+	answer = "decline"
+	assert.Equal(t, "Request cancelled", speak(map[string]any{"text": long}))
+	assert.Equal(t, "Request cancelled", speak(map[string]any{"text": "ok?", "category": CategoryQuestion}))
+	assert.Contains(t, asked[2], "its category asks for confirmation")
+	assert.Equal(t, 2, spoken, "declined calls are never spoken")
+
+	t.Run("fallbacks are confirmed with their own estimate", func(t *testing.T) {
+		assert.Equal(t, "Request cancelled", speakWith("openai_tts", map[string]any{"text": long}))
+		require.Len(t, asked, 4)
+		assert.Contains(t, asked[3], "Provider: openai")
+		assert.Contains(t, asked[3], "instead of elevenlabs, which reached a limit")
+		assert.Equal(t, 2, spoken)
+	})
+}
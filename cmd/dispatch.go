@@ -0,0 +1,79 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/charmbracelet/log"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Modes of the interactive tts tool.
+const (
+	// TTSModeSpeak speaks with the chosen provider and settings
+	TTSModeSpeak = "speak"
+	// TTSModeRecommend tells the model which provider tool to call and how
+	TTSModeRecommend = "recommend"
+)
+
+// TTSModes lists the accepted values of --tts-mode.
+var TTSModes = []string{TTSModeSpeak, TTSModeRecommend}
+
+// ttsMode selects what the tts tool does once a provider is chosen.
+var ttsMode = TTSModeRecommend
+
+// settingsChosenKey marks a call dispatched by the tts tool, whose settings
+// the user has already been asked for.
+type settingsChosenKey struct{}
+
+// settingsChosen reports whether the call's settings were already elicited.
+func settingsChosen(ctx context.Context) bool {
+	return ctx.Value(settingsChosenKey{}) != nil
+}
+
+// ttsToolDescription describes the tts tool in the configured mode.
+func ttsToolDescription() string {
+	if ttsMode == TTSModeSpeak {
+		return "Selects a TTS provider and voice settings interactively, then speaks the text with them."
+	}
+	return "Selects a TTS provider and voice settings interactively, " +
+		"then returns a recommendation to call the chosen provider tool."
+}
+
+// dispatchSpeech speaks a tts call with the chosen provider's handler. The
+// settings form answers become the provider's arguments, so the handler does
+// not ask for them again.
+func dispatchSpeech(ctx context.Context, req *mcp.CallToolRequest, providerID string, input TTSParams, settings map[string]any) (*mcp.CallToolResult, any, error) {
+	handler, ok := speechHandlers[providerID]
+	if !ok {
+		return errorResult(fmt.Sprintf("Error: %s is not available", providerID)), nil, nil
+	}
+	log.Debug("Dispatching tts call", "provider", providerID)
+	input.Settings = settings
+	result, err := handler(context.WithValue(ctx, settingsChosenKey{}, true), req, input)
+	if err != nil {
+		return errorResult(fmt.Sprintf("Error: %v", err)), nil, nil
+	}
+	return result, nil, nil
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatchSpeech(t *testing.T) {
+	useTestLimits(t, "")
+	useTestMetrics(t)
+
+	var got OpenAITTSParams
+	var chosen bool
+	server := mcp.NewServer(&mcp.Implementation{Name: "mcp-tts"}, nil)
+	addSpeechTool(server, &mcp.Tool{Name: ProviderOpenAI}, openAIParams, func(ctx context.Context, req *mcp.CallToolRequest, input OpenAITTSParams) (*mcp.CallToolResult, any, error) {
+		got = input
+		chosen = settingsChosen(ctx)
+		return textResult("Speaking: " + input.Text), nil, nil
+	})
+
+	category := CategorySummary
+	result, _, err := dispatchSpeech(context.Background(), nil, ProviderOpenAI,
+		TTSParams{Text: "done", Category: &category},
+		map[string]any{"voice": "nova", "speed": 1.5, rememberField: true},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "Speaking: done", resultText(result))
+	assert.Equal(t, "done", got.Text)
+	require.NotNil(t, got.Voice)
+	assert.Equal(t, "nova", *got.Voice, "the chosen settings become the provider's arguments")
+	require.NotNil(t, got.Speed)
+	assert.Equal(t, 1.5, *got.Speed)
+	assert.Nil(t, got.Model)
+	assert.Equal(t, &category, got.Category)
+	assert.True(t, chosen, "the provider does not ask for settings again")
+
+	result, _, err = dispatchSpeech(context.Background(), nil, ProviderGoogle, TTSParams{Text: "hi"}, nil)
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Equal(t, "Error: google_tts is not available", resultText(result))
+}
+
+func TestTTSToolDescription(t *testing.T) {
+	orig := ttsMode
+	defer func() { ttsMode = orig }()
+
+	ttsMode = TTSModeRecommend
+	assert.Contains(t, ttsToolDescription(), "returns a recommendation")
+	ttsMode = TTSModeSpeak
+	assert.Contains(t, ttsToolDescription(), "then speaks the text")
+}
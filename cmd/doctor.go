@@ -0,0 +1,400 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/spf13/cobra"
+)
+
+// Doctor check results, from best to worst.
+const (
+	CheckPass = "pass"
+	CheckWarn = "warn"
+	CheckFail = "fail"
+)
+
+// doctorTimeout bounds each provider credential request.
+const doctorTimeout = 10 * time.Second
+
+var (
+	doctorJSON bool
+	// doctorConfigErr holds the config loading error so doctor can report it
+	// instead of refusing to start.
+	doctorConfigErr error
+	// doctorAudioInit opens the audio output; replaced in tests.
+	doctorAudioInit = func() error { return initSpeaker(beep.SampleRate(24000)) }
+)
+
+// doctorCheck is the outcome of one diagnostic.
+type doctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// doctorReport collects the checks run by `mcp-tts doctor`.
+type doctorReport struct {
+	Status string        `json:"status"`
+	Checks []doctorCheck `json:"checks"`
+}
+
+func (r *doctorReport) add(name, status, format string, args ...any) {
+	r.Checks = append(r.Checks, doctorCheck{Name: name, Status: status, Detail: fmt.Sprintf(format, args...)})
+	rank := []string{CheckPass, CheckWarn, CheckFail}
+	if slices.Index(rank, status) > slices.Index(rank, r.Status) {
+		r.Status = status
+	}
+}
+
+// count returns how many checks ended with status.
+func (r *doctorReport) count(status string) int {
+	n := 0
+	for _, c := range r.Checks {
+		if c.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// runDoctor checks the configuration, audio output, lock and output
+// directories and each provider's credentials.
+func runDoctor(ctx context.Context, cwd string) *doctorReport {
+	r := &doctorReport{Status: CheckPass}
+	r.checkConfig(cwd)
+	r.checkAudio()
+	r.checkOutputDir(outputDir)
+	r.checkLockDir(globalLockDir())
+	r.checkSay()
+	r.checkElevenLabs(ctx)
+	r.checkGoogle(ctx)
+	r.checkOpenAI(ctx)
+	return r
+}
+
+func (r *doctorReport) checkConfig(cwd string) {
+	if doctorConfigErr != nil {
+		r.add("config", CheckFail, "%v", doctorConfigErr)
+	} else {
+		var files []string
+		for _, layer := range activeConfig.Layers {
+			if layer.Path != "" && !slices.Contains(files, layer.Path) {
+				files = append(files, layer.Path)
+			}
+		}
+		detail := "no config files, using defaults"
+		if len(files) > 0 {
+			detail = strings.Join(files, ", ")
+		}
+		if activeConfig.Profile != "" {
+			detail += fmt.Sprintf(" (profile %s)", activeConfig.Profile)
+		}
+		r.add("config", CheckPass, "%s", detail)
+	}
+
+	if lex, err := loadLexicon(lexiconFiles(cwd)...); err != nil {
+		r.add("lexicon", CheckFail, "%v", err)
+	} else {
+		r.add("lexicon", CheckPass, "%d entries", len(lex.Entries))
+	}
+
+	if _, err := newRedactor(redactMode, redactPatterns); err != nil {
+		r.add("redaction", CheckFail, "%v", err)
+	} else {
+		r.add("redaction", CheckPass, "mode %s, %d extra patterns", redactMode, len(redactPatterns))
+	}
+}
+
+func (r *doctorReport) checkAudio() {
+	if noPlay {
+		r.add("audio", CheckPass, "playback disabled (--no-play)")
+		return
+	}
+	if err := doctorAudioInit(); err != nil {
+		hint := ""
+		if runtime.GOOS == "linux" {
+			hint = "; check that ALSA or PulseAudio has an output device, or use --no-play with --output-dir"
+		}
+		r.add("audio", CheckFail, "audio output unavailable: %v%s", err, hint)
+		return
+	}
+	r.add("audio", CheckPass, "audio output initialized")
+}
+
+func (r *doctorReport) checkOutputDir(dir string) {
+	format, formatErr := resolveSaveFormat(nil)
+	switch {
+	case formatErr != nil:
+		r.add("output", CheckFail, "invalid --save-format: %v", formatErr)
+		return
+	case dir == "" && noPlay:
+		r.add("output", CheckFail, "--no-play requires --output-dir to be set")
+		return
+	case dir == "":
+		r.add("output", CheckPass, "not set, audio is played but not saved")
+		return
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			r.add("output", CheckFail, "output directory does not exist: %s", dir)
+		} else {
+			r.add("output", CheckFail, "failed to access output directory: %v", err)
+		}
+		return
+	}
+	if !info.IsDir() {
+		r.add("output", CheckFail, "output path is not a directory: %s", dir)
+		return
+	}
+	f, err := os.CreateTemp(dir, ".mcp-tts-doctor-*")
+	if err != nil {
+		r.add("output", CheckFail, "output directory is not writable: %v", err)
+		return
+	}
+	f.Close()
+	os.Remove(f.Name())
+	if !isPlayableFormat(format) && !noPlay {
+		r.add("output", CheckWarn, "%s is writable, but --save-format %s requires --no-play", dir, format)
+		return
+	}
+	r.add("output", CheckPass, "%s is writable (format %s)", dir, format)
+}
+
+func (r *doctorReport) checkLockDir(lockDir string) {
+	if !sequentialTTS {
+		r.add("lock", CheckPass, "sequential TTS disabled, no lock is used")
+		return
+	}
+
+	info, err := os.Stat(lockDir)
+	if os.IsNotExist(err) {
+		// The lock is created on demand; make sure that will work
+		probe, err := os.MkdirTemp(filepath.Dir(lockDir), ".mcp-tts-doctor-*")
+		if err != nil {
+			r.add("lock", CheckFail, "cannot create lock in %s: %v", filepath.Dir(lockDir), err)
+			return
+		}
+		os.Remove(probe)
+		r.add("lock", CheckPass, "%s is free", lockDir)
+		return
+	}
+	if err != nil {
+		r.add("lock", CheckFail, "failed to access %s: %v", lockDir, err)
+		return
+	}
+	if !info.IsDir() {
+		r.add("lock", CheckFail, "%s is not a directory; remove it so TTS calls can take the lock", lockDir)
+		return
+	}
+
+	lock := &ttsMutexFile{lockDir: lockDir, contentFile: filepath.Join(lockDir, "content.json")}
+	var content lockContent
+	if data, err := os.ReadFile(lock.contentFile); err == nil {
+		json.Unmarshal(data, &content)
+	}
+	if lock.isStale() {
+		r.add("lock", CheckWarn, "%s is stale (pid %d is gone); the next TTS call will clean it up", lockDir, content.PID)
+		return
+	}
+	if content.PID == 0 {
+		r.add("lock", CheckWarn, "%s has no owner yet; it is treated as stale after 5 minutes", lockDir)
+		return
+	}
+	r.add("lock", CheckPass, "%s is held by pid %d since %s", lockDir, content.PID, content.StartTime.Format(time.RFC3339))
+}
+
+func (r *doctorReport) checkSay() {
+	if runtime.GOOS != "darwin" {
+		return
+	}
+	voice := providerDefaults(ProviderSay, activeConfig.Profile).Voice
+	if voice == "" {
+		r.add("say", CheckPass, "using the system voice")
+		return
+	}
+	installed, err := IsVoiceInstalled(voice)
+	switch {
+	case err != nil:
+		r.add("say", CheckFail, "failed to list voices: %v", err)
+	case !installed:
+		r.add("say", CheckFail, "%s", VoiceNotInstalledError(voice))
+	default:
+		r.add("say", CheckPass, "voice %s is installed", voice)
+	}
+}
+
+func (r *doctorReport) checkElevenLabs(ctx context.Context) {
+	const name = "elevenlabs"
+	apiKey := os.Getenv("ELEVENLABS_API_KEY")
+	if apiKey == "" {
+		r.add(name, CheckWarn, "ELEVENLABS_API_KEY not set, elevenlabs_tts is unavailable")
+		return
+	}
+	voice := providerDefaults(ProviderElevenLabs, activeConfig.Profile).Voice
+	endpoint := providerBaseURL(ProviderElevenLabs) + "/v1/voices/" + url.PathEscape(voice)
+	status, err := doctorProbe(ctx, endpoint, map[string]string{"xi-api-key": apiKey})
+	switch {
+	case err != nil:
+		r.add(name, CheckFail, "%v", err)
+	case status == http.StatusOK:
+		r.add(name, CheckPass, "API key valid, voice %s available", voice)
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		r.add(name, CheckFail, "API key rejected (status %d)", status)
+	case status == http.StatusBadRequest || status == http.StatusNotFound:
+		r.add(name, CheckFail, "voice %s not found (status %d), check ELEVENLABS_VOICE_ID", voice, status)
+	default:
+		r.add(name, CheckFail, "unexpected status %d from %s", status, endpoint)
+	}
+}
+
+func (r *doctorReport) checkGoogle(ctx context.Context) {
+	const name = "google"
+	apiKey := os.Getenv("GOOGLE_AI_API_KEY")
+	if apiKey == "" {
+		apiKey = os.Getenv("GEMINI_API_KEY")
+	}
+	if apiKey == "" {
+		r.add(name, CheckWarn, "GOOGLE_AI_API_KEY or GEMINI_API_KEY not set, google_tts is unavailable")
+		return
+	}
+	settings := providerDefaults(ProviderGoogle, activeConfig.Profile)
+	endpoint := providerBaseURL(ProviderGoogle) + "/v1beta/models/" + url.PathEscape(settings.Model)
+	status, err := doctorProbe(ctx, endpoint, map[string]string{"x-goog-api-key": apiKey})
+	r.checkModel(name, endpoint, settings, GoogleVoices, status, err)
+}
+
+func (r *doctorReport) checkOpenAI(ctx context.Context) {
+	const name = "openai"
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	if apiKey == "" {
+		r.add(name, CheckWarn, "OPENAI_API_KEY not set, openai_tts is unavailable")
+		return
+	}
+	settings := providerDefaults(ProviderOpenAI, activeConfig.Profile)
+	endpoint := providerBaseURL(ProviderOpenAI) + "/models/" + url.PathEscape(settings.Model)
+	status, err := doctorProbe(ctx, endpoint, map[string]string{"Authorization": "Bearer " + apiKey})
+	r.checkModel(name, endpoint, settings, OpenAIVoices, status, err)
+}
+
+// checkModel reports a model lookup against a provider API and whether the
+// configured voice is one the provider offers.
+func (r *doctorReport) checkModel(name, endpoint string, settings providerSettings, voices []string, status int, err error) {
+	switch {
+	case err != nil:
+		r.add(name, CheckFail, "%v", err)
+	case status == http.StatusBadRequest || status == http.StatusUnauthorized || status == http.StatusForbidden:
+		// Gemini answers 400 API_KEY_INVALID rather than 401
+		r.add(name, CheckFail, "API key rejected (status %d)", status)
+	case status == http.StatusNotFound:
+		r.add(name, CheckFail, "model %s not found", settings.Model)
+	case status != http.StatusOK:
+		r.add(name, CheckFail, "unexpected status %d from %s", status, endpoint)
+	case !slices.Contains(voices, settings.Voice):
+		r.add(name, CheckWarn, "API key valid, but voice %s is not one of: %s", settings.Voice, strings.Join(voices, ", "))
+	default:
+		r.add(name, CheckPass, "API key valid, model %s, voice %s", settings.Model, settings.Voice)
+	}
+}
+
+// doctorProbe sends an authenticated GET and returns the response status.
+func doctorProbe(ctx context.Context, endpoint string, headers map[string]string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0, fmt.Errorf("invalid endpoint: %w", err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("cannot reach %s://%s: %w", req.URL.Scheme, req.URL.Host, err)
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+	return res.StatusCode, nil
+}
+
+// write prints the report as a table, or as JSON.
+func (r *doctorReport) write(w io.Writer, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, c := range r.Checks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", strings.ToUpper(c.Status), c.Name, c.Detail)
+	}
+	fmt.Fprintf(tw, "\n%d passed, %d warnings, %d failed\n", r.count(CheckPass), r.count(CheckWarn), r.count(CheckFail))
+	return tw.Flush()
+}
+
+// doctorCmd diagnoses setup problems that otherwise surface as tool errors.
+var doctorCmd = &cobra.Command{
+	Use:          "doctor",
+	Short:        "Check audio output, credentials, voices, lock and output directories, and config",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Report config errors as a failed check instead of aborting
+		doctorConfigErr = setupConfig(cmd.Root().PersistentFlags())
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		report := runDoctor(cmd.Context(), cwd)
+		if err := report.write(cmd.OutOrStdout(), doctorJSON); err != nil {
+			return err
+		}
+		if n := report.count(CheckFail); n > 0 {
+			return fmt.Errorf("%d doctor checks failed", n)
+		}
+		return nil
+	},
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorJSON, "json", false, "Print the report as JSON")
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubProviderAPI answers the doctor's credential probes for "good-key".
+func stubProviderAPI(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("xi-api-key") + r.Header.Get("x-goog-api-key") + r.Header.Get("Authorization")
+		if key != "good-key" && key != "Bearer good-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/voices/" + DefaultElevenLabsVoiceID,
+			"/v1beta/models/" + DefaultGoogleModel,
+			"/models/" + DefaultOpenAIModel:
+			w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("ELEVENLABS_BASE_URL", srv.URL)
+	t.Setenv("GOOGLE_GEMINI_BASE_URL", srv.URL+"/")
+	t.Setenv("OPENAI_BASE_URL", srv.URL)
+	t.Setenv("GOOGLE_AI_API_KEY", "")
+	return srv
+}
+
+func findCheck(t *testing.T, r *doctorReport, name string) doctorCheck {
+	t.Helper()
+	for _, c := range r.Checks {
+		if c.Name == name {
+			return c
+		}
+	}
+	t.Fatalf("no %s check in report", name)
+	return doctorCheck{}
+}
+
+func TestDoctorProviderCredentials(t *testing.T) {
+	stubProviderAPI(t)
+	oldConfig := activeConfig
+	defer func() { activeConfig = oldConfig }()
+	activeConfig = &appConfig{}
+
+	t.Run("valid keys pass", func(t *testing.T) {
+		t.Setenv("ELEVENLABS_API_KEY", "good-key")
+		t.Setenv("GEMINI_API_KEY", "good-key")
+		t.Setenv("OPENAI_API_KEY", "good-key")
+		r := &doctorReport{Status: CheckPass}
+		r.checkElevenLabs(context.Background())
+		r.checkGoogle(context.Background())
+		r.checkOpenAI(context.Background())
+		assert.Equal(t, CheckPass, r.Status, r.Checks)
+	})
+
+	t.Run("rejected key fails", func(t *testing.T) {
+		t.Setenv("OPENAI_API_KEY", "bad-key")
+		r := &doctorReport{Status: CheckPass}
+		r.checkOpenAI(context.Background())
+		c := findCheck(t, r, "openai")
+		assert.Equal(t, CheckFail, c.Status)
+		assert.Contains(t, c.Detail, "API key rejected (status 401)")
+	})
+
+	t.Run("unknown ElevenLabs voice fails", func(t *testing.T) {
+		t.Setenv("ELEVENLABS_API_KEY", "good-key")
+		t.Setenv("ELEVENLABS_VOICE_ID", "nope")
+		r := &doctorReport{Status: CheckPass}
+		r.checkElevenLabs(context.Background())
+		c := findCheck(t, r, "elevenlabs")
+		assert.Equal(t, CheckFail, c.Status)
+		assert.Contains(t, c.Detail, "voice nope not found")
+	})
+
+	t.Run("missing keys warn", func(t *testing.T) {
+		t.Setenv("GEMINI_API_KEY", "")
+		r := &doctorReport{Status: CheckPass}
+		r.checkGoogle(context.Background())
+		assert.Equal(t, CheckWarn, findCheck(t, r, "google").Status)
+	})
+
+	t.Run("unreachable endpoint fails", func(t *testing.T) {
+		t.Setenv("OPENAI_API_KEY", "good-key")
+		t.Setenv("OPENAI_BASE_URL", "http://127.0.0.1:1")
+		r := &doctorReport{Status: CheckPass}
+		r.checkOpenAI(context.Background())
+		assert.Contains(t, findCheck(t, r, "openai").Detail, "cannot reach http://127.0.0.1:1")
+	})
+}
+
+func TestDoctorLockDir(t *testing.T) {
+	origSequential := sequentialTTS
+	defer func() { sequentialTTS = origSequential }()
+	sequentialTTS = true
+	lockDir := filepath.Join(t.TempDir(), "mcp-tts-global.lock.d")
+
+	r := &doctorReport{Status: CheckPass}
+	r.checkLockDir(lockDir)
+	assert.Equal(t, CheckPass, findCheck(t, r, "lock").Status)
+
+	t.Run("held by a live process", func(t *testing.T) {
+		require.NoError(t, os.Mkdir(lockDir, 0755))
+		defer os.RemoveAll(lockDir)
+		data, _ := json.Marshal(lockContent{PID: os.Getpid(), StartTime: time.Now()})
+		writeTestFile(t, lockDir, "content.json", string(data))
+		r := &doctorReport{Status: CheckPass}
+		r.checkLockDir(lockDir)
+		c := findCheck(t, r, "lock")
+		assert.Equal(t, CheckPass, c.Status)
+		assert.Contains(t, c.Detail, "held by pid")
+	})
+
+	t.Run("stale lock warns", func(t *testing.T) {
+		require.NoError(t, os.Mkdir(lockDir, 0755))
+		defer os.RemoveAll(lockDir)
+		writeTestFile(t, lockDir, "content.json", "{corrupt")
+		old := time.Now().Add(-time.Hour)
+		require.NoError(t, os.Chtimes(lockDir, old, old))
+		r := &doctorReport{Status: CheckPass}
+		r.checkLockDir(lockDir)
+		c := findCheck(t, r, "lock")
+		assert.Equal(t, CheckWarn, c.Status)
+		assert.Contains(t, c.Detail, "stale")
+	})
+
+	t.Run("file in the way fails", func(t *testing.T) {
+		path := writeTestFile(t, t.TempDir(), "lock.d", "")
+		r := &doctorReport{Status: CheckPass}
+		r.checkLockDir(path)
+		assert.Equal(t, CheckFail, findCheck(t, r, "lock").Status)
+	})
+}
+
+func TestDoctorOutputDir(t *testing.T) {
+	origNoPlay, origFormat := noPlay, saveFormat
+	defer func() { noPlay, saveFormat = origNoPlay, origFormat }()
+	noPlay, saveFormat = false, ""
+
+	dir := t.TempDir()
+	file := writeTestFile(t, dir, "file.txt", "")
+	tests := []struct {
+		dir, status, detail string
+	}{
+		{"", CheckPass, "not set"},
+		{dir, CheckPass, "is writable"},
+		{filepath.Join(dir, "missing"), CheckFail, "does not exist"},
+		{file, CheckFail, "not a directory"},
+	}
+	for _, tt := range tests {
+		r := &doctorReport{Status: CheckPass}
+		r.checkOutputDir(tt.dir)
+		c := findCheck(t, r, "output")
+		assert.Equal(t, tt.status, c.Status, tt.dir)
+		assert.Contains(t, c.Detail, tt.detail, tt.dir)
+	}
+
+	noPlay = true
+	r := &doctorReport{Status: CheckPass}
+	r.checkOutputDir("")
+	assert.Contains(t, findCheck(t, r, "output").Detail, "--no-play requires --output-dir")
+}
+
+func TestDoctorCommand(t *testing.T) {
+	stubProviderAPI(t)
+	t.Setenv("ELEVENLABS_API_KEY", "")
+	t.Setenv("GEMINI_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "good-key")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	origAudio, origJSON, origConfig, origConfigErr := doctorAudioInit, doctorJSON, activeConfig, doctorConfigErr
+	origNoPlay, origSequential := noPlay, sequentialTTS
+	defer func() {
+		doctorAudioInit, doctorJSON, activeConfig, doctorConfigErr = origAudio, origJSON, origConfig, origConfigErr
+		noPlay, sequentialTTS = origNoPlay, origSequential
+	}()
+	noPlay, sequentialTTS = false, false
+	activeConfig = &appConfig{}
+	doctorAudioInit = func() error { return errors.New("no output device") }
+
+	var out bytes.Buffer
+	doctorCmd.SetOut(&out)
+	doctorCmd.SetContext(context.Background())
+	defer doctorCmd.SetOut(nil)
+
+	doctorJSON = true
+	err := doctorCmd.RunE(doctorCmd, nil)
+	assert.EqualError(t, err, "1 doctor checks failed")
+
+	var report doctorReport
+	require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	assert.Equal(t, CheckFail, report.Status)
+	assert.Equal(t, CheckFail, findCheck(t, &report, "audio").Status)
+	assert.Equal(t, CheckPass, findCheck(t, &report, "openai").Status)
+	assert.Equal(t, CheckWarn, findCheck(t, &report, "elevenlabs").Status)
+
+	t.Run("text report", func(t *testing.T) {
+		out.Reset()
+		doctorJSON = false
+		doctorAudioInit = func() error { return nil }
+		require.NoError(t, doctorCmd.RunE(doctorCmd, nil))
+		assert.Contains(t, out.String(), "PASS  audio")
+		assert.Contains(t, out.String(), "WARN  google")
+		assert.Contains(t, out.String(), "passed, 2 warnings, 0 failed")
+	})
+}
@@ -1,5 +1,15 @@
 package cmd
 
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+)
+
 type SynthesisOptions struct {
 	Stability       float64 `json:"stability,omitempty"`
 	SimilarityBoost float64 `json:"similarity_boost,omitempty"`
@@ -15,4 +25,58 @@ type ElevenLabsParams struct {
 	PreviousText  string           `json:"previous_text,omitempty"`
 	NextText      string           `json:"next_text,omitempty"`
 	VoiceSettings SynthesisOptions `json:"voice_settings"`
+	// Pronunciation dictionaries applied server-side, from the lexicon
+	PronunciationDictionaryLocators []PronunciationDictionaryLocator `json:"pronunciation_dictionary_locators,omitempty"`
+}
+
+// elevenLabsTimestampChunk is one JSON object of a /stream/with-timestamps response.
+type elevenLabsTimestampChunk struct {
+	AudioBase64 string               `json:"audio_base64"`
+	Alignment   *elevenLabsAlignment `json:"alignment"`
+}
+
+// elevenLabsAlignment holds per-character timings in seconds.
+type elevenLabsAlignment struct {
+	Characters []string  `json:"characters"`
+	StartTimes []float64 `json:"character_start_times_seconds"`
+	EndTimes   []float64 `json:"character_end_times_seconds"`
+}
+
+// elevenLabsRequest posts a synthesis request, retrying rate limits and
+// temporary server errors per policy. The caller closes the response body.
+func elevenLabsRequest(ctx context.Context, policy retryPolicy, url, apiKey, accept string, body []byte) (*http.Response, error) {
+	var res *http.Response
+	err := policy.do(ctx, ProviderElevenLabs, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %v", err)
+		}
+		req.Header.Set("xi-api-key", apiKey)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("accept", accept)
+
+		safeLog("Sending HTTP request", req)
+		res, err = http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to send request: %v", err)
+		}
+		if res.StatusCode == http.StatusOK {
+			return nil
+		}
+		defer res.Body.Close()
+
+		log.Error("Request failed", "status", res.Status, "statusCode", res.StatusCode)
+		// Read the error response body for more details
+		errBody, readErr := io.ReadAll(res.Body)
+		errMsg := fmt.Errorf("ElevenLabs API error: status %d %s", res.StatusCode, res.Status)
+		if readErr == nil && len(errBody) > 0 {
+			log.Error("Error response body", "body", string(errBody))
+			errMsg = fmt.Errorf("ElevenLabs API error (status %d): %s", res.StatusCode, string(errBody))
+		}
+		return classifyHTTPError(errMsg, res.StatusCode, res.Header)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
 }
@@ -30,6 +30,7 @@ import (
 
 	"github.com/charmbracelet/log"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // canElicit returns true if the request has a session capable of elicitation.
@@ -84,6 +85,21 @@ func elicitForm(
 	session *mcp.ServerSession,
 	message string,
 	schema map[string]any,
+) elicitationResult {
+	ctx, span := startSpan(ctx, "elicitation")
+	result := sendElicitation(ctx, session, message, schema)
+	outcome := elicitationOutcome(result.Status)
+	span.SetAttributes(attribute.String("elicitation.outcome", outcome))
+	endSpan(span, result.Err)
+	ttsMetrics.elicitations.inc(outcome)
+	return result
+}
+
+func sendElicitation(
+	ctx context.Context,
+	session *mcp.ServerSession,
+	message string,
+	schema map[string]any,
 ) elicitationResult {
 	if session == nil {
 		return elicitationResult{Status: elicitUnavailable}
@@ -222,6 +238,7 @@ func saySettingsSchema() map[string]any {
 				"title":       "Speech Rate (WPM)",
 				"description": "Words per minute, 50-500 (default: 200)",
 			},
+			rememberField: rememberSchemaProperty(),
 		},
 	}
 }
@@ -240,6 +257,7 @@ func googleSettingsSchema() map[string]any {
 				"title": "Model",
 				"enum":  GoogleModels,
 			},
+			rememberField: rememberSchemaProperty(),
 		},
 	}
 }
@@ -263,6 +281,7 @@ func openAISettingsSchema() map[string]any {
 				"title":       "Speed",
 				"description": "0.25-4.0 (default: 1.0)",
 			},
+			rememberField: rememberSchemaProperty(),
 		},
 	}
 }
@@ -320,24 +339,29 @@ func applyOpenAISettings(input *OpenAITTSParams, content map[string]any) {
 }
 
 func sayRecommendationArgs(input SayTTSParams) map[string]any {
+	defaults := providerDefaults(ProviderSay, profileArg(input.Profile))
 	args := map[string]any{
 		"text": input.Text,
-		"rate": DefaultSayRate,
+		"rate": defaults.Rate,
 	}
 	if input.Rate != nil {
 		args["rate"] = *input.Rate
 	}
 	if input.Voice != nil && *input.Voice != "" {
 		args["voice"] = *input.Voice
+	} else if defaults.Voice != "" {
+		args["voice"] = defaults.Voice
 	}
+	addCallArgs(args, input.Profile, input.Category)
 	return args
 }
 
 func googleRecommendationArgs(input GoogleTTSParams) map[string]any {
+	defaults := providerDefaults(ProviderGoogle, profileArg(input.Profile))
 	args := map[string]any{
 		"text":  input.Text,
-		"voice": DefaultGoogleVoice,
-		"model": DefaultGoogleModel,
+		"voice": defaults.Voice,
+		"model": defaults.Model,
 	}
 	if input.Voice != nil && *input.Voice != "" {
 		args["voice"] = *input.Voice
@@ -345,15 +369,17 @@ func googleRecommendationArgs(input GoogleTTSParams) map[string]any {
 	if input.Model != nil && *input.Model != "" {
 		args["model"] = *input.Model
 	}
+	addCallArgs(args, input.Profile, input.Category)
 	return args
 }
 
 func openAIRecommendationArgs(input OpenAITTSParams) map[string]any {
+	defaults := providerDefaults(ProviderOpenAI, profileArg(input.Profile))
 	args := map[string]any{
 		"text":  input.Text,
-		"voice": DefaultOpenAIVoice,
-		"model": DefaultOpenAIModel,
-		"speed": DefaultOpenAISpeed,
+		"voice": defaults.Voice,
+		"model": defaults.Model,
+		"speed": defaults.Speed,
 	}
 	if input.Voice != nil && *input.Voice != "" {
 		args["voice"] = *input.Voice
@@ -367,25 +393,52 @@ func openAIRecommendationArgs(input OpenAITTSParams) map[string]any {
 	if input.Instructions != nil && *input.Instructions != "" {
 		args["instructions"] = *input.Instructions
 	}
+	addCallArgs(args, input.Profile, input.Category)
 	return args
 }
 
+// profileArg returns the profile named by a tool argument, or the startup profile.
+func profileArg(profile *string) string {
+	if profile != nil && *profile != "" {
+		return *profile
+	}
+	return activeConfig.Profile
+}
+
+// addCallArgs passes an explicitly requested profile and category on to the provider tool.
+func addCallArgs(args map[string]any, profile, category *string) {
+	if profile != nil && *profile != "" {
+		args["profile"] = *profile
+	}
+	if category != nil && *category != "" {
+		args["category"] = *category
+	}
+}
+
 func providerRecommendationArgs(providerID, text string, content map[string]any) map[string]any {
+	return ttsRecommendationArgs(providerID, TTSParams{Text: text}, content)
+}
+
+// ttsRecommendationArgs builds the provider tool arguments for a tts call,
+// using the defaults of the profile it names.
+func ttsRecommendationArgs(providerID string, tts TTSParams, content map[string]any) map[string]any {
 	switch providerID {
 	case ProviderSay:
-		input := SayTTSParams{Text: text}
+		input := SayTTSParams{Text: tts.Text, Profile: tts.Profile, Category: tts.Category}
 		applySaySettings(&input, content)
 		return sayRecommendationArgs(input)
 	case ProviderGoogle:
-		input := GoogleTTSParams{Text: text}
+		input := GoogleTTSParams{Text: tts.Text, Profile: tts.Profile, Category: tts.Category}
 		applyGoogleSettings(&input, content)
 		return googleRecommendationArgs(input)
 	case ProviderOpenAI:
-		input := OpenAITTSParams{Text: text}
+		input := OpenAITTSParams{Text: tts.Text, Profile: tts.Profile, Category: tts.Category}
 		applyOpenAISettings(&input, content)
 		return openAIRecommendationArgs(input)
 	default:
-		return map[string]any{"text": text}
+		args := map[string]any{"text": tts.Text}
+		addCallArgs(args, tts.Profile, tts.Category)
+		return args
 	}
 }
 
@@ -409,7 +462,9 @@ func maybeElicitContent(
 	message string,
 	schema map[string]any,
 ) (map[string]any, *mcp.CallToolResult, bool) {
-	if !canElicit(req) {
+	// Calls forwarded after another provider hit a limit use the defaults,
+	// and calls dispatched by the tts tool were already asked
+	if !canElicit(req) || fallingThrough(ctx) || settingsChosen(ctx) {
 		return nil, nil, false
 	}
 
@@ -435,10 +490,10 @@ func buildTTSSchema() json.RawMessage {
 	schema := map[string]any{
 		"type": "object",
 		"properties": map[string]any{
-			"text": map[string]any{
-				"type":        "string",
-				"description": "The text to speak aloud",
-			},
+			"text":     textSchemaProperty("The text to speak aloud"),
+			"condense": condenseSchemaProperty(),
+			"profile":  profileSchemaProperty(),
+			"category": categorySchemaProperty(),
 		},
 		"required": []string{"text"},
 	}
@@ -0,0 +1,337 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// maxElevenLabsDictionaries is the most pronunciation dictionaries one request may reference.
+const maxElevenLabsDictionaries = 3
+
+// lexiconFileNames are the accepted lexicon file names, in lookup order.
+// JSON is valid YAML, so both are parsed with the YAML decoder.
+var lexiconFileNames = []string{"lexicon.yaml", "lexicon.yml", "lexicon.json"}
+
+// elevenLabsPhonemeModels are the ElevenLabs models that honor <phoneme> tags.
+var elevenLabsPhonemeModels = []string{"eleven_flash_v2", "eleven_turbo_v2", "eleven_monolingual_v1"}
+
+// lexiconEntry maps a term to how it should be pronounced.
+type lexiconEntry struct {
+	Term          string `yaml:"term" json:"term"`
+	Alias         string `yaml:"alias,omitempty" json:"alias,omitempty"` // phonetic respelling, e.g. "cube control"
+	IPA           string `yaml:"ipa,omitempty" json:"ipa,omitempty"`
+	CaseSensitive bool   `yaml:"case_sensitive,omitempty" json:"case_sensitive,omitempty"`
+}
+
+// PronunciationDictionaryLocator references a pronunciation dictionary stored in ElevenLabs.
+type PronunciationDictionaryLocator struct {
+	ID        string `yaml:"id" json:"pronunciation_dictionary_id"`
+	VersionID string `yaml:"version_id,omitempty" json:"version_id,omitempty"`
+}
+
+// lexicon is the merged pronunciation lexicon applied to every provider.
+type lexicon struct {
+	Entries                []lexiconEntry                   `yaml:"entries"`
+	ElevenLabsDictionaries []PronunciationDictionaryLocator `yaml:"elevenlabs_dictionaries"`
+
+	pattern *regexp.Regexp
+}
+
+// activeLexicon is the lexicon loaded at startup.
+var activeLexicon *lexicon
+
+// mcpTTSConfigDir returns the user config directory, ~/.config/mcp-tts
+// (or $XDG_CONFIG_HOME/mcp-tts).
+func mcpTTSConfigDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "mcp-tts"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "mcp-tts"), nil
+}
+
+// projectConfigDir is the per-project directory, relative to the project root.
+const projectConfigDir = ".mcp-tts"
+
+// findLexiconFile returns the first lexicon file present in dir, or "".
+func findLexiconFile(dir string) string {
+	for _, name := range lexiconFileNames {
+		fpath := filepath.Join(dir, name)
+		if _, err := os.Stat(fpath); err == nil {
+			return fpath
+		}
+	}
+	return ""
+}
+
+// lexiconFiles returns the global and per-project lexicon files that exist,
+// in merge order (project entries win).
+func lexiconFiles(projectDir string) []string {
+	var files []string
+	if lexiconPath != "" {
+		files = append(files, lexiconPath)
+	} else if dir, err := mcpTTSConfigDir(); err == nil {
+		if f := findLexiconFile(dir); f != "" {
+			files = append(files, f)
+		}
+	}
+	if projectDir != "" {
+		if f := findLexiconFile(filepath.Join(projectDir, projectConfigDir)); f != "" {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// loadLexicon reads and merges lexicon files. Later files override entries
+// for the same term and add their ElevenLabs dictionaries.
+func loadLexicon(paths ...string) (*lexicon, error) {
+	merged := &lexicon{}
+	for _, fpath := range paths {
+		data, err := os.ReadFile(fpath)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && fpath != lexiconPath {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read lexicon: %w", err)
+		}
+		var l lexicon
+		if err := yaml.Unmarshal(data, &l); err != nil {
+			return nil, fmt.Errorf("failed to parse lexicon %s: %w", fpath, err)
+		}
+		for _, e := range l.Entries {
+			if strings.TrimSpace(e.Term) == "" {
+				return nil, fmt.Errorf("lexicon %s: entry without a term", fpath)
+			}
+			if e.Alias == "" && e.IPA == "" {
+				return nil, fmt.Errorf("lexicon %s: %q needs an alias or ipa", fpath, e.Term)
+			}
+			merged.Entries = slices.DeleteFunc(merged.Entries, func(o lexiconEntry) bool {
+				return strings.EqualFold(o.Term, e.Term)
+			})
+			merged.Entries = append(merged.Entries, e)
+		}
+		for _, d := range l.ElevenLabsDictionaries {
+			if d.ID == "" {
+				return nil, fmt.Errorf("lexicon %s: ElevenLabs dictionary without an id", fpath)
+			}
+			merged.ElevenLabsDictionaries = append(merged.ElevenLabsDictionaries, d)
+		}
+		log.Debug("Loaded pronunciation lexicon", "path", fpath, "entries", len(l.Entries))
+	}
+	if n := len(merged.ElevenLabsDictionaries); n > maxElevenLabsDictionaries {
+		log.Warn("Too many ElevenLabs pronunciation dictionaries, using the last ones", "count", n, "max", maxElevenLabsDictionaries)
+		merged.ElevenLabsDictionaries = merged.ElevenLabsDictionaries[n-maxElevenLabsDictionaries:]
+	}
+	merged.compile()
+	return merged, nil
+}
+
+// compile builds one alternation so each span of text is substituted at most
+// once. Longer terms are tried first so "kube proxy" wins over "kube".
+func (l *lexicon) compile() {
+	if len(l.Entries) == 0 {
+		return
+	}
+	entries := slices.Clone(l.Entries)
+	slices.SortStableFunc(entries, func(a, b lexiconEntry) int {
+		return len(b.Term) - len(a.Term)
+	})
+	l.Entries = entries
+
+	alternatives := make([]string, 0, len(entries))
+	for _, e := range entries {
+		term := regexp.QuoteMeta(e.Term)
+		if !e.CaseSensitive {
+			term = "(?i:" + term + ")"
+		}
+		// Only anchor on word boundaries where the term itself starts/ends with a word character
+		if isWordRune(firstRune(e.Term)) {
+			term = `\b` + term
+		}
+		if isWordRune(lastRune(e.Term)) {
+			term += `\b`
+		}
+		alternatives = append(alternatives, term)
+	}
+	l.pattern = regexp.MustCompile(strings.Join(alternatives, "|"))
+}
+
+// elevenLabsLocators returns the configured ElevenLabs pronunciation dictionaries.
+func (l *lexicon) elevenLabsLocators() []PronunciationDictionaryLocator {
+	if l == nil {
+		return nil
+	}
+	return l.ElevenLabsDictionaries
+}
+
+// lookup returns the entry matching a substring found by the pattern.
+func (l *lexicon) lookup(match string) (lexiconEntry, bool) {
+	for _, e := range l.Entries {
+		if e.Term == match || (!e.CaseSensitive && strings.EqualFold(e.Term, match)) {
+			return e, true
+		}
+	}
+	return lexiconEntry{}, false
+}
+
+// apply replaces every lexicon term in text with render's output.
+func (l *lexicon) apply(text string, render func(e lexiconEntry, match string) string) string {
+	if l == nil || l.pattern == nil {
+		return text
+	}
+	return l.pattern.ReplaceAllStringFunc(text, func(match string) string {
+		if e, ok := l.lookup(match); ok {
+			return render(e, match)
+		}
+		return match
+	})
+}
+
+// ipaHints describes IPA-only entries found in text, for providers that take
+// style instructions but no phoneme markup.
+func (l *lexicon) ipaHints(text string) string {
+	if l == nil || l.pattern == nil {
+		return ""
+	}
+	var hints []string
+	for _, match := range l.pattern.FindAllString(text, -1) {
+		e, ok := l.lookup(match)
+		if !ok || e.IPA == "" || e.Alias != "" {
+			continue
+		}
+		hint := fmt.Sprintf("Pronounce %q as /%s/.", e.Term, e.IPA)
+		if !slices.Contains(hints, hint) {
+			hints = append(hints, hint)
+		}
+	}
+	return strings.Join(hints, " ")
+}
+
+// respell renders an entry as its phonetic respelling, keeping the term when
+// only IPA is available.
+func respell(e lexiconEntry, match string) string {
+	if e.Alias != "" {
+		return e.Alias
+	}
+	return match
+}
+
+// elevenLabsPronunciation renders IPA as a <phoneme> tag on models that
+// support it and falls back to the respelling elsewhere.
+func elevenLabsPronunciation(modelID string) func(lexiconEntry, string) string {
+	phonemes := slices.Contains(elevenLabsPhonemeModels, modelID)
+	return func(e lexiconEntry, match string) string {
+		if phonemes && e.IPA != "" {
+			return fmt.Sprintf(`<phoneme alphabet="ipa" ph="%s">%s</phoneme>`, html.EscapeString(e.IPA), match)
+		}
+		return respell(e, match)
+	}
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func firstRune(s string) rune {
+	for _, r := range s {
+		return r
+	}
+	return 0
+}
+
+func lastRune(s string) rune {
+	runes := []rune(s)
+	if len(runes) == 0 {
+		return 0
+	}
+	return runes[len(runes)-1]
+}
+
+// lexiconCmd groups pronunciation lexicon commands.
+var lexiconCmd = &cobra.Command{
+	Use:   "lexicon",
+	Short: "Inspect the pronunciation lexicon",
+}
+
+// lexiconTestCmd previews how the lexicon rewrites a phrase.
+var lexiconTestCmd = &cobra.Command{
+	Use:   "test <phrase>",
+	Short: "Preview the text sent to each provider after lexicon substitution",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := setupLogging(); err != nil {
+			return err
+		}
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		files := lexiconFiles(cwd)
+		lex, err := loadLexicon(files...)
+		if err != nil {
+			return err
+		}
+		activeLexicon = lex
+
+		out := cmd.OutOrStdout()
+		if len(files) == 0 {
+			fmt.Fprintln(out, "No lexicon files found")
+		}
+		for _, f := range files {
+			fmt.Fprintf(out, "Lexicon: %s\n", f)
+		}
+
+		phrase := args[0]
+		fmt.Fprintf(out, "\n%-11s %s\n", "say:", sayInput(phrase, nil, DefaultSayRate))
+		fmt.Fprintf(out, "%-11s %s\n", "elevenlabs:", elevenLabsInput(phrase, nil, DefaultElevenLabsModel))
+		fmt.Fprintf(out, "%-11s %s\n", "google:", googleInput(phrase, nil, ""))
+		openAIText, openAIHints := openAIInput(phrase, nil)
+		fmt.Fprintf(out, "%-11s %s\n", "openai:", openAIText)
+		if openAIHints != "" {
+			fmt.Fprintf(out, "%-11s %s\n", "", "instructions: "+openAIHints)
+		}
+		return nil
+	},
+}
+
+func init() {
+	lexiconCmd.AddCommand(lexiconTestCmd)
+	rootCmd.AddCommand(lexiconCmd)
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadLexicon(t *testing.T) {
+	dir := t.TempDir()
+	global := writeTestFile(t, dir, "lexicon.yaml", `
+entries:
+  - term: kubectl
+    alias: cube control
+  - term: nginx
+    alias: engine x
+elevenlabs_dictionaries:
+  - id: dict1
+    version_id: v1
+`)
+	project := writeTestFile(t, filepath.Join(dir, "project"), "lexicon.json", `{
+  "entries": [
+    {"term": "NGINX", "alias": "engine ex"},
+    {"term": "blacktop", "ipa": "ˈblæktɒp"}
+  ]
+}`)
+
+	lex, err := loadLexicon(global, project)
+	require.NoError(t, err)
+	assert.Len(t, lex.Entries, 3)
+	assert.Equal(t, []PronunciationDictionaryLocator{{ID: "dict1", VersionID: "v1"}}, lex.elevenLabsLocators())
+	assert.Equal(t, "use cube control behind engine ex", lex.apply("use kubectl behind nginx", respell),
+		"project entries override global ones")
+
+	t.Run("missing optional files are skipped", func(t *testing.T) {
+		lex, err := loadLexicon(filepath.Join(dir, "nope.yaml"))
+		require.NoError(t, err)
+		assert.Empty(t, lex.Entries)
+	})
+
+	t.Run("entries need a pronunciation", func(t *testing.T) {
+		bad := writeTestFile(t, dir, "bad.yaml", "entries:\n  - term: foo\n")
+		_, err := loadLexicon(bad)
+		assert.ErrorContains(t, err, "needs an alias or ipa")
+	})
+
+	t.Run("invalid YAML is an error", func(t *testing.T) {
+		bad := writeTestFile(t, dir, "broken.yaml", "entries: [")
+		_, err := loadLexicon(bad)
+		assert.ErrorContains(t, err, "failed to parse lexicon")
+	})
+
+	t.Run("explicit --lexicon path must exist", func(t *testing.T) {
+		origPath := lexiconPath
+		defer func() { lexiconPath = origPath }()
+		lexiconPath = filepath.Join(dir, "missing.yaml")
+		_, err := loadLexicon(lexiconFiles("")...)
+		assert.ErrorContains(t, err, "failed to read lexicon")
+	})
+}
+
+func TestLexiconFiles(t *testing.T) {
+	origPath := lexiconPath
+	defer func() { lexiconPath = origPath }()
+	lexiconPath = ""
+
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	global := writeTestFile(t, filepath.Join(configHome, "mcp-tts"), "lexicon.yml", "entries: []\n")
+	projectDir := t.TempDir()
+	project := writeTestFile(t, filepath.Join(projectDir, projectConfigDir), "lexicon.yaml", "entries: []\n")
+
+	assert.Equal(t, []string{global, project}, lexiconFiles(projectDir))
+	assert.Equal(t, []string{global}, lexiconFiles(""))
+}
+
+func TestLexiconApply(t *testing.T) {
+	lex := &lexicon{Entries: []lexiconEntry{
+		{Term: "kube", Alias: "cube"},
+		{Term: "kube proxy", Alias: "cube proxy service"},
+		{Term: "Go", Alias: "go lang", CaseSensitive: true},
+		{Term: "C++", Alias: "see plus plus"},
+	}}
+	lex.compile()
+
+	tests := []struct {
+		in, want string
+	}{
+		{"restart kube proxy", "restart cube proxy service"},
+		{"KUBE is up", "cube is up"},
+		{"kubernetes", "kubernetes"},
+		{"Go, go, gopher", "go lang, go, gopher"},
+		{"written in C++.", "written in see plus plus."},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, lex.apply(tt.in, respell), tt.in)
+	}
+
+	var empty *lexicon
+	assert.Equal(t, "kube", empty.apply("kube", respell))
+	assert.Empty(t, empty.ipaHints("kube"))
+}
+
+func TestLexiconProviderRendering(t *testing.T) {
+	origLexicon := activeLexicon
+	defer func() { activeLexicon = origLexicon }()
+	activeLexicon = &lexicon{Entries: []lexiconEntry{
+		{Term: "nginx", Alias: "engine x"},
+		{Term: "blacktop", IPA: "ˈblæktɒp"},
+	}}
+	activeLexicon.compile()
+
+	text := "blacktop runs nginx"
+	assert.Equal(t, "blacktop runs engine x", sayInput(text, nil, DefaultSayRate))
+	assert.Equal(t, `<phoneme alphabet="ipa" ph="ˈblæktɒp">blacktop</phoneme> runs engine x`, elevenLabsInput(text, nil, "eleven_flash_v2"))
+	assert.Equal(t, "blacktop runs engine x", elevenLabsInput(text, nil, DefaultElevenLabsModel))
+
+	input, instructions := openAIInput(text, nil)
+	assert.Equal(t, "blacktop runs engine x", input)
+	assert.Equal(t, `Pronounce "blacktop" as /ˈblæktɒp/.`, instructions)
+	assert.Equal(t, "Pronounce \"blacktop\" as /ˈblæktɒp/. Read the following text aloud:\nblacktop runs engine x", googleInput(text, nil, ""))
+
+	t.Run("IPA is escaped inside the phoneme tag", func(t *testing.T) {
+		activeLexicon = &lexicon{Entries: []lexiconEntry{{Term: "quote", IPA: `kwoʊt"<`}}}
+		activeLexicon.compile()
+		assert.Equal(t, `<phoneme alphabet="ipa" ph="kwoʊt&#34;&lt;">quote</phoneme>`, elevenLabsInput("quote", nil, "eleven_flash_v2"))
+		activeLexicon = &lexicon{Entries: []lexiconEntry{
+			{Term: "nginx", Alias: "engine x"},
+			{Term: "blacktop", IPA: "ˈblæktɒp"},
+		}}
+		activeLexicon.compile()
+	})
+
+	t.Run("markup segments are substituted before rendering", func(t *testing.T) {
+		m, err := parseSSML(`<emphasis>nginx</emphasis> <say-as interpret-as="characters">nginx</say-as>`)
+		require.NoError(t, err)
+		assert.Equal(t, "[[emph +]]engine x[[emph -]] [[char LTRL]]nginx[[char NORM]]", sayInput(m.Text(), m, DefaultSayRate))
+	})
+}
+
+func TestLexiconTestCommand(t *testing.T) {
+	origPath := lexiconPath
+	origLexicon := activeLexicon
+	defer func() {
+		lexiconPath = origPath
+		activeLexicon = origLexicon
+	}()
+	lexiconPath = writeTestFile(t, t.TempDir(), "lexicon.yaml", "entries:\n  - term: kubectl\n    alias: cube control\n")
+
+	var out bytes.Buffer
+	lexiconTestCmd.SetOut(&out)
+	defer lexiconTestCmd.SetOut(nil)
+	require.NoError(t, lexiconTestCmd.RunE(lexiconTestCmd, []string{"run kubectl apply"}))
+	assert.Contains(t, out.String(), "say:        run cube control apply")
+	assert.Contains(t, out.String(), "openai:     run cube control apply")
+}
@@ -29,6 +29,30 @@ type ttsMutexFile struct {
 	contentFile string
 }
 
+// globalLockDir returns the lock directory shared by all MCP instances.
+func globalLockDir() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.TempDir(), "mcp-tts-global.lock.d")
+	}
+	return globalTTSLockDir
+}
+
+// withFileLock runs fn while holding a directory lock next to path, so
+// read-modify-write updates of a state file shared by every server on the
+// machine do not overwrite each other.
+func withFileLock(ctx context.Context, path string, fn func() error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	lockDir := path + ".lock.d"
+	lock := &ttsMutexFile{lockDir: lockDir, contentFile: filepath.Join(lockDir, "content.json")}
+	if err := lock.acquireLock(ctx); err != nil {
+		return err
+	}
+	defer lock.releaseLock()
+	return fn()
+}
+
 // acquireGlobalTTSLock - simple file-based locking for multiple MCP instances
 func acquireGlobalTTSLock(ctx context.Context) (release func(), err error) {
 	log.Debug("acquireGlobalTTSLock called", "sequentialTTS", sequentialTTS, "pid", os.Getpid())
@@ -37,10 +61,7 @@ func acquireGlobalTTSLock(ctx context.Context) (release func(), err error) {
 		return func() {}, nil
 	}
 
-	lockDir := globalTTSLockDir
-	if runtime.GOOS == "windows" {
-		lockDir = filepath.Join(os.TempDir(), "mcp-tts-global.lock.d")
-	}
+	lockDir := globalLockDir()
 
 	lock := &ttsMutexFile{
 		lockDir:     lockDir,
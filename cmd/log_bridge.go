@@ -0,0 +1,122 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// clientLoggerName names this server in log notifications sent to clients.
+const clientLoggerName = "mcp-tts"
+
+// mcpLogLevels maps logger levels to MCP logging levels.
+var mcpLogLevels = map[log.Level]mcp.LoggingLevel{
+	log.DebugLevel: "debug",
+	log.InfoLevel:  "info",
+	log.WarnLevel:  "warning",
+	log.ErrorLevel: "error",
+	log.FatalLevel: "critical",
+}
+
+// logBridge receives every log record as JSON, writes it to the local
+// logger and sends it to connected clients as a logging/message
+// notification. Each session only gets records at or above the level its
+// client set with logging/setLevel; clients that never set one get none.
+type logBridge struct {
+	server *mcp.Server
+	local  *log.Logger
+}
+
+// forwardLogs makes the default logger also send its records to the clients
+// of s. Records carry the same fields as local logs, so the --log-text policy
+// and header masking apply to both.
+func forwardLogs(s *mcp.Server) {
+	local := log.Default()
+	bridge := log.New(&logBridge{server: s, local: local})
+	bridge.SetFormatter(log.JSONFormatter)
+	// The local logger and each client apply their own levels
+	bridge.SetLevel(log.DebugLevel)
+	log.SetDefault(bridge)
+}
+
+func (b *logBridge) Write(p []byte) (int, error) {
+	level, msg, keyvals, err := parseLogRecord(p)
+	if err != nil {
+		b.local.Print(strings.TrimSpace(string(p)))
+		return len(p), nil
+	}
+	b.local.Log(level, msg, keyvals...)
+
+	data := map[string]any{log.MessageKey: msg}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		data[keyvals[i].(string)] = keyvals[i+1]
+	}
+	params := &mcp.LoggingMessageParams{Level: mcpLogLevels[level], Logger: clientLoggerName, Data: data}
+	for ss := range b.server.Sessions() {
+		// Sessions drop records below their client's level. Failures are not
+		// logged, which would only produce another record to send.
+		_ = ss.Log(context.Background(), params)
+	}
+	return len(p), nil
+}
+
+// parseLogRecord splits a JSON log record into its level, message and the
+// remaining fields in their original order.
+func parseLogRecord(p []byte) (log.Level, string, []any, error) {
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return 0, "", nil, fmt.Errorf("log record is not a JSON object")
+	}
+	level := log.InfoLevel
+	var msg string
+	var keyvals []any
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return 0, "", nil, err
+		}
+		key, _ := tok.(string)
+		var value any
+		if err := dec.Decode(&value); err != nil {
+			return 0, "", nil, err
+		}
+		switch key {
+		case log.LevelKey:
+			if level, err = log.ParseLevel(fmt.Sprint(value)); err != nil {
+				return 0, "", nil, err
+			}
+		case log.MessageKey:
+			msg = fmt.Sprint(value)
+		default:
+			keyvals = append(keyvals, key, value)
+		}
+	}
+	return level, msg, keyvals, nil
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logClient forwards logs to a connected client and returns the local log
+// output and the notifications the client receives.
+func logClient(t *testing.T, level mcp.LoggingLevel) (*bytes.Buffer, <-chan *mcp.LoggingMessageParams) {
+	t.Helper()
+	var buf bytes.Buffer
+	origLogger := log.Default()
+	local := log.New(&buf)
+	log.SetDefault(local)
+	t.Cleanup(func() { log.SetDefault(origLogger) })
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "mcp-tts"}, nil)
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	_, err := server.Connect(context.Background(), serverTransport, nil)
+	require.NoError(t, err)
+	received := make(chan *mcp.LoggingMessageParams, 10)
+	client := mcp.NewClient(&mcp.Implementation{Name: "test"}, &mcp.ClientOptions{
+		LoggingMessageHandler: func(ctx context.Context, req *mcp.LoggingMessageRequest) {
+			received <- req.Params
+		},
+	})
+	session, err := client.Connect(context.Background(), clientTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { session.Close() })
+	if level != "" {
+		require.NoError(t, session.SetLoggingLevel(context.Background(), &mcp.SetLoggingLevelParams{Level: level}))
+	}
+	forwardLogs(server)
+	return &buf, received
+}
+
+func TestForwardLogsRespectsClientLevel(t *testing.T) {
+	buf, received := logClient(t, "warning")
+
+	log.Info("Using default voice", "voice", "alloy")
+	log.Warn("Speed out of range, using default", "speed", 9, "default", 1.0)
+
+	select {
+	case params := <-received:
+		assert.Equal(t, mcp.LoggingLevel("warning"), params.Level)
+		assert.Equal(t, clientLoggerName, params.Logger)
+		data, err := json.Marshal(params.Data)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"msg":"Speed out of range, using default","speed":9,"default":1}`, string(data))
+	case <-time.After(time.Second):
+		t.Fatal("no log notification received")
+	}
+	select {
+	case params := <-received:
+		t.Fatalf("unexpected notification %v", params.Data)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	out := buf.String()
+	assert.Contains(t, out, "Using default voice voice=alloy")
+	assert.Contains(t, out, "Speed out of range, using default speed=9 default=1")
+}
+
+func TestForwardLogsNeedsSetLevel(t *testing.T) {
+	buf, received := logClient(t, "")
+
+	log.Error("Failed to save MP3 file", "error", "disk full")
+	assert.Contains(t, buf.String(), "Failed to save MP3 file")
+	select {
+	case params := <-received:
+		t.Fatalf("unexpected notification %v", params.Data)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestForwardLogsKeepsRedaction(t *testing.T) {
+	origMode := logTextMode
+	t.Cleanup(func() { logTextMode = origMode })
+	logTextMode = LogTextNone
+	buf, received := logClient(t, "debug")
+
+	log.Debug("Speaking", "text", logText("my secret plan"))
+
+	select {
+	case params := <-received:
+		assert.Equal(t, mcp.LoggingLevel("debug"), params.Level)
+		assert.Equal(t, "[14 chars]", params.Data.(map[string]any)["text"])
+	case <-time.After(time.Second):
+		t.Fatal("no log notification received")
+	}
+	// The local logger keeps its own level
+	assert.Empty(t, buf.String())
+}
+
+func TestParseLogRecord(t *testing.T) {
+	level, msg, keyvals, err := parseLogRecord([]byte(`{"level":"error","msg":"Failed","b":"x","a":2}` + "\n"))
+	require.NoError(t, err)
+	assert.Equal(t, log.ErrorLevel, level)
+	assert.Equal(t, "Failed", msg)
+	assert.Equal(t, []any{"b", "x", "a", json.Number("2")}, keyvals)
+
+	_, _, _, err = parseLogRecord([]byte("not json"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,204 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/log"
+)
+
+// Spoken text logging policies for --log-text.
+const (
+	LogTextNone      = "none"      // log only the length of spoken text
+	LogTextTruncated = "truncated" // log the first logTextPreview characters
+	LogTextFull      = "full"
+)
+
+// Log output formats for --log-format.
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// logTextPreview is how many characters of spoken text truncated mode keeps.
+const logTextPreview = 32
+
+var (
+	LogTextModes  = []string{LogTextNone, LogTextTruncated, LogTextFull}
+	LogFormats    = []string{LogFormatText, LogFormatJSON}
+	sensitiveKeys = []string{"text", "input"}
+	// maskedHeaders are replaced before HTTP requests are logged
+	maskedHeaders = []string{"Xi-Api-Key", "Authorization", "X-Goog-Api-Key"}
+)
+
+var (
+	logTextMode   string = LogTextNone
+	logFormat     string = LogFormatText
+	logFile       string
+	logMaxSizeMB  int = 10
+	logMaxBackups int = 3
+)
+
+// logText applies the --log-text policy to spoken text before it is logged.
+// Spoken content is not persisted in logs unless explicitly enabled.
+func logText(text string) string {
+	switch logTextMode {
+	case LogTextFull:
+		return text
+	case LogTextTruncated:
+		runes := []rune(text)
+		if len(runes) <= logTextPreview {
+			return text
+		}
+		return string(runes[:logTextPreview]) + fmt.Sprintf("… (%d chars)", len(runes))
+	default:
+		return fmt.Sprintf("[%d chars]", len([]rune(text)))
+	}
+}
+
+// loggableParams converts tool or request parameters into a map for logging,
+// applying the --log-text policy to text fields and dereferencing pointers.
+func loggableParams(params any) map[string]any {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+	for _, key := range sensitiveKeys {
+		if text, ok := fields[key].(string); ok {
+			fields[key] = logText(text)
+		}
+	}
+	return fields
+}
+
+// setupLogging applies the verbosity, format and destination flags.
+func setupLogging() error {
+	if !slices.Contains(LogTextModes, logTextMode) {
+		return fmt.Errorf("invalid --log-text %q (supported: %s)", logTextMode, strings.Join(LogTextModes, ", "))
+	}
+	if verbose {
+		log.SetLevel(log.DebugLevel)
+	}
+	switch logFormat {
+	case LogFormatText:
+	case LogFormatJSON:
+		log.SetFormatter(log.JSONFormatter)
+	default:
+		return fmt.Errorf("invalid --log-format %q (supported: %s)", logFormat, strings.Join(LogFormats, ", "))
+	}
+	if logFile != "" {
+		f, err := newRotatingFile(logFile, int64(logMaxSizeMB)*1024*1024, logMaxBackups)
+		if err != nil {
+			return err
+		}
+		log.SetOutput(f)
+	}
+	return nil
+}
+
+// rotatingFile is an append-only log file that is rotated to path.1,
+// path.2, ... once it grows past maxSize.
+type rotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	f          *os.File
+	size       int64
+}
+
+func newRotatingFile(path string, maxSize int64, maxBackups int) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	r.f = f
+	r.size = info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate shifts path.N-1 to path.N (dropping the oldest) and starts a new file.
+func (r *rotatingFile) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+	if r.maxBackups <= 0 {
+		if err := os.Remove(r.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return r.open()
+	}
+	for i := r.maxBackups - 1; i >= 1; i-- {
+		src := fmt.Sprintf("%s.%d", r.path, i)
+		if _, err := os.Stat(src); err == nil {
+			if err := os.Rename(src, fmt.Sprintf("%s.%d", r.path, i+1)); err != nil {
+				return err
+			}
+		}
+	}
+	if err := os.Rename(r.path, r.path+".1"); err != nil {
+		return err
+	}
+	return r.open()
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Close()
+}
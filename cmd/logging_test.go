@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogText(t *testing.T) {
+	origMode := logTextMode
+	defer func() { logTextMode = origMode }()
+	text := "The deployment to production finished without errors"
+
+	logTextMode = LogTextNone
+	assert.Equal(t, "[52 chars]", logText(text))
+
+	logTextMode = LogTextTruncated
+	assert.Equal(t, "The deployment to production fin… (52 chars)", logText(text))
+	assert.Equal(t, "short", logText("short"))
+
+	logTextMode = LogTextFull
+	assert.Equal(t, text, logText(text))
+}
+
+func TestLoggableParams(t *testing.T) {
+	origMode := logTextMode
+	defer func() { logTextMode = origMode }()
+	logTextMode = LogTextNone
+
+	fields := loggableParams(OpenAITTSParams{
+		Text:  "secret plans",
+		Voice: stringPtr("nova"),
+		Speed: float64Ptr(1.5),
+	})
+	assert.Equal(t, "[12 chars]", fields["text"])
+	assert.Equal(t, "nova", fields["voice"])
+	assert.Equal(t, 1.5, fields["speed"])
+	_, hasModel := fields["model"]
+	assert.False(t, hasModel)
+}
+
+func TestSafeLogMasksCredentials(t *testing.T) {
+	var buf bytes.Buffer
+	origLogger := log.Default()
+	logger := log.New(&buf)
+	logger.SetLevel(log.DebugLevel)
+	log.SetDefault(logger)
+	defer log.SetDefault(origLogger)
+
+	req, err := http.NewRequest(http.MethodPost, "https://example.com", nil)
+	require.NoError(t, err)
+	req.Header.Set("xi-api-key", "xi-secret")
+	req.Header.Set("Authorization", "Bearer bearer-secret")
+	req.Header.Set("x-goog-api-key", "goog-secret")
+
+	safeLog("Sending HTTP request", req)
+	out := buf.String()
+	assert.Contains(t, out, "Sending HTTP request")
+	for _, secret := range []string{"xi-secret", "bearer-secret", "goog-secret"} {
+		assert.NotContains(t, out, secret)
+	}
+	assert.Equal(t, "Bearer bearer-secret", req.Header.Get("Authorization"), "original request is untouched")
+}
+
+func TestSetupLoggingValidation(t *testing.T) {
+	origMode, origFormat := logTextMode, logFormat
+	defer func() { logTextMode, logFormat = origMode, origFormat }()
+
+	logTextMode, logFormat = "everything", LogFormatText
+	assert.ErrorContains(t, setupLogging(), "invalid --log-text")
+
+	logTextMode, logFormat = LogTextNone, "xml"
+	assert.ErrorContains(t, setupLogging(), "invalid --log-format")
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mcp-tts.log")
+	f, err := newRotatingFile(path, 10, 2)
+	require.NoError(t, err)
+	defer f.Close()
+
+	for _, line := range []string{"aaaaaaaa\n", "bbbbbbbb\n", "cccccccc\n", "dddddddd\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	read := func(p string) string {
+		data, err := os.ReadFile(p)
+		require.NoError(t, err)
+		return string(data)
+	}
+	assert.Equal(t, "dddddddd\n", read(path))
+	assert.Equal(t, "cccccccc\n", read(path+".1"))
+	assert.Equal(t, "bbbbbbbb\n", read(path+".2"))
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err), "only maxBackups files are kept")
+
+	t.Run("appends to an existing file", func(t *testing.T) {
+		g, err := newRotatingFile(path, 1024, 2)
+		require.NoError(t, err)
+		_, err = g.Write([]byte("eeee\n"))
+		require.NoError(t, err)
+		require.NoError(t, g.Close())
+		assert.True(t, strings.HasSuffix(read(path), "dddddddd\neeee\n"))
+	})
+}
@@ -0,0 +1,401 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/charmbracelet/log"
+)
+
+// Markup modes accepted by the per-call markup argument.
+const (
+	MarkupNone = "none"
+	MarkupSSML = "ssml"
+)
+
+// MarkupModes lists the accepted markup modes.
+var MarkupModes = []string{MarkupNone, MarkupSSML}
+
+// maxElevenLabsBreak is the longest <break> ElevenLabs accepts.
+const maxElevenLabsBreak = 3 * time.Second
+
+// breakStrengths maps SSML break strengths to pause lengths.
+var breakStrengths = map[string]time.Duration{
+	"none":     0,
+	"x-weak":   100 * time.Millisecond,
+	"weak":     250 * time.Millisecond,
+	"medium":   500 * time.Millisecond,
+	"strong":   time.Second,
+	"x-strong": 2 * time.Second,
+}
+
+// prosodyRates maps SSML rate keywords to speed multipliers.
+var prosodyRates = map[string]float64{
+	"x-slow":  0.5,
+	"slow":    0.75,
+	"medium":  1,
+	"default": 1,
+	"fast":    1.25,
+	"x-fast":  1.5,
+}
+
+// speechSegment is either a run of text with its prosody or a pause.
+type speechSegment struct {
+	Text     string
+	Pause    time.Duration
+	Emphasis string  // "", "reduced", "moderate" or "strong"
+	SayAs    string  // interpret-as value, e.g. "characters" or "digits"
+	Rate     float64 // speed multiplier; 0 means unchanged
+}
+
+// speechMarkup is provider-neutral marked-up text, parsed once and rendered
+// per provider. Features a provider cannot express are dropped.
+type speechMarkup struct {
+	Segments []speechSegment
+}
+
+// parseMarkup parses text according to the markup mode. Returns nil for plain text.
+func parseMarkup(text string, mode *string) (*speechMarkup, error) {
+	if mode == nil || *mode == "" || *mode == MarkupNone {
+		return nil, nil
+	}
+	if *mode != MarkupSSML {
+		return nil, fmt.Errorf("unsupported markup %q (supported: %s)", *mode, strings.Join(MarkupModes, ", "))
+	}
+	m, err := parseSSML(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SSML: %w", err)
+	}
+	return m, nil
+}
+
+// parseSSML parses the supported SSML subset: <break>, <emphasis>, <say-as>
+// and <prosody rate>. The <speak> root is optional and unknown elements keep
+// their text.
+func parseSSML(input string) (*speechMarkup, error) {
+	src := strings.TrimSpace(input)
+	if !strings.HasPrefix(src, "<speak") {
+		src = "<speak>" + src + "</speak>"
+	}
+
+	dec := xml.NewDecoder(strings.NewReader(src))
+	// Non-strict so a stray "&" in plain prose is not an error
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+
+	m := &speechMarkup{}
+	stack := []speechSegment{{}}
+	var open []string
+	for {
+		// RawToken leaves element matching to us; Token would silently
+		// auto-close mismatched elements in non-strict mode
+		tok, err := dec.RawToken()
+		if errors.Is(err, io.EOF) {
+			if len(open) > 0 {
+				return nil, fmt.Errorf("unclosed <%s>", open[len(open)-1])
+			}
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			style := stack[len(stack)-1]
+			attrs := make(map[string]string, len(t.Attr))
+			for _, a := range t.Attr {
+				attrs[a.Name.Local] = a.Value
+			}
+			switch t.Name.Local {
+			case "speak":
+			case "break":
+				pause, err := parseBreak(attrs)
+				if err != nil {
+					return nil, err
+				}
+				m.Segments = append(m.Segments, speechSegment{Pause: pause})
+			case "emphasis":
+				style.Emphasis = "moderate"
+				if level := attrs["level"]; level != "" {
+					style.Emphasis = level
+				}
+			case "say-as":
+				style.SayAs = attrs["interpret-as"]
+			case "prosody":
+				if rate := attrs["rate"]; rate != "" {
+					r, err := parseProsodyRate(rate)
+					if err != nil {
+						return nil, err
+					}
+					style.Rate = r
+				}
+			default:
+				log.Debug("Ignoring unsupported SSML element", "element", t.Name.Local)
+			}
+			stack = append(stack, style)
+			open = append(open, t.Name.Local)
+		case xml.EndElement:
+			if len(open) == 0 || open[len(open)-1] != t.Name.Local {
+				return nil, fmt.Errorf("unexpected </%s>", t.Name.Local)
+			}
+			stack = stack[:len(stack)-1]
+			open = open[:len(open)-1]
+		case xml.CharData:
+			style := stack[len(stack)-1]
+			style.Text = string(t)
+			m.Segments = append(m.Segments, style)
+		}
+	}
+	return m, nil
+}
+
+// parseBreak returns the pause for a <break> element's time or strength.
+func parseBreak(attrs map[string]string) (time.Duration, error) {
+	if v := attrs["time"]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return 0, fmt.Errorf("invalid break time %q", v)
+		}
+		return d, nil
+	}
+	strength := attrs["strength"]
+	if strength == "" {
+		strength = "medium"
+	}
+	d, ok := breakStrengths[strength]
+	if !ok {
+		return 0, fmt.Errorf("invalid break strength %q", strength)
+	}
+	return d, nil
+}
+
+// parseProsodyRate parses a rate keyword or percentage into a multiplier.
+func parseProsodyRate(v string) (float64, error) {
+	if r, ok := prosodyRates[v]; ok {
+		return r, nil
+	}
+	if pct, ok := strings.CutSuffix(v, "%"); ok {
+		if n, err := strconv.ParseFloat(pct, 64); err == nil && n > 0 {
+			return n / 100, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid prosody rate %q", v)
+}
+
+// Text returns the markup's text content without any markup, for display,
+// filenames and captions.
+func (m *speechMarkup) Text() string {
+	var b strings.Builder
+	for _, seg := range m.Segments {
+		b.WriteString(seg.Text)
+	}
+	return collapseSpaces(b.String())
+}
+
+// markupRenderer translates segments for one provider. A nil hook means the
+// provider cannot express that feature and the text passes through unchanged.
+type markupRenderer struct {
+	pause    func(d time.Duration) string
+	sayAs    func(text, interpretAs string) string
+	emphasis func(text, level string) string
+	rate     func(text string, rate float64) string
+}
+
+func (m *speechMarkup) render(r markupRenderer) string {
+	var b strings.Builder
+	for _, seg := range m.Segments {
+		if seg.Text == "" {
+			if seg.Pause > 0 && r.pause != nil {
+				b.WriteString(" " + r.pause(seg.Pause) + " ")
+			}
+			continue
+		}
+		text := seg.Text
+		if seg.SayAs != "" && r.sayAs != nil {
+			text = r.sayAs(text, seg.SayAs)
+		}
+		if seg.Emphasis != "" && r.emphasis != nil {
+			text = r.emphasis(text, seg.Emphasis)
+		}
+		if seg.Rate != 0 && seg.Rate != 1 && r.rate != nil {
+			text = r.rate(text, seg.Rate)
+		}
+		b.WriteString(text)
+	}
+	return collapseSpaces(b.String())
+}
+
+// styleHints describes emphasis and rate changes in prose, for providers
+// that take natural-language style instructions instead of inline markup.
+func (m *speechMarkup) styleHints() string {
+	var hints []string
+	for _, seg := range m.Segments {
+		text := collapseSpaces(seg.Text)
+		switch {
+		case seg.Text == "" && seg.Pause > 0:
+			hint := "Pause briefly at each ellipsis (...)."
+			if !slices.Contains(hints, hint) {
+				hints = append(hints, hint)
+			}
+		case text == "":
+		case seg.Emphasis == "strong":
+			hints = append(hints, fmt.Sprintf("Strongly emphasize %q.", text))
+		case seg.Emphasis == "moderate":
+			hints = append(hints, fmt.Sprintf("Emphasize %q.", text))
+		case seg.Emphasis == "reduced":
+			hints = append(hints, fmt.Sprintf("De-emphasize %q.", text))
+		}
+		if text != "" && seg.Rate != 0 && seg.Rate != 1 {
+			pace := "slowly"
+			if seg.Rate > 1 {
+				pace = "quickly"
+			}
+			hints = append(hints, fmt.Sprintf("Say %q %s.", text, pace))
+		}
+	}
+	return strings.Join(hints, " ")
+}
+
+// spellOut separates letters or digits so they are read one at a time.
+// Other interpret-as values are left for the provider to read naturally.
+func spellOut(text, interpretAs string) string {
+	switch interpretAs {
+	case "characters", "spell-out", "verbatim", "digits":
+	default:
+		return text
+	}
+	var parts []string
+	for _, word := range strings.Fields(text) {
+		var chars []string
+		for _, r := range word {
+			chars = append(chars, string(r))
+		}
+		parts = append(parts, strings.Join(chars, " "))
+	}
+	// Keep surrounding whitespace so neighbouring words do not run together
+	lead := text[:len(text)-len(strings.TrimLeftFunc(text, unicode.IsSpace))]
+	trail := text[len(strings.TrimRightFunc(text, unicode.IsSpace)):]
+	return lead + strings.Join(parts, ", ") + trail
+}
+
+// ellipsisPause is the fallback for providers without explicit pauses.
+func ellipsisPause(time.Duration) string { return "..." }
+
+// renderSay translates markup into macOS say embedded commands.
+func (m *speechMarkup) renderSay(baseRate int) string {
+	return m.render(markupRenderer{
+		pause: func(d time.Duration) string {
+			return fmt.Sprintf("[[slnc %d]]", d.Milliseconds())
+		},
+		sayAs: func(text, interpretAs string) string {
+			if spellOut(text, interpretAs) == text {
+				return text
+			}
+			return "[[char LTRL]]" + text + "[[char NORM]]"
+		},
+		emphasis: func(text, level string) string {
+			if level == "reduced" {
+				return text
+			}
+			return "[[emph +]]" + text + "[[emph -]]"
+		},
+		rate: func(text string, rate float64) string {
+			return fmt.Sprintf("[[rate %d]]%s[[rate %d]]", int(float64(baseRate)*rate), text, baseRate)
+		},
+	})
+}
+
+// renderElevenLabs translates markup for an ElevenLabs model. Eleven v3 uses
+// audio tags and capitalization; older models accept <break> tags.
+func (m *speechMarkup) renderElevenLabs(modelID string) string {
+	if strings.HasPrefix(modelID, "eleven_v3") {
+		return m.render(markupRenderer{
+			pause: func(d time.Duration) string {
+				if d >= time.Second {
+					return "[long pause]"
+				}
+				return "[short pause]"
+			},
+			sayAs: spellOut,
+			emphasis: func(text, level string) string {
+				if level == "reduced" {
+					return text
+				}
+				return strings.ToUpper(text)
+			},
+		})
+	}
+	return m.render(markupRenderer{
+		pause: func(d time.Duration) string {
+			return fmt.Sprintf(`<break time="%.1fs" />`, min(d, maxElevenLabsBreak).Seconds())
+		},
+		sayAs: spellOut,
+	})
+}
+
+// renderOpenAI returns the text to synthesize and style instructions to
+// append to the request's instructions.
+func (m *speechMarkup) renderOpenAI() (text, instructions string) {
+	return m.render(markupRenderer{pause: ellipsisPause, sayAs: spellOut}), m.styleHints()
+}
+
+// renderGoogle returns a Gemini prompt, prefixing a style direction when the
+// markup has emphasis or rate changes.
+func (m *speechMarkup) renderGoogle(extraHints string) string {
+	text := m.render(markupRenderer{pause: ellipsisPause, sayAs: spellOut})
+	return googleStylePrompt(strings.TrimSpace(m.styleHints()+" "+extraHints), text)
+}
+
+// googleStylePrompt prefixes text with natural-language style directions.
+func googleStylePrompt(hints, text string) string {
+	if hints == "" {
+		return text
+	}
+	return fmt.Sprintf("%s Read the following text aloud:\n%s", hints, text)
+}
+
+// withLexicon returns a copy with lexicon substitutions applied to the text
+// segments. Spelled-out segments are left alone.
+func (m *speechMarkup) withLexicon(l *lexicon, render func(lexiconEntry, string) string) *speechMarkup {
+	out := &speechMarkup{Segments: slices.Clone(m.Segments)}
+	for i, seg := range out.Segments {
+		if seg.SayAs == "" {
+			out.Segments[i].Text = l.apply(seg.Text, render)
+		}
+	}
+	return out
+}
+
+// collapseSpaces collapses runs of whitespace into single spaces.
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
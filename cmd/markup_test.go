@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSSML(t *testing.T) {
+	t.Run("speak root is optional", func(t *testing.T) {
+		withRoot, err := parseSSML("<speak>Hello world</speak>")
+		require.NoError(t, err)
+		withoutRoot, err := parseSSML("Hello world")
+		require.NoError(t, err)
+		assert.Equal(t, withRoot, withoutRoot)
+		assert.Equal(t, "Hello world", withoutRoot.Text())
+	})
+
+	t.Run("supported elements", func(t *testing.T) {
+		m, err := parseSSML(`Deploy <emphasis level="strong">now</emphasis>.<break time="750ms"/>` +
+			`Run <say-as interpret-as="characters">CLI</say-as> <prosody rate="slow">carefully</prosody>`)
+		require.NoError(t, err)
+		assert.Equal(t, []speechSegment{
+			{Text: "Deploy "},
+			{Text: "now", Emphasis: "strong"},
+			{Text: "."},
+			{Pause: 750 * time.Millisecond},
+			{Text: "Run "},
+			{Text: "CLI", SayAs: "characters"},
+			{Text: " "},
+			{Text: "carefully", Rate: 0.75},
+		}, m.Segments)
+		assert.Equal(t, "Deploy now.Run CLI carefully", m.Text())
+	})
+
+	t.Run("nested styles combine", func(t *testing.T) {
+		m, err := parseSSML(`<prosody rate="150%"><emphasis>fast and loud</emphasis></prosody>`)
+		require.NoError(t, err)
+		require.Len(t, m.Segments, 1)
+		assert.Equal(t, speechSegment{Text: "fast and loud", Emphasis: "moderate", Rate: 1.5}, m.Segments[0])
+	})
+
+	t.Run("break strengths", func(t *testing.T) {
+		m, err := parseSSML(`a<break/>b<break strength="x-strong"/>c`)
+		require.NoError(t, err)
+		assert.Equal(t, 500*time.Millisecond, m.Segments[1].Pause)
+		assert.Equal(t, 2*time.Second, m.Segments[3].Pause)
+	})
+
+	t.Run("unknown elements keep their text", func(t *testing.T) {
+		m, err := parseSSML(`<voice name="x">Hello</voice> &amp; AT&T`)
+		require.NoError(t, err)
+		assert.Equal(t, "Hello & AT&T", m.Text())
+	})
+
+	errorCases := map[string]string{
+		"mismatched tags":  `<emphasis>oops</prosody>`,
+		"bad break time":   `<break time="soon"/>`,
+		"bad strength":     `<break strength="huge"/>`,
+		"bad prosody rate": `<prosody rate="warp">x</prosody>`,
+	}
+	for name, input := range errorCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseSSML(input)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseMarkup(t *testing.T) {
+	m, err := parseMarkup("<break/>", nil)
+	require.NoError(t, err)
+	assert.Nil(t, m, "markup is opt-in")
+
+	m, err = parseMarkup("<break/>", stringPtr(MarkupNone))
+	require.NoError(t, err)
+	assert.Nil(t, m)
+
+	m, err = parseMarkup("hi<break/>", stringPtr(MarkupSSML))
+	require.NoError(t, err)
+	require.NotNil(t, m)
+
+	_, err = parseMarkup("hi", stringPtr("markdown"))
+	assert.ErrorContains(t, err, "unsupported markup")
+
+	_, err = parseMarkup("<emphasis>hi", stringPtr(MarkupSSML))
+	assert.ErrorContains(t, err, "invalid SSML")
+}
+
+func TestMarkupRendering(t *testing.T) {
+	m, err := parseSSML(`Ship it.<break time="1s"/>Ask <say-as interpret-as="characters">QA</say-as> ` +
+		`<emphasis>today</emphasis>, <prosody rate="slow">please</prosody>.`)
+	require.NoError(t, err)
+
+	t.Run("say embedded commands", func(t *testing.T) {
+		assert.Equal(t,
+			"Ship it. [[slnc 1000]] Ask [[char LTRL]]QA[[char NORM]] [[emph +]]today[[emph -]], [[rate 150]]please[[rate 200]].",
+			m.renderSay(200))
+	})
+
+	t.Run("elevenlabs v3 audio tags", func(t *testing.T) {
+		assert.Equal(t, "Ship it. [long pause] Ask Q A TODAY, please.", m.renderElevenLabs("eleven_v3"))
+	})
+
+	t.Run("elevenlabs break tags", func(t *testing.T) {
+		assert.Equal(t, `Ship it. <break time="1.0s" /> Ask Q A today, please.`, m.renderElevenLabs("eleven_multilingual_v2"))
+	})
+
+	t.Run("elevenlabs caps break length", func(t *testing.T) {
+		long, err := parseSSML(`a<break time="10s"/>b`)
+		require.NoError(t, err)
+		assert.Equal(t, `a <break time="3.0s" /> b`, long.renderElevenLabs("eleven_turbo_v2_5"))
+	})
+
+	t.Run("openai instructions", func(t *testing.T) {
+		text, instructions := m.renderOpenAI()
+		assert.Equal(t, "Ship it. ... Ask Q A today, please.", text)
+		assert.Equal(t, `Pause briefly at each ellipsis (...). Emphasize "today". Say "please" slowly.`, instructions)
+	})
+
+	t.Run("google style prompt", func(t *testing.T) {
+		assert.Equal(t,
+			"Pause briefly at each ellipsis (...). Emphasize \"today\". Say \"please\" slowly. Read the following text aloud:\nShip it. ... Ask Q A today, please.",
+			m.renderGoogle(""))
+
+		plain, err := parseSSML("Just words.")
+		require.NoError(t, err)
+		assert.Equal(t, "Just words.", plain.renderGoogle(""))
+	})
+}
+
+func TestSpellOut(t *testing.T) {
+	assert.Equal(t, "A P I", spellOut("API", "characters"))
+	assert.Equal(t, " 4 2, 7 ", spellOut(" 42 7 ", "digits"))
+	assert.Equal(t, "42", spellOut("42", "cardinal"))
+}
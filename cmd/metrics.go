@@ -0,0 +1,339 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// metricsAddr is the --metrics-addr flag: where to serve /metrics, "" to disable.
+var metricsAddr string
+
+// latencyBuckets are the histogram buckets, in seconds, for every duration metric.
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// metric is one metric family written in the Prometheus text format.
+type metric interface {
+	write(w io.Writer)
+}
+
+// metricRegistry holds the metric families served on /metrics.
+type metricRegistry struct {
+	metrics []metric
+}
+
+func (r *metricRegistry) counter(name, help string, labels ...string) *counterVec {
+	c := &counterVec{name: name, help: help, labels: labels, values: make(map[string]float64)}
+	r.metrics = append(r.metrics, c)
+	return c
+}
+
+func (r *metricRegistry) histogram(name, help string, labels ...string) *histogramVec {
+	h := &histogramVec{name: name, help: help, labels: labels, series: make(map[string]*histogramSeries)}
+	r.metrics = append(r.metrics, h)
+	return h
+}
+
+func (r *metricRegistry) gauge(name, help string, value func() float64) {
+	r.metrics = append(r.metrics, &gaugeFunc{name: name, help: help, value: value})
+}
+
+// write renders every metric family.
+func (r *metricRegistry) write(w io.Writer) {
+	for _, m := range r.metrics {
+		m.write(w)
+	}
+}
+
+// ServeHTTP serves the registry in the Prometheus text exposition format.
+func (r *metricRegistry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.write(w)
+}
+
+// labelKey joins label values into a map key; labelPairs splits it again.
+func labelKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// labelEscaper escapes label values for the text format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labelPairs formats label names and a label key as {a="x",b="y"}, adding extra pairs.
+func labelPairs(names []string, key string, extra ...string) string {
+	var pairs []string
+	if len(names) > 0 {
+		for i, v := range strings.Split(key, "\xff") {
+			pairs = append(pairs, names[i]+`="`+labelEscaper.Replace(v)+`"`)
+		}
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+labelEscaper.Replace(extra[i+1])+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// counterVec is a counter with labels.
+type counterVec struct {
+	name, help string
+	labels     []string
+	mu         sync.Mutex
+	values     map[string]float64
+}
+
+func (c *counterVec) inc(values ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[labelKey(values)]++
+}
+
+func (c *counterVec) value(values ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[labelKey(values)]
+}
+
+func (c *counterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, key := range slices.Sorted(maps.Keys(c.values)) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, labelPairs(c.labels, key), formatFloat(c.values[key]))
+	}
+}
+
+// histogramVec is a histogram with labels, using latencyBuckets.
+type histogramVec struct {
+	name, help string
+	labels     []string
+	mu         sync.Mutex
+	series     map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+func (h *histogramVec) observe(seconds float64, values ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key := labelKey(values)
+	s := h.series[key]
+	if s == nil {
+		s = &histogramSeries{counts: make([]uint64, len(latencyBuckets))}
+		h.series[key] = s
+	}
+	if i, _ := slices.BinarySearch(latencyBuckets, seconds); i < len(latencyBuckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += seconds
+}
+
+// since observes the time elapsed since start.
+func (h *histogramVec) since(start time.Time, values ...string) {
+	h.observe(time.Since(start).Seconds(), values...)
+}
+
+func (h *histogramVec) count(values ...string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s := h.series[labelKey(values)]; s != nil {
+		return s.count
+	}
+	return 0
+}
+
+func (h *histogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for _, key := range slices.Sorted(maps.Keys(h.series)) {
+		s := h.series[key]
+		var cumulative uint64
+		for i, le := range latencyBuckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelPairs(h.labels, key, "le", formatFloat(le)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelPairs(h.labels, key, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, labelPairs(h.labels, key), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, labelPairs(h.labels, key), s.count)
+	}
+}
+
+// gaugeFunc is a gauge read when metrics are scraped.
+type gaugeFunc struct {
+	name, help string
+	value      func() float64
+}
+
+func (g *gaugeFunc) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", g.name, g.help, g.name, g.name, formatFloat(g.value()))
+}
+
+// ttsMetrics instruments the stages of a speech request.
+var ttsMetrics = newTTSMetrics()
+
+type speechMetrics struct {
+	registry     *metricRegistry
+	requests     *counterVec
+	duration     *histogramVec
+	synthesis    *histogramVec
+	firstAudio   *histogramVec
+	playback     *histogramVec
+	lockWait     *histogramVec
+	elicitations *counterVec
+	limited      *counterVec
+}
+
+func newTTSMetrics() *speechMetrics {
+	r := &metricRegistry{}
+	m := &speechMetrics{
+		registry:     r,
+		requests:     r.counter("mcp_tts_requests_total", "Speech tool calls by provider and status (ok, error, cancelled).", "provider", "status"),
+		duration:     r.histogram("mcp_tts_request_duration_seconds", "Time from a speech tool call to its result.", "provider"),
+		synthesis:    r.histogram("mcp_tts_synthesis_duration_seconds", "Time a cloud provider takes to return audio.", "provider"),
+		firstAudio:   r.histogram("mcp_tts_time_to_first_audio_seconds", "Time from a speech tool call until playback starts.", "provider"),
+		playback:     r.histogram("mcp_tts_playback_duration_seconds", "Time spent playing audio.", "provider"),
+		lockWait:     r.histogram("mcp_tts_lock_wait_seconds", "Time spent waiting for the speech queue and global lock."),
+		elicitations: r.counter("mcp_tts_elicitations_total", "Elicitation requests by outcome (accepted, rejected, failed, unavailable).", "outcome"),
+		limited:      r.counter("mcp_tts_limited_total", "Speech tool calls refused by a rate limit or budget, by provider.", "provider"),
+	}
+	r.gauge("mcp_tts_queue_depth", "Speech calls waiting for their turn in this process.", func() float64 {
+		return float64(localSpeechQueue.depth())
+	})
+	return m
+}
+
+// callStartKey holds the time a speech tool call started.
+type callStartKey struct{}
+
+// instrumentSpeech counts, times and traces a provider tool's calls. Calls
+// that fall through to another provider keep the original start time.
+func instrumentSpeech[In any](providerID string, handler mcp.ToolHandlerFor[In, any]) mcp.ToolHandlerFor[In, any] {
+	provider := providerKey(providerID)
+	return func(ctx context.Context, req *mcp.CallToolRequest, input In) (*mcp.CallToolResult, any, error) {
+		start := time.Now()
+		if _, ok := ctx.Value(callStartKey{}).(time.Time); !ok {
+			ctx = context.WithValue(ctx, callStartKey{}, start)
+		}
+		setAuditProvider(ctx, provider)
+		ctx, span := startToolSpan(ctx, req, providerID)
+		result, out, err := handler(ctx, req, input)
+		endToolSpan(span, result, err)
+		ttsMetrics.requests.inc(provider, requestStatus(ctx, result, err))
+		ttsMetrics.duration.since(start, provider)
+		return result, out, err
+	}
+}
+
+// requestStatus classifies a tool result for the requests metric.
+func requestStatus(ctx context.Context, result *mcp.CallToolResult, err error) string {
+	switch {
+	case ctx.Err() != nil:
+		return "cancelled"
+	case err != nil || result == nil || result.IsError:
+		return "error"
+	default:
+		return "ok"
+	}
+}
+
+// startPlayback records the time to first audio of a call and starts the
+// playback span. It returns a function that ends the span and records how
+// long playback took.
+func startPlayback(ctx context.Context, providerID string) func() {
+	provider := providerKey(providerID)
+	now := time.Now()
+	if start, ok := ctx.Value(callStartKey{}).(time.Time); ok {
+		ttsMetrics.firstAudio.observe(now.Sub(start).Seconds(), provider)
+	}
+	_, span := startSpan(ctx, "playback", attribute.String("tts.provider", provider))
+	return func() {
+		span.End()
+		ttsMetrics.playback.since(now, provider)
+	}
+}
+
+// elicitationOutcome names an elicitation status for the elicitations metric.
+func elicitationOutcome(status elicitationStatus) string {
+	switch status {
+	case elicitAccepted:
+		return "accepted"
+	case elicitRejected:
+		return "rejected"
+	case elicitFailed:
+		return "failed"
+	default:
+		return "unavailable"
+	}
+}
+
+// serveMetrics serves /metrics on addr until ctx is done.
+func serveMetrics(ctx context.Context, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on --metrics-addr %s: %w", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", ttsMetrics.registry)
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("Metrics server stopped", "error", err)
+		}
+	}()
+	log.Info("Serving metrics", "addr", "http://"+ln.Addr().String()+"/metrics")
+	return nil
+}
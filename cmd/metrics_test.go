@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useTestMetrics gives a test its own metrics.
+func useTestMetrics(t *testing.T) *speechMetrics {
+	t.Helper()
+	orig := ttsMetrics
+	t.Cleanup(func() { ttsMetrics = orig })
+	ttsMetrics = newTTSMetrics()
+	return ttsMetrics
+}
+
+func TestMetricRegistryFormat(t *testing.T) {
+	r := &metricRegistry{}
+	c := r.counter("test_total", "A counter.", "provider", "status")
+	c.inc("openai", "ok")
+	c.inc("openai", "ok")
+	c.inc(`we"ird`, "error")
+	h := r.histogram("test_seconds", "A histogram.", "provider")
+	h.observe(0.3, "google")
+	h.observe(200, "google")
+	r.gauge("test_depth", "A gauge.", func() float64 { return 3 })
+
+	var out bytes.Buffer
+	r.write(&out)
+	text := out.String()
+	assert.Contains(t, text, "# HELP test_total A counter.\n# TYPE test_total counter\n")
+	assert.Contains(t, text, `test_total{provider="openai",status="ok"} 2`)
+	assert.Contains(t, text, `test_total{provider="we\"ird",status="error"} 1`)
+	assert.Contains(t, text, "# TYPE test_seconds histogram\n")
+	assert.Contains(t, text, `test_seconds_bucket{provider="google",le="0.25"} 0`)
+	assert.Contains(t, text, `test_seconds_bucket{provider="google",le="0.5"} 1`)
+	assert.Contains(t, text, `test_seconds_bucket{provider="google",le="120"} 1`)
+	assert.Contains(t, text, `test_seconds_bucket{provider="google",le="+Inf"} 2`)
+	assert.Contains(t, text, `test_seconds_sum{provider="google"} 200.3`)
+	assert.Contains(t, text, `test_seconds_count{provider="google"} 2`)
+	assert.Contains(t, text, "# TYPE test_depth gauge\ntest_depth 3\n")
+}
+
+func TestInstrumentSpeech(t *testing.T) {
+	m := useTestMetrics(t)
+
+	var seen time.Time
+	handler := instrumentSpeech(ProviderOpenAI, func(ctx context.Context, req *mcp.CallToolRequest, input TTSParams) (*mcp.CallToolResult, any, error) {
+		seen, _ = ctx.Value(callStartKey{}).(time.Time)
+		if input.Text == "fail" {
+			return errorResult("Error: boom"), nil, nil
+		}
+		return textResult("ok"), nil, nil
+	})
+
+	_, _, err := handler(context.Background(), nil, TTSParams{Text: "hello"})
+	require.NoError(t, err)
+	assert.False(t, seen.IsZero(), "the call start is passed on to the handler")
+	_, _, err = handler(context.Background(), nil, TTSParams{Text: "fail"})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = handler(ctx, nil, TTSParams{Text: "hello"})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1.0, m.requests.value("openai", "ok"))
+	assert.Equal(t, 1.0, m.requests.value("openai", "error"))
+	assert.Equal(t, 1.0, m.requests.value("openai", "cancelled"))
+	assert.Equal(t, uint64(3), m.duration.count("openai"))
+
+	t.Run("fall-through keeps the original start", func(t *testing.T) {
+		start := time.Now().Add(-time.Minute)
+		_, _, err := handler(context.WithValue(context.Background(), callStartKey{}, start), nil, TTSParams{Text: "hello"})
+		require.NoError(t, err)
+		assert.Equal(t, start, seen)
+	})
+}
+
+func TestStartPlayback(t *testing.T) {
+	m := useTestMetrics(t)
+
+	stop := startPlayback(context.Background(), ProviderGoogle)
+	stop()
+	assert.Equal(t, uint64(0), m.firstAudio.count("google"), "time to first audio needs a call start")
+	assert.Equal(t, uint64(1), m.playback.count("google"))
+
+	ctx := context.WithValue(context.Background(), callStartKey{}, time.Now().Add(-2*time.Second))
+	startPlayback(ctx, ProviderGoogle)()
+	assert.Equal(t, uint64(1), m.firstAudio.count("google"))
+	assert.Equal(t, uint64(2), m.playback.count("google"))
+}
+
+func TestElicitationMetrics(t *testing.T) {
+	m := useTestMetrics(t)
+
+	result := elicitForm(context.Background(), nil, "pick", nil)
+	assert.Equal(t, elicitUnavailable, result.Status)
+	assert.Equal(t, 1.0, m.elicitations.value("unavailable"))
+	for status, outcome := range map[elicitationStatus]string{
+		elicitAccepted: "accepted",
+		elicitRejected: "rejected",
+		elicitFailed:   "failed",
+	} {
+		assert.Equal(t, outcome, elicitationOutcome(status))
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	m := useTestMetrics(t)
+	m.requests.inc("say", "ok")
+	m.lockWait.observe(0.2)
+
+	srv := httptest.NewServer(m.registry)
+	defer srv.Close()
+	res, err := http.Get(srv.URL + "/metrics")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", res.Header.Get("Content-Type"))
+	assert.Contains(t, string(body), `mcp_tts_requests_total{provider="say",status="ok"} 1`)
+	assert.Contains(t, string(body), "mcp_tts_lock_wait_seconds_count 1")
+	assert.Contains(t, string(body), "mcp_tts_queue_depth 0")
+
+	t.Run("unusable address is an error", func(t *testing.T) {
+		err := serveMetrics(context.Background(), "256.0.0.1:bad")
+		assert.ErrorContains(t, err, "failed to listen on --metrics-addr")
+	})
+}
@@ -0,0 +1,285 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/log"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// preferencesFile holds remembered settings per MCP client name
+	preferencesFile = "preferences.json"
+	// rememberField is the settings form checkbox that keeps the answers
+	rememberField = "remember"
+)
+
+// persistPreferences keeps remembered settings across server restarts.
+var persistPreferences bool
+
+// storedPreferences is the persisted preferences.json.
+type storedPreferences struct {
+	Clients map[string]map[string]map[string]any `json:"clients"` // client name -> provider -> settings
+}
+
+// preferenceStore remembers the settings a user accepted in a settings form,
+// per MCP session and, with --persist-preferences, per client name. Updates
+// to preferences.json hold a lock next to it, since several servers may
+// share the file.
+type preferenceStore struct {
+	mu       sync.Mutex
+	sessions map[*mcp.ServerSession]map[string]map[string]any // session -> provider -> settings
+	path     string
+}
+
+// activePreferences is used by the tool handlers.
+var activePreferences = newPreferenceStore()
+
+func newPreferenceStore() *preferenceStore {
+	s := &preferenceStore{sessions: make(map[*mcp.ServerSession]map[string]map[string]any)}
+	if dir, err := mcpTTSConfigDir(); err == nil {
+		s.path = filepath.Join(dir, preferencesFile)
+	}
+	return s
+}
+
+// clientName identifies a client across sessions and restarts.
+func clientName(req *mcp.CallToolRequest) string {
+	if req == nil || req.Session == nil {
+		return ""
+	}
+	params := req.Session.InitializeParams()
+	if params == nil || params.ClientInfo == nil {
+		return ""
+	}
+	return params.ClientInfo.Name
+}
+
+func (s *preferenceStore) persisted() bool {
+	return persistPreferences && s.path != ""
+}
+
+func (s *preferenceStore) load() (*storedPreferences, error) {
+	prefs := &storedPreferences{Clients: make(map[string]map[string]map[string]any)}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return prefs, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, prefs); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.path, err)
+	}
+	if prefs.Clients == nil {
+		prefs.Clients = make(map[string]map[string]map[string]any)
+	}
+	return prefs, nil
+}
+
+func (s *preferenceStore) save(prefs *storedPreferences) error {
+	data, err := json.MarshalIndent(prefs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data)
+}
+
+// lookup returns the remembered settings for a provider, or nil.
+func (s *preferenceStore) lookup(req *mcp.CallToolRequest, providerID string) map[string]any {
+	if req == nil || req.Session == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if settings := s.sessions[req.Session][providerID]; settings != nil {
+		return settings
+	}
+	client := clientName(req)
+	if !s.persisted() || client == "" {
+		return nil
+	}
+	prefs, err := s.load()
+	if err != nil {
+		log.Warn("Failed to read remembered settings", "error", err)
+		return nil
+	}
+	return prefs.Clients[client][providerKey(providerID)]
+}
+
+// remember keeps settings for the rest of the session, and for the client
+// when preferences persist.
+func (s *preferenceStore) remember(ctx context.Context, req *mcp.CallToolRequest, providerID string, content map[string]any) {
+	if req == nil || req.Session == nil {
+		return
+	}
+	settings := maps.Clone(content)
+	delete(settings, rememberField)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sessions[req.Session] == nil {
+		s.sessions[req.Session] = make(map[string]map[string]any)
+	}
+	s.sessions[req.Session][providerID] = settings
+
+	client := clientName(req)
+	if !s.persisted() || client == "" {
+		return
+	}
+	err := withFileLock(ctx, s.path, func() error {
+		prefs, err := s.load()
+		if err != nil {
+			return err
+		}
+		if prefs.Clients[client] == nil {
+			prefs.Clients[client] = make(map[string]map[string]any)
+		}
+		prefs.Clients[client][providerKey(providerID)] = settings
+		return s.save(prefs)
+	})
+	if err != nil {
+		log.Warn("Failed to save remembered settings", "error", err)
+	}
+}
+
+// reset forgets the settings remembered for a session and its client, for
+// one provider or, with an empty providerID, all of them.
+func (s *preferenceStore) reset(ctx context.Context, req *mcp.CallToolRequest, providerID string) error {
+	if req == nil || req.Session == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if providerID == "" {
+		delete(s.sessions, req.Session)
+	} else {
+		delete(s.sessions[req.Session], providerID)
+	}
+
+	client := clientName(req)
+	if !s.persisted() || client == "" {
+		return nil
+	}
+	return withFileLock(ctx, s.path, func() error {
+		prefs, err := s.load()
+		if err != nil {
+			return err
+		}
+		if _, ok := prefs.Clients[client]; !ok {
+			return nil
+		}
+		if providerID == "" {
+			delete(prefs.Clients, client)
+		} else {
+			delete(prefs.Clients[client], providerKey(providerID))
+		}
+		return s.save(prefs)
+	})
+}
+
+// forget drops a closed session's settings.
+func (s *preferenceStore) forget(session *mcp.ServerSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, session)
+}
+
+// forgetPreferencesOnClose drops a session's remembered settings once it ends.
+func forgetPreferencesOnClose(_ context.Context, req *mcp.InitializedRequest) {
+	store := activePreferences
+	go func() {
+		req.Session.Wait()
+		store.forget(req.Session)
+	}()
+}
+
+// rememberSchemaProperty is the settings form checkbox that keeps the answers.
+func rememberSchemaProperty() map[string]any {
+	description := "Use these settings for the rest of this session without asking again"
+	if persistPreferences {
+		description = "Use these settings from now on without asking again"
+	}
+	return map[string]any{
+		"type":        "boolean",
+		"title":       "Remember my choice",
+		"description": description,
+	}
+}
+
+// elicitSettings returns the settings remembered for a provider, or asks the
+// user for them and remembers the answers when they tick "Remember my choice".
+func elicitSettings(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	providerID string,
+	action string,
+	message string,
+	schema map[string]any,
+) (map[string]any, *mcp.CallToolResult, bool) {
+	if settings := activePreferences.lookup(req, providerID); settings != nil {
+		log.Debug("Using remembered settings", "provider", providerID)
+		return settings, nil, false
+	}
+	content, result, stop := maybeElicitContent(ctx, req, action, message, schema)
+	if stop {
+		return nil, result, true
+	}
+	if remember, _ := content[rememberField].(bool); remember {
+		activePreferences.remember(ctx, req, providerID, content)
+	}
+	return content, nil, false
+}
+
+// ResetPreferencesParams are the arguments of tts_reset_preferences.
+type ResetPreferencesParams struct {
+	Provider *string `json:"provider,omitempty" mcp:"Provider whose remembered settings to forget (say, elevenlabs, google or openai); all providers when omitted"`
+}
+
+// resetPreferences handles tts_reset_preferences.
+func resetPreferences(ctx context.Context, req *mcp.CallToolRequest, input ResetPreferencesParams) (*mcp.CallToolResult, any, error) {
+	providerID, scope := "", "all providers"
+	if input.Provider != nil && *input.Provider != "" {
+		var ok bool
+		if providerID, ok = providerConfigKeys[*input.Provider]; !ok {
+			keys := slices.Sorted(maps.Keys(providerConfigKeys))
+			return errorResult(fmt.Sprintf("Error: unknown provider %q (supported: %s)", *input.Provider, strings.Join(keys, ", "))), nil, nil
+		}
+		scope = *input.Provider
+	}
+	if err := activePreferences.reset(ctx, req, providerID); err != nil {
+		return errorResult(fmt.Sprintf("Error: Failed to reset remembered settings: %v", err)), nil, nil
+	}
+	return textResult("Forgot remembered TTS settings for " + scope + "; the next call will ask again"), nil, nil
+}
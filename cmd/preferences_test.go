@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useTestPreferences gives a test its own preference store.
+func useTestPreferences(t *testing.T, persist bool) *preferenceStore {
+	t.Helper()
+	origStore, origPersist := activePreferences, persistPreferences
+	t.Cleanup(func() { activePreferences, persistPreferences = origStore, origPersist })
+	activePreferences = newPreferenceStore()
+	activePreferences.path = filepath.Join(t.TempDir(), preferencesFile)
+	persistPreferences = persist
+	return activePreferences
+}
+
+// preferencesServer serves a tool that reports the OpenAI settings it would
+// use, and tts_reset_preferences.
+func preferencesServer() *mcp.Server {
+	server := mcp.NewServer(&mcp.Implementation{Name: "mcp-tts"}, &mcp.ServerOptions{InitializedHandler: forgetPreferencesOnClose})
+	mcp.AddTool(server, &mcp.Tool{Name: "openai_tts"}, func(ctx context.Context, req *mcp.CallToolRequest, input OpenAITTSParams) (*mcp.CallToolResult, any, error) {
+		content, result, stop := elicitSettings(ctx, req, ProviderOpenAI, "elicit OpenAI TTS settings", "Configure OpenAI TTS settings:", openAISettingsSchema())
+		if stop {
+			return result, nil, nil
+		}
+		applyOpenAISettings(&input, content)
+		if input.Voice == nil {
+			return textResult("default voice"), nil, nil
+		}
+		return textResult("voice " + *input.Voice), nil, nil
+	})
+	mcp.AddTool(server, &mcp.Tool{Name: "tts_reset_preferences"}, resetPreferences)
+	return server
+}
+
+// preferencesClient connects a client that answers settings forms with the
+// given content and counts how often it is asked.
+func preferencesClient(t *testing.T, server *mcp.Server, name string, content map[string]any, asked *int) *mcp.ClientSession {
+	t.Helper()
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	_, err := server.Connect(context.Background(), serverTransport, nil)
+	require.NoError(t, err)
+	client := mcp.NewClient(&mcp.Implementation{Name: name}, &mcp.ClientOptions{
+		ElicitationHandler: func(ctx context.Context, req *mcp.ElicitRequest) (*mcp.ElicitResult, error) {
+			*asked++
+			return &mcp.ElicitResult{Action: "accept", Content: content}, nil
+		},
+	})
+	session, err := client.Connect(context.Background(), clientTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { session.Close() })
+	return session
+}
+
+func callTool(t *testing.T, session *mcp.ClientSession, name string, args map[string]any) string {
+	t.Helper()
+	result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: name, Arguments: args})
+	require.NoError(t, err)
+	return resultText(result)
+}
+
+func TestSettingsSchemasOfferRemember(t *testing.T) {
+	for _, provider := range []string{ProviderSay, ProviderGoogle, ProviderOpenAI} {
+		properties := settingsSchemaForProvider(provider)["properties"].(map[string]any)
+		assert.Equal(t, "boolean", properties[rememberField].(map[string]any)["type"], provider)
+	}
+}
+
+func TestRememberSessionPreferences(t *testing.T) {
+	useTestPreferences(t, false)
+	server := preferencesServer()
+
+	var asked int
+	session := preferencesClient(t, server, "test-client", map[string]any{"voice": "nova", "remember": true}, &asked)
+	assert.Equal(t, "voice nova", callTool(t, session, "openai_tts", map[string]any{"text": "one"}))
+	assert.Equal(t, "voice nova", callTool(t, session, "openai_tts", map[string]any{"text": "two"}))
+	assert.Equal(t, 1, asked, "remembered settings apply without asking")
+
+	var otherAsked int
+	other := preferencesClient(t, server, "test-client", map[string]any{}, &otherAsked)
+	assert.Equal(t, "default voice", callTool(t, other, "openai_tts", map[string]any{"text": "hi"}))
+	assert.Equal(t, 1, otherAsked, "other sessions are asked")
+
+	assert.Contains(t, callTool(t, session, "tts_reset_preferences", map[string]any{"provider": "openai"}), "Forgot remembered TTS settings for openai")
+	callTool(t, session, "openai_tts", map[string]any{"text": "three"})
+	assert.Equal(t, 2, asked, "reset settings are asked for again")
+
+	assert.Equal(t, `Error: unknown provider "azure" (supported: elevenlabs, google, openai, say)`,
+		callTool(t, session, "tts_reset_preferences", map[string]any{"provider": "azure"}))
+
+	t.Run("unticked answers are not remembered", func(t *testing.T) {
+		var asked int
+		session := preferencesClient(t, server, "test-client", map[string]any{"voice": "onyx"}, &asked)
+		callTool(t, session, "openai_tts", map[string]any{"text": "one"})
+		callTool(t, session, "openai_tts", map[string]any{"text": "two"})
+		assert.Equal(t, 2, asked)
+	})
+}
+
+func TestPersistPreferences(t *testing.T) {
+	store := useTestPreferences(t, true)
+	server := preferencesServer()
+
+	var asked int
+	first := preferencesClient(t, server, "claude-code", map[string]any{"voice": "sage", "speed": 1.2, "remember": true}, &asked)
+	callTool(t, first, "openai_tts", map[string]any{"text": "one"})
+	require.NoError(t, first.Close())
+
+	prefs, err := store.load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"voice": "sage", "speed": 1.2}, prefs.Clients["claude-code"]["openai"])
+
+	second := preferencesClient(t, server, "claude-code", nil, &asked)
+	assert.Equal(t, "voice sage", callTool(t, second, "openai_tts", map[string]any{"text": "two"}))
+	assert.Equal(t, 1, asked, "the client's settings outlive the session")
+
+	var otherAsked int
+	other := preferencesClient(t, server, "cursor", nil, &otherAsked)
+	callTool(t, other, "openai_tts", map[string]any{"text": "three"})
+	assert.Equal(t, 1, otherAsked, "settings are kept per client name")
+
+	callTool(t, second, "tts_reset_preferences", nil)
+	prefs, err = store.load()
+	require.NoError(t, err)
+	assert.NotContains(t, prefs.Clients, "claude-code")
+}
+
+func TestPersistPreferencesWaitsForOtherServers(t *testing.T) {
+	store := useTestPreferences(t, true)
+	server := preferencesServer()
+
+	var asked int
+	session := preferencesClient(t, server, "claude-code", map[string]any{"voice": "sage", "remember": true}, &asked)
+	done := make(chan struct{})
+	require.NoError(t, withFileLock(context.Background(), store.path, func() error {
+		go func() {
+			defer close(done)
+			session.CallTool(context.Background(), &mcp.CallToolParams{Name: "openai_tts", Arguments: map[string]any{"text": "one"}})
+		}()
+		select {
+		case <-done:
+			t.Error("settings were saved while another server held the lock")
+		case <-time.After(200 * time.Millisecond):
+		}
+		// another server remembers settings for its client meanwhile
+		return store.save(&storedPreferences{Clients: map[string]map[string]map[string]any{
+			"cursor": {"openai": {"voice": "echo"}},
+		}})
+	}))
+	<-done
+
+	prefs, err := store.load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"voice": "sage"}, prefs.Clients["claude-code"]["openai"])
+	assert.Equal(t, map[string]any{"voice": "echo"}, prefs.Clients["cursor"]["openai"], "the other server's settings are kept")
+}
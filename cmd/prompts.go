@@ -0,0 +1,367 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxPromptFileBytes caps the file read_aloud_file puts in its prompt.
+const maxPromptFileBytes = 256 << 10
+
+// providerArgument lets a prompt's user pick the provider tool to call.
+var providerArgument = &mcp.PromptArgument{
+	Name:        "provider",
+	Title:       "Provider",
+	Description: "TTS provider to speak with (say, elevenlabs, google or openai); the first configured one when omitted",
+}
+
+// serverPrompt pairs a prompt with the handler that renders it.
+type serverPrompt struct {
+	prompt  *mcp.Prompt
+	handler mcp.PromptHandler
+}
+
+// serverPrompts are the prompts the server offers.
+var serverPrompts = []serverPrompt{
+	{&mcp.Prompt{
+		Name:        "announce_summary",
+		Title:       "Announce Summary",
+		Description: "Condense finished work into a short spoken summary and announce it",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "content", Title: "Content", Description: "The work, discussion or output to summarize", Required: true},
+			providerArgument,
+		},
+	}, announceSummaryPrompt},
+	{&mcp.Prompt{
+		Name:        "announce_error",
+		Title:       "Announce Error",
+		Description: "Explain a failure out loud: what broke, the likely cause and what happens next",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "error", Title: "Error", Description: "The error message, failing output or problem description", Required: true},
+			{Name: "context", Title: "Context", Description: "What was being attempted when it failed"},
+			providerArgument,
+		},
+	}, announceErrorPrompt},
+	{&mcp.Prompt{
+		Name:        "read_aloud_file",
+		Title:       "Read File Aloud",
+		Description: "Read a text file aloud, adapted for listening",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "path", Title: "Path", Description: "File in the project or the client's roots, absolute or relative to the project", Required: true},
+			providerArgument,
+		},
+	}, readAloudFilePrompt},
+	{&mcp.Prompt{
+		Name:        "daily_standup_briefing",
+		Title:       "Daily Standup Briefing",
+		Description: "Turn standup notes into a spoken briefing: what was done, what is next and what is blocked",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "yesterday", Title: "Yesterday", Description: "What was done since the last standup", Required: true},
+			{Name: "today", Title: "Today", Description: "What is planned next", Required: true},
+			{Name: "blockers", Title: "Blockers", Description: "Anything blocked or needing help"},
+			providerArgument,
+		},
+	}, standupBriefingPrompt},
+	{&mcp.Prompt{
+		Name:        "speak_text",
+		Title:       "Speak Text",
+		Description: "Speak text word for word with a chosen voice, model, profile and category",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "text", Title: "Text", Description: "The text to speak", Required: true},
+			providerArgument,
+			{Name: "voice", Title: "Voice", Description: "Voice to speak with; the project's voice when omitted"},
+			{Name: "model", Title: "Model", Description: "Model to synthesize with (google and openai only)"},
+			{Name: "profile", Title: "Profile", Description: "Named config profile supplying defaults"},
+			{Name: "category", Title: "Category", Description: "Kind of message (info, success, warning, error, summary, question); info when omitted"},
+		},
+	}, speakTextPrompt},
+}
+
+// addPrompts registers the announcement prompts.
+func addPrompts(s *mcp.Server) {
+	for _, p := range serverPrompts {
+		s.AddPrompt(p.prompt, p.handler)
+	}
+}
+
+// promptTakes reports whether the named prompt has the argument.
+func promptTakes(prompt, argument string) bool {
+	for _, p := range serverPrompts {
+		if p.prompt.Name == prompt {
+			return slices.ContainsFunc(p.prompt.Arguments, func(a *mcp.PromptArgument) bool { return a.Name == argument })
+		}
+	}
+	return false
+}
+
+// promptArgs returns a prompt's arguments, requiring the named ones.
+func promptArgs(req *mcp.GetPromptRequest, required ...string) (map[string]string, error) {
+	args := req.Params.Arguments
+	for _, name := range required {
+		if strings.TrimSpace(args[name]) == "" {
+			return nil, fmt.Errorf("missing required argument %q", name)
+		}
+	}
+	return args, nil
+}
+
+// promptTool returns the speech tool a prompt tells the model to call.
+func promptTool(provider string) (string, error) {
+	if provider != "" {
+		id, ok := providerConfigKeys[provider]
+		if !ok {
+			return "", fmt.Errorf("unknown provider %q (supported: say, elevenlabs, google, openai)", provider)
+		}
+		return id, nil
+	}
+	providers := availableProviders()
+	if len(providers) == 0 {
+		return "", fmt.Errorf("no TTS providers configured")
+	}
+	return providers[0].ID, nil
+}
+
+// speechInstructions tells the model how to turn content into speech and
+// which tool to speak it with.
+func speechInstructions(task, tool, category string, words int) string {
+	priority := PriorityNormal
+	if settings, _ := activeConfig.categorySettings(category, activeConfig.Profile); settings.Priority != "" {
+		priority = settings.Priority
+	}
+	length := time.Duration(words) * time.Minute / defaultWordsPerMinute
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s Then speak it by calling the %s tool with category %q, which is spoken at %s priority. ", task, tool, category, priority)
+	b.WriteString("Leave voice unset so the server uses the project's voice.\n\n")
+	b.WriteString("Write for the ear:\n")
+	b.WriteString("- Plain conversational sentences, no markdown, bullet points or code blocks\n")
+	b.WriteString("- Leave out URLs and long hashes or IDs; name files without their directories\n")
+	b.WriteString("- Give counts instead of reading long lists\n")
+	if words > 0 {
+		fmt.Fprintf(&b, "- Aim for about %d words, roughly %s of speech\n", words, length.Round(time.Second))
+	}
+	if maxChars > 0 {
+		fmt.Fprintf(&b, "- Each call may speak at most %d characters\n", maxChars)
+	}
+	return b.String()
+}
+
+// speechPrompt builds a single user message from instructions and material.
+func speechPrompt(description, instructions string, sections ...string) *mcp.GetPromptResult {
+	text := instructions
+	for i := 0; i+1 < len(sections); i += 2 {
+		if strings.TrimSpace(sections[i+1]) != "" {
+			text += fmt.Sprintf("\n%s:\n\n%s\n", sections[i], strings.TrimSpace(sections[i+1]))
+		}
+	}
+	return &mcp.GetPromptResult{
+		Description: description,
+		Messages:    []*mcp.PromptMessage{{Role: "user", Content: &mcp.TextContent{Text: text}}},
+	}
+}
+
+func announceSummaryPrompt(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	args, err := promptArgs(req, "content")
+	if err != nil {
+		return nil, err
+	}
+	tool, err := promptTool(args["provider"])
+	if err != nil {
+		return nil, err
+	}
+	instructions := speechInstructions(
+		`Summarize the content below as a short spoken announcement that starts like "All done", says what was accomplished and mentions anything left to do.`,
+		tool, CategorySummary, 75,
+	)
+	return speechPrompt("Announce a summary", instructions, "Content", args["content"]), nil
+}
+
+func announceErrorPrompt(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	args, err := promptArgs(req, "error")
+	if err != nil {
+		return nil, err
+	}
+	tool, err := promptTool(args["provider"])
+	if err != nil {
+		return nil, err
+	}
+	instructions := speechInstructions(
+		"Explain the error below in a calm spoken announcement: what failed, the most likely cause and what happens next. Do not read stack traces or error codes aloud.",
+		tool, CategoryError, 40,
+	)
+	return speechPrompt("Announce an error", instructions, "Context", args["context"], "Error", args["error"]), nil
+}
+
+func readAloudFilePrompt(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	args, err := promptArgs(req, "path")
+	if err != nil {
+		return nil, err
+	}
+	tool, err := promptTool(args["provider"])
+	if err != nil {
+		return nil, err
+	}
+	path, err := promptFilePath(ctx, req.Session, args["path"])
+	if err != nil {
+		return nil, err
+	}
+	data, err := readPromptFile(path)
+	if err != nil {
+		return nil, err
+	}
+	task := fmt.Sprintf("Read the file %s aloud. Keep its wording, but adapt anything that does not work when heard: describe tables and code briefly instead of reading them symbol by symbol.", filepath.Base(path))
+	if maxChars > 0 && utf8.RuneCount(data) > maxChars {
+		task += fmt.Sprintf(" It is too long for one call, so split it at paragraph or sentence boundaries into parts under %d characters and speak them in order, one call per part.", maxChars)
+	}
+	instructions := speechInstructions(task, tool, CategoryInfo, 0)
+	return speechPrompt("Read "+filepath.Base(path)+" aloud", instructions, "File contents", string(data)), nil
+}
+
+// promptFilePath resolves a read_aloud_file path against the project and
+// refuses files outside the project and the client's roots, following
+// symlinks so they cannot point elsewhere.
+func promptFilePath(ctx context.Context, session *mcp.ServerSession, path string) (string, error) {
+	project := sessionProject(ctx, session)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(project, path)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	for _, dir := range append([]string{project}, sessionRoots(ctx, session)...) {
+		if dir == "" {
+			continue
+		}
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			dir = real
+		}
+		if rel, err := filepath.Rel(dir, resolved); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("%s is outside the project and the client's roots", path)
+}
+
+// readPromptFile reads a text file for read_aloud_file.
+func readPromptFile(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+	if info.Size() > maxPromptFileBytes {
+		return nil, fmt.Errorf("%s is %d bytes, over the %d byte limit for reading aloud", path, info.Size(), maxPromptFileBytes)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("%s is not a text file", path)
+	}
+	return data, nil
+}
+
+func standupBriefingPrompt(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	args, err := promptArgs(req, "yesterday", "today")
+	if err != nil {
+		return nil, err
+	}
+	tool, err := promptTool(args["provider"])
+	if err != nil {
+		return nil, err
+	}
+	// Blockers need attention, so they are announced as a warning
+	category := CategoryInfo
+	if strings.TrimSpace(args["blockers"]) != "" {
+		category = CategoryWarning
+	}
+	instructions := speechInstructions(
+		"Turn the standup notes below into a spoken briefing in three short parts: what got done, what is planned today, and any blockers. Group related items and skip ticket numbers.",
+		tool, category, 120,
+	)
+	return speechPrompt("Daily standup briefing", instructions,
+		"Yesterday", args["yesterday"], "Today", args["today"], "Blockers", args["blockers"]), nil
+}
+
+// promptToolArguments are the speech tool arguments each provider accepts
+// besides text, for prompts that pass them through.
+var promptToolArguments = map[string][]string{
+	ProviderSay:        {"voice", "profile", "category"},
+	ProviderElevenLabs: {"profile", "category"},
+	ProviderGoogle:     {"voice", "model", "profile", "category"},
+	ProviderOpenAI:     {"voice", "model", "profile", "category"},
+}
+
+func speakTextPrompt(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	args, err := promptArgs(req, "text")
+	if err != nil {
+		return nil, err
+	}
+	tool, err := promptTool(args["provider"])
+	if err != nil {
+		return nil, err
+	}
+	category := CategoryInfo
+	if args["category"] != "" {
+		category = args["category"]
+	}
+	if _, err := categoryFor(&category, activeConfig.Profile); err != nil {
+		return nil, err
+	}
+	profile := args["profile"]
+	if _, err := callProfile(&profile); err != nil {
+		return nil, err
+	}
+	toolArgs := map[string]string{"category": category}
+	for _, name := range []string{"voice", "model", "profile"} {
+		if args[name] == "" {
+			continue
+		}
+		if !slices.Contains(promptToolArguments[tool], name) {
+			return nil, fmt.Errorf("the %s tool takes no %s argument", tool, name)
+		}
+		toolArgs[name] = args[name]
+	}
+	encoded, err := json.Marshal(toolArgs)
+	if err != nil {
+		return nil, err
+	}
+	instructions := fmt.Sprintf("Speak the text below word for word by calling the %s tool with it as text and these arguments: %s. Do not rephrase, summarize or add to it.\n", tool, encoded)
+	if maxChars > 0 && utf8.RuneCountInString(args["text"]) > maxChars {
+		instructions += fmt.Sprintf("It is too long for one call, so split it at paragraph or sentence boundaries into parts under %d characters and speak them in order, one call per part.\n", maxChars)
+	}
+	return speechPrompt("Speak text", instructions, "Text", args["text"]), nil
+}
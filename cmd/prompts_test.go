@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// promptSession connects a client to a server serving the prompts, with
+// only OpenAI configured.
+func promptSession(t *testing.T, roots ...string) *mcp.ClientSession {
+	t.Helper()
+	useTestLimits(t, "")
+	useTestSafeguards(t, 1000, 0, nil, nil)
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	for _, env := range []string{"ELEVENLABS_API_KEY", "GOOGLE_AI_API_KEY", "GEMINI_API_KEY"} {
+		t.Setenv(env, "")
+	}
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "mcp-tts"}, nil)
+	addPrompts(server)
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	_, err := server.Connect(context.Background(), serverTransport, nil)
+	require.NoError(t, err)
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client"}, nil)
+	for _, root := range roots {
+		client.AddRoots(&mcp.Root{URI: "file://" + filepath.ToSlash(root)})
+	}
+	session, err := client.Connect(context.Background(), clientTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { session.Close() })
+	return session
+}
+
+func getPrompt(t *testing.T, session *mcp.ClientSession, name string, args map[string]string) (string, error) {
+	t.Helper()
+	result, err := session.GetPrompt(context.Background(), &mcp.GetPromptParams{Name: name, Arguments: args})
+	if err != nil {
+		return "", err
+	}
+	require.Len(t, result.Messages, 1)
+	assert.Equal(t, mcp.Role("user"), result.Messages[0].Role)
+	return result.Messages[0].Content.(*mcp.TextContent).Text, nil
+}
+
+func TestListPrompts(t *testing.T) {
+	session := promptSession(t)
+	result, err := session.ListPrompts(context.Background(), nil)
+	require.NoError(t, err)
+	var names []string
+	for _, p := range result.Prompts {
+		names = append(names, p.Name)
+	}
+	assert.ElementsMatch(t, []string{"announce_summary", "announce_error", "read_aloud_file", "daily_standup_briefing", "speak_text"}, names)
+}
+
+func TestAnnouncePrompts(t *testing.T) {
+	session := promptSession(t)
+
+	text, err := getPrompt(t, session, "announce_summary", map[string]string{"content": "Merged PR #42 adding login"})
+	require.NoError(t, err)
+	assert.Contains(t, text, `calling the openai_tts tool with category "summary", which is spoken at normal priority`)
+	assert.Contains(t, text, "Aim for about 75 words, roughly 28s of speech")
+	assert.Contains(t, text, "Each call may speak at most 1000 characters")
+	assert.Contains(t, text, "Content:\n\nMerged PR #42 adding login\n")
+
+	text, err = getPrompt(t, session, "announce_error", map[string]string{"error": "panic: nil map", "context": "running tests", "provider": "say"})
+	require.NoError(t, err)
+	assert.Contains(t, text, `calling the say_tts tool with category "error", which is spoken at high priority`)
+	assert.Less(t, strings.Index(text, "Context:"), strings.Index(text, "Error:"))
+
+	_, err = getPrompt(t, session, "announce_error", map[string]string{"error": "boom", "provider": "azure"})
+	assert.ErrorContains(t, err, `unknown provider "azure"`)
+	_, err = getPrompt(t, session, "announce_summary", nil)
+	assert.ErrorContains(t, err, `missing required argument "content"`)
+}
+
+func TestSpeakTextPrompt(t *testing.T) {
+	session := promptSession(t)
+
+	text, err := getPrompt(t, session, "speak_text", map[string]string{"text": "Build is green", "voice": "onyx", "model": "tts-1"})
+	require.NoError(t, err)
+	assert.Contains(t, text, `calling the openai_tts tool with it as text and these arguments: {"category":"info","model":"tts-1","voice":"onyx"}`)
+	assert.Contains(t, text, "Text:\n\nBuild is green\n")
+
+	text, err = getPrompt(t, session, "speak_text", map[string]string{"text": "Deploy failed", "provider": "say", "category": "error"})
+	require.NoError(t, err)
+	assert.Contains(t, text, `calling the say_tts tool with it as text and these arguments: {"category":"error"}`)
+
+	_, err = getPrompt(t, session, "speak_text", map[string]string{"text": "hi", "provider": "say", "model": "tts-1"})
+	assert.ErrorContains(t, err, "the say_tts tool takes no model argument")
+	_, err = getPrompt(t, session, "speak_text", map[string]string{"text": "hi", "category": "shout"})
+	assert.ErrorContains(t, err, `unknown category "shout"`)
+	_, err = getPrompt(t, session, "speak_text", map[string]string{"text": "hi", "profile": "loud"})
+	assert.ErrorContains(t, err, `unknown profile "loud"`)
+}
+
+func TestStandupBriefingPrompt(t *testing.T) {
+	session := promptSession(t)
+
+	text, err := getPrompt(t, session, "daily_standup_briefing", map[string]string{"yesterday": "Fixed the cache", "today": "Release 1.2"})
+	require.NoError(t, err)
+	assert.Contains(t, text, `category "info"`)
+	assert.NotContains(t, text, "Blockers:")
+
+	text, err = getPrompt(t, session, "daily_standup_briefing", map[string]string{"yesterday": "Fixed the cache", "today": "Release 1.2", "blockers": "Waiting on review"})
+	require.NoError(t, err)
+	assert.Contains(t, text, `category "warning", which is spoken at high priority`, "blockers are announced as a warning")
+	assert.Contains(t, text, "Blockers:\n\nWaiting on review\n")
+}
+
+func TestReadAloudFilePrompt(t *testing.T) {
+	dir, other := t.TempDir(), t.TempDir()
+	session := promptSession(t, dir)
+	notes := writeTestFile(t, dir, "notes.md", "# Release notes\n\nEverything is faster.\n")
+
+	text, err := getPrompt(t, session, "read_aloud_file", map[string]string{"path": notes})
+	require.NoError(t, err)
+	assert.Contains(t, text, "Read the file notes.md aloud.")
+	assert.Contains(t, text, "File contents:\n\n# Release notes\n\nEverything is faster.\n")
+	assert.NotContains(t, text, "too long for one call")
+
+	long := writeTestFile(t, dir, "long.txt", strings.Repeat("A sentence. ", 100))
+	text, err = getPrompt(t, session, "read_aloud_file", map[string]string{"path": long})
+	require.NoError(t, err)
+	assert.Contains(t, text, "split it at paragraph or sentence boundaries into parts under 1000 characters")
+
+	_, err = getPrompt(t, session, "read_aloud_file", map[string]string{"path": dir})
+	assert.ErrorContains(t, err, "is a directory")
+	_, err = getPrompt(t, session, "read_aloud_file", map[string]string{"path": writeTestFile(t, dir, "audio.bin", "\xff\xfe\x00")})
+	assert.ErrorContains(t, err, "is not a text file")
+	_, err = getPrompt(t, session, "read_aloud_file", map[string]string{"path": filepath.Join(dir, "missing.txt")})
+	assert.Error(t, err)
+
+	text, err = getPrompt(t, session, "read_aloud_file", map[string]string{"path": "notes.md"})
+	require.NoError(t, err, "relative paths are resolved against the first root")
+	assert.Contains(t, text, "Everything is faster.")
+
+	secret := writeTestFile(t, other, "secret.txt", "hunter2")
+	_, err = getPrompt(t, session, "read_aloud_file", map[string]string{"path": secret})
+	assert.ErrorContains(t, err, "is outside the project and the client's roots")
+	_, err = getPrompt(t, session, "read_aloud_file", map[string]string{"path": filepath.Join("..", filepath.Base(other), "secret.txt")})
+	assert.ErrorContains(t, err, "is outside the project and the client's roots")
+	require.NoError(t, os.Symlink(secret, filepath.Join(dir, "link.txt")))
+	_, err = getPrompt(t, session, "read_aloud_file", map[string]string{"path": "link.txt"})
+	assert.ErrorContains(t, err, "is outside the project and the client's roots", "symlinks are followed")
+}
@@ -22,8 +22,14 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 // Provider IDs used in tool registration and elicitation routing.
@@ -36,17 +42,19 @@ const (
 
 // Default values for provider-specific settings.
 const (
-	DefaultSayRate     = 200
-	DefaultGoogleVoice = "Kore"
-	DefaultGoogleModel = "gemini-3.1-flash-tts-preview"
-	DefaultOpenAIVoice = "alloy"
-	DefaultOpenAIModel = "gpt-4o-mini-tts-2025-12-15"
-	DefaultOpenAISpeed = 1.0
+	DefaultSayRate           = 200
+	DefaultElevenLabsVoiceID = "1SM7GgM6IMuvQlz2BwM3"
+	DefaultElevenLabsModel   = "eleven_v3"
+	DefaultGoogleVoice       = "Kore"
+	DefaultGoogleModel       = "gemini-3.1-flash-tts-preview"
+	DefaultOpenAIVoice       = "alloy"
+	DefaultOpenAIModel       = "gpt-4o-mini-tts-2025-12-15"
+	DefaultOpenAISpeed       = 1.0
 )
 
-// Voice and model lists shared by tool schemas (schemas.go) and
-// elicitation forms (elicitation.go). Update these when providers
-// add or remove options.
+// Voice and model lists shared by tool schemas (schemas.go),
+// elicitation forms (elicitation.go) and completions (completion.go).
+// Update these when providers add or remove options.
 var (
 	GoogleVoices = []string{
 		"Achernar", "Achird", "Algenib", "Algieba", "Alnilam",
@@ -69,8 +77,136 @@ var (
 	OpenAIModels = []string{
 		"gpt-4o-mini-tts-2025-12-15", "tts-1", "tts-1-hd",
 	}
+	ElevenLabsModels = []string{
+		"eleven_v3", "eleven_multilingual_v2", "eleven_flash_v2_5",
+		"eleven_turbo_v2_5", "eleven_flash_v2", "eleven_turbo_v2",
+	}
+)
+
+// Default API endpoints. ELEVENLABS_BASE_URL, GOOGLE_GEMINI_BASE_URL and
+// OPENAI_BASE_URL point the providers (and `mcp-tts doctor`) elsewhere, e.g.
+// at a proxy or a local stub. The Google and OpenAI SDKs read their variables
+// themselves.
+const (
+	DefaultElevenLabsBaseURL = "https://api.elevenlabs.io"
+	DefaultGoogleBaseURL     = "https://generativelanguage.googleapis.com"
+	DefaultOpenAIBaseURL     = "https://api.openai.com/v1"
 )
 
+// providerBaseURL returns the API endpoint for a cloud provider without a
+// trailing slash.
+func providerBaseURL(providerID string) string {
+	env, def := "", ""
+	switch providerID {
+	case ProviderElevenLabs:
+		env, def = "ELEVENLABS_BASE_URL", DefaultElevenLabsBaseURL
+	case ProviderGoogle:
+		env, def = "GOOGLE_GEMINI_BASE_URL", DefaultGoogleBaseURL
+	case ProviderOpenAI:
+		env, def = "OPENAI_BASE_URL", DefaultOpenAIBaseURL
+	}
+	if v := os.Getenv(env); v != "" {
+		def = v
+	}
+	return strings.TrimRight(def, "/")
+}
+
+// speechHandler speaks text with one provider. Handlers registered with
+// addSpeechTool can be called by other providers, e.g. to fall through when
+// a budget runs out.
+type speechHandler func(ctx context.Context, req *mcp.CallToolRequest, input TTSParams) (*mcp.CallToolResult, error)
+
+// speechHandlers maps provider IDs to the handlers of registered tools.
+var speechHandlers = map[string]speechHandler{}
+
+// addSpeechTool registers a provider tool and its handler for calls from
+// other providers. params converts shared arguments to the tool's own.
+func addSpeechTool[In interface{ ttsParams() TTSParams }](s *mcp.Server, tool *mcp.Tool, params func(TTSParams) In, handler mcp.ToolHandlerFor[In, any]) {
+	handler = reportIgnoredSettings(instrumentSpeech(tool.Name, handler))
+	mcp.AddTool(s, tool, handler)
+	speechHandlers[tool.Name] = func(ctx context.Context, req *mcp.CallToolRequest, input TTSParams) (*mcp.CallToolResult, error) {
+		result, _, err := handler(ctx, req, params(input))
+		return result, err
+	}
+}
+
+// reportIgnoredSettings notes in a call's result which settings of the
+// profile it requested were not applied, because they only take effect for
+// the profile selected at startup.
+func reportIgnoredSettings[In interface{ ttsParams() TTSParams }](handler mcp.ToolHandlerFor[In, any]) mcp.ToolHandlerFor[In, any] {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input In) (*mcp.CallToolResult, any, error) {
+		result, out, err := handler(ctx, req, input)
+		// A fallback reports to the provider that handed the call over
+		if err != nil || result == nil || result.IsError || fallingThrough(ctx) {
+			return result, out, err
+		}
+		profile, perr := callProfile(input.ttsParams().Profile)
+		if perr != nil {
+			return result, out, err
+		}
+		if ignored := ignoredProfileSettings(profile); len(ignored) > 0 && len(result.Content) > 0 {
+			if text, ok := result.Content[0].(*mcp.TextContent); ok {
+				text.Text += fmt.Sprintf("\nProfile %q settings not applied to this call (select the profile at startup to use them): %s",
+					profile, strings.Join(ignored, ", "))
+			}
+		}
+		return result, out, err
+	}
+}
+
+// ttsParams returns the arguments every provider tool shares.
+func (p SayTTSParams) ttsParams() TTSParams {
+	return TTSParams{Text: p.Text, Profile: p.Profile, Category: p.Category, Format: p.Format, Captions: p.Captions, Markup: p.Markup}
+}
+
+func (p ElevenLabsTTSParams) ttsParams() TTSParams {
+	return TTSParams{Text: p.Text, Profile: p.Profile, Category: p.Category, Format: p.Format, Captions: p.Captions, Markup: p.Markup}
+}
+
+func (p GoogleTTSParams) ttsParams() TTSParams {
+	return TTSParams{Text: p.Text, Profile: p.Profile, Category: p.Category, Format: p.Format, Captions: p.Captions, Markup: p.Markup}
+}
+
+func (p OpenAITTSParams) ttsParams() TTSParams {
+	return TTSParams{Text: p.Text, Profile: p.Profile, Category: p.Category, Format: p.Format, Captions: p.Captions, Markup: p.Markup}
+}
+
+// fallbackFormat keeps a requested format the provider can produce and
+// leaves the rest to the provider, so a call handed to another provider is
+// not refused over its format.
+func fallbackFormat(providerID string, format *string) *string {
+	if format == nil || checkSaveFormat(providerID, strings.ToLower(*format)) != nil {
+		return nil
+	}
+	return format
+}
+
+func sayParams(in TTSParams) SayTTSParams {
+	params := SayTTSParams{Text: in.Text, Profile: in.Profile, Category: in.Category,
+		Format: fallbackFormat(ProviderSay, in.Format), Captions: in.Captions, Markup: in.Markup}
+	applySaySettings(&params, in.Settings)
+	return params
+}
+
+func elevenLabsParams(in TTSParams) ElevenLabsTTSParams {
+	return ElevenLabsTTSParams{Text: in.Text, Profile: in.Profile, Category: in.Category,
+		Format: fallbackFormat(ProviderElevenLabs, in.Format), Captions: in.Captions, Markup: in.Markup}
+}
+
+func googleParams(in TTSParams) GoogleTTSParams {
+	params := GoogleTTSParams{Text: in.Text, Profile: in.Profile, Category: in.Category,
+		Format: fallbackFormat(ProviderGoogle, in.Format), Captions: in.Captions, Markup: in.Markup}
+	applyGoogleSettings(&params, in.Settings)
+	return params
+}
+
+func openAIParams(in TTSParams) OpenAITTSParams {
+	params := OpenAITTSParams{Text: in.Text, Profile: in.Profile, Category: in.Category,
+		Format: fallbackFormat(ProviderOpenAI, in.Format), Captions: in.Captions, Markup: in.Markup}
+	applyOpenAISettings(&params, in.Settings)
+	return params
+}
+
 type providerOption struct {
 	ID   string
 	Name string
@@ -90,5 +226,20 @@ func availableProviders() []providerOption {
 	if os.Getenv("OPENAI_API_KEY") != "" {
 		providers = append(providers, providerOption{ProviderOpenAI, "OpenAI"})
 	}
+	return orderProviders(providers, activeConfig.ProviderOrder())
+}
+
+// orderProviders sorts providers by the configured order. Providers missing
+// from the order keep their default position after the listed ones.
+func orderProviders(providers []providerOption, order []string) []providerOption {
+	rank := func(p providerOption) int {
+		if i := slices.Index(order, p.ID); i >= 0 {
+			return i
+		}
+		return len(order)
+	}
+	slices.SortStableFunc(providers, func(a, b providerOption) int {
+		return rank(a) - rank(b)
+	})
 	return providers
 }
@@ -0,0 +1,185 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Redaction modes for --redact.
+const (
+	RedactModeRedact  = "redact"  // replace each secret with a spoken label such as "email address"
+	RedactModeReplace = "replace" // replace each secret with the word "redacted"
+	RedactModeRefuse  = "refuse"  // reject the request
+	RedactModeOff     = "off"
+)
+
+// RedactModes lists the accepted --redact values.
+var RedactModes = []string{RedactModeRedact, RedactModeReplace, RedactModeRefuse, RedactModeOff}
+
+// redactionDetector finds one kind of sensitive value.
+type redactionDetector struct {
+	Name    string
+	Label   string // spoken in place of the match in redact mode
+	Pattern *regexp.Regexp
+	Valid   func(match string) bool // optional check to weed out false positives
+}
+
+func isIPAddress(s string) bool {
+	_, err := netip.ParseAddr(s)
+	return err == nil
+}
+
+// isIPv6Address rejects clock times (10:30:00) and C++ scopes (std::vector)
+// by requiring at least three hex groups.
+func isIPv6Address(s string) bool {
+	groups := 0
+	for _, g := range strings.Split(s, ":") {
+		if g != "" {
+			groups++
+		}
+	}
+	return groups >= 3 && isIPAddress(s)
+}
+
+// builtinDetectors run before user patterns. Order matters: earlier
+// detectors claim their matches first.
+var builtinDetectors = []redactionDetector{
+	{
+		Name:    "jwt",
+		Label:   "token",
+		Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`),
+	},
+	{
+		Name:  "api_key",
+		Label: "API key",
+		// OpenAI/Anthropic/ElevenLabs style sk- keys, Google AIza keys, GitHub and Slack tokens
+		Pattern: regexp.MustCompile(`\b(?:(?:sk|pk|rk)[-_][A-Za-z0-9_-]{16,}|AIza[0-9A-Za-z_-]{35}|gh[pousr]_[A-Za-z0-9]{36,}|xox[abprs]-[A-Za-z0-9-]{10,})`),
+	},
+	{
+		Name:    "aws_key",
+		Label:   "AWS key",
+		Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b|(?i:aws_secret_access_key)\s*[=:]\s*[A-Za-z0-9/+=]{40}`),
+	},
+	{
+		Name:    "email",
+		Label:   "email address",
+		Pattern: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`),
+	},
+	{
+		Name:    "ipv4",
+		Label:   "IP address",
+		Pattern: regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`),
+		Valid:   isIPAddress,
+	},
+	{
+		Name:    "ipv6",
+		Label:   "IP address",
+		Pattern: regexp.MustCompile(`(?i)(?:[0-9a-f]{0,4}:){2,7}[0-9a-f]{0,4}`),
+		Valid:   isIPv6Address,
+	},
+}
+
+// redactor removes secrets and personal data from text before it is sent
+// to a provider, saved or logged.
+type redactor struct {
+	mode      string
+	detectors []redactionDetector
+}
+
+var (
+	// Redaction settings
+	redactMode     string = RedactModeRedact
+	redactPatterns []string
+	// Redactor built from the settings at startup
+	activeRedactor = &redactor{mode: RedactModeRedact, detectors: builtinDetectors}
+)
+
+// newRedactor builds a redactor from a mode and user regular expressions.
+func newRedactor(mode string, patterns []string) (*redactor, error) {
+	if !slices.Contains(RedactModes, mode) {
+		return nil, fmt.Errorf("unsupported redaction mode %q (supported: %s)", mode, strings.Join(RedactModes, ", "))
+	}
+	r := &redactor{mode: mode, detectors: slices.Clone(builtinDetectors)}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", p, err)
+		}
+		r.detectors = append(r.detectors, redactionDetector{Name: "custom", Label: "redacted", Pattern: re})
+	}
+	return r, nil
+}
+
+// redact returns text with sensitive values replaced and a count of matches
+// per detector. Refuse mode replaces matches with "redacted" like replace
+// mode; callers check the counts to refuse the request.
+func (r *redactor) redact(text string) (string, map[string]int) {
+	if r == nil || r.mode == RedactModeOff {
+		return text, nil
+	}
+	counts := map[string]int{}
+	for _, d := range r.detectors {
+		text = d.Pattern.ReplaceAllStringFunc(text, func(match string) string {
+			if d.Valid != nil && !d.Valid(match) {
+				return match
+			}
+			counts[d.Name]++
+			if r.mode == RedactModeRedact {
+				return d.Label
+			}
+			return "redacted"
+		})
+	}
+	if len(counts) == 0 {
+		return text, nil
+	}
+	return text, counts
+}
+
+// redactText applies the active redactor to text bound for a provider.
+// It logs which detectors matched, never the matched values, and returns a
+// non-nil result when the request must be refused.
+func redactText(field, text string) (string, *mcp.CallToolResult) {
+	redacted, counts := activeRedactor.redact(text)
+	if len(counts) == 0 {
+		return text, nil
+	}
+	kinds := make([]string, 0, len(counts))
+	for kind := range counts {
+		kinds = append(kinds, kind)
+	}
+	slices.Sort(kinds)
+	log.Debug("Redaction report", "field", field, "mode", activeRedactor.mode, "matches", counts)
+
+	if activeRedactor.mode == RedactModeRefuse {
+		return "", errorResult(fmt.Sprintf("Error: %s contains sensitive data (%s); refusing to speak it", field, strings.Join(kinds, ", ")))
+	}
+	return redacted, nil
+}
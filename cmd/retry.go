@@ -0,0 +1,274 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/openai/openai-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genai"
+)
+
+// Default retry policy for cloud synthesis requests.
+const (
+	DefaultRetryMaxAttempts = 3
+	DefaultRetryBaseDelay   = 500 * time.Millisecond
+	DefaultRetryJitter      = 0.2
+	DefaultRetryMaxElapsed  = 30 * time.Second
+)
+
+// defaultRetryPolicy applies to every cloud provider unless the config overrides it.
+var defaultRetryPolicy = retryPolicy{
+	MaxAttempts: DefaultRetryMaxAttempts,
+	BaseDelay:   DefaultRetryBaseDelay,
+	Jitter:      DefaultRetryJitter,
+	MaxElapsed:  DefaultRetryMaxElapsed,
+}
+
+// retryPolicy controls how often a rate-limited or unavailable synthesis
+// request is repeated. It only covers the request itself: once audio reaches
+// the speaker a failure is final, so nothing is spoken twice.
+type retryPolicy struct {
+	MaxAttempts int           `yaml:"max_attempts,omitempty"` // 1 disables retries, 0 keeps the inherited value
+	BaseDelay   time.Duration `yaml:"base_delay,omitempty"`   // doubled after each attempt
+	Jitter      float64       `yaml:"jitter,omitempty"`       // +/- fraction of each delay
+	MaxElapsed  time.Duration `yaml:"max_elapsed,omitempty"`  // give up rather than wait past this
+}
+
+// retryFields are the config keys of a retry policy, in display order.
+var retryFields = []string{"retry.max_attempts", "retry.base_delay", "retry.jitter", "retry.max_elapsed"}
+
+// merge overlays the non-zero fields of src, recording source for each one.
+func (p *retryPolicy) merge(src retryPolicy, set func(field string)) {
+	if src.MaxAttempts != 0 {
+		p.MaxAttempts = src.MaxAttempts
+		set("retry.max_attempts")
+	}
+	if src.BaseDelay != 0 {
+		p.BaseDelay = src.BaseDelay
+		set("retry.base_delay")
+	}
+	if src.Jitter != 0 {
+		p.Jitter = src.Jitter
+		set("retry.jitter")
+	}
+	if src.MaxElapsed != 0 {
+		p.MaxElapsed = src.MaxElapsed
+		set("retry.max_elapsed")
+	}
+}
+
+// field returns a setting formatted for display, or "" when unset.
+func (p retryPolicy) field(name string) string {
+	switch name {
+	case "retry.max_attempts":
+		if p.MaxAttempts != 0 {
+			return strconv.Itoa(p.MaxAttempts)
+		}
+	case "retry.base_delay":
+		if p.BaseDelay != 0 {
+			return p.BaseDelay.String()
+		}
+	case "retry.jitter":
+		if p.Jitter != 0 {
+			return strconv.FormatFloat(p.Jitter, 'f', -1, 64)
+		}
+	case "retry.max_elapsed":
+		if p.MaxElapsed != 0 {
+			return p.MaxElapsed.String()
+		}
+	}
+	return ""
+}
+
+func (p retryPolicy) validate() error {
+	switch {
+	case p.MaxAttempts < 0:
+		return fmt.Errorf("retry max_attempts must not be negative (1 turns retries off, 0 keeps the default)")
+	case p.BaseDelay < 0 || p.MaxElapsed < 0:
+		return fmt.Errorf("retry delays must not be negative")
+	case p.Jitter < 0 || p.Jitter > 1:
+		return fmt.Errorf("retry jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+// backoff returns the wait before the attempt after attempt n (1-based).
+func (p retryPolicy) backoff(n int) time.Duration {
+	delay := p.BaseDelay << (n - 1)
+	if delay <= 0 {
+		// Shift overflow
+		delay = p.MaxElapsed
+	}
+	if p.Jitter > 0 {
+		delay = time.Duration(float64(delay) * (1 + p.Jitter*(2*rand.Float64()-1)))
+	}
+	return delay
+}
+
+// do runs request until it succeeds, fails with an error that is not a
+// retryableError, or the policy runs out of attempts or time. A server's
+// Retry-After is honored when it asks for a longer wait than the backoff.
+func (p retryPolicy) do(ctx context.Context, providerID string, request func() error) error {
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := request()
+		var retryable *retryableError
+		if err == nil || !errors.As(err, &retryable) {
+			return err
+		}
+		if attempt >= p.MaxAttempts {
+			if attempt > 1 {
+				return fmt.Errorf("%w (gave up after %d attempts)", err, attempt)
+			}
+			return err
+		}
+
+		delay := max(p.backoff(attempt), retryable.retryAfter)
+		if p.MaxElapsed > 0 && time.Since(start)+delay > p.MaxElapsed {
+			return fmt.Errorf("%w (gave up after %d attempts, next retry in %s exceeds %s)", err, attempt, delay.Round(time.Millisecond), p.MaxElapsed)
+		}
+
+		log.Warn("Synthesis request failed, retrying", "provider", providerID, "attempt", attempt, "delay", delay.Round(time.Millisecond), "error", err)
+		trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+			attribute.Int("attempt", attempt),
+			attribute.String("delay", delay.Round(time.Millisecond).String()),
+			attribute.String("error", err.Error()),
+		))
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryableError marks a failed request that may succeed when repeated.
+type retryableError struct {
+	err        error
+	retryAfter time.Duration // wait requested by the server, 0 if none
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// retryableStatus reports whether an HTTP status is worth retrying:
+// rate limits, timeouts and temporary server errors.
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// classifyHTTPError wraps err as retryable when the response status allows it.
+func classifyHTTPError(err error, code int, header http.Header) error {
+	if !retryableStatus(code) {
+		return err
+	}
+	return &retryableError{err: err, retryAfter: parseRetryAfter(header.Get("Retry-After"))}
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
+
+// classifyOpenAIError marks retryable OpenAI API errors.
+func classifyOpenAIError(err error) error {
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	var header http.Header
+	if apiErr.Response != nil {
+		header = apiErr.Response.Header
+	}
+	return classifyHTTPError(err, apiErr.StatusCode, header)
+}
+
+// classifyGoogleError marks retryable Gemini API errors. The SDK drops the
+// response headers, so the wait comes from the google.rpc.RetryInfo detail.
+func classifyGoogleError(err error) error {
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) || !retryableStatus(apiErr.Code) {
+		return err
+	}
+	retryable := &retryableError{err: err}
+	for _, detail := range apiErr.Details {
+		if detail["@type"] != "type.googleapis.com/google.rpc.RetryInfo" {
+			continue
+		}
+		if delay, ok := detail["retryDelay"].(string); ok {
+			if d, err := time.ParseDuration(delay); err == nil {
+				retryable.retryAfter = d
+			}
+		}
+	}
+	return retryable
+}
+
+// generateGoogleSpeech calls Gemini, retrying per policy.
+func generateGoogleSpeech(ctx context.Context, policy retryPolicy, client *genai.Client, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	var response *genai.GenerateContentResponse
+	err := policy.do(ctx, ProviderGoogle, func() error {
+		var err error
+		response, err = client.Models.GenerateContent(ctx, model, contents, config)
+		return classifyGoogleError(err)
+	})
+	return response, err
+}
+
+// newOpenAISpeech calls OpenAI, retrying per policy. The client must be
+// created with option.WithMaxRetries(0) so the SDK does not retry as well.
+func newOpenAISpeech(ctx context.Context, policy retryPolicy, client openai.Client, params openai.AudioSpeechNewParams) (*http.Response, error) {
+	var response *http.Response
+	err := policy.do(ctx, ProviderOpenAI, func() error {
+		var err error
+		response, err = client.Audio.Speech.New(ctx, params)
+		return classifyOpenAIError(err)
+	})
+	return response, err
+}
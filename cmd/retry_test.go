@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+// fastRetries keeps test backoff in the millisecond range.
+var fastRetries = retryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxElapsed: time.Second}
+
+// scriptedServer answers each request with the next status in script, then
+// 200 with body. It counts requests.
+func scriptedServer(t *testing.T, body string, header http.Header, script ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		if n <= len(script) {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(script[n-1])
+			w.Write([]byte(`{"error": {"code": 429, "message": "slow down"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestElevenLabsRequestRetries(t *testing.T) {
+	t.Run("429 then 503 then success", func(t *testing.T) {
+		srv, calls := scriptedServer(t, "audio", nil, http.StatusTooManyRequests, http.StatusServiceUnavailable)
+		res, err := elevenLabsRequest(context.Background(), fastRetries, srv.URL, "key", "audio/mpeg", []byte(`{}`))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		srv, calls := scriptedServer(t, "audio", nil, 503, 503, 503, 503)
+		_, err := elevenLabsRequest(context.Background(), fastRetries, srv.URL, "key", "audio/mpeg", []byte(`{}`))
+		assert.ErrorContains(t, err, "ElevenLabs API error (status 503)")
+		assert.ErrorContains(t, err, "gave up after 3 attempts")
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("client errors are not retried", func(t *testing.T) {
+		srv, calls := scriptedServer(t, "audio", nil, http.StatusUnauthorized)
+		_, err := elevenLabsRequest(context.Background(), fastRetries, srv.URL, "key", "audio/mpeg", []byte(`{}`))
+		assert.ErrorContains(t, err, "status 401")
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("Retry-After beyond max elapsed gives up early", func(t *testing.T) {
+		srv, calls := scriptedServer(t, "audio", http.Header{"Retry-After": {"120"}}, 429)
+		_, err := elevenLabsRequest(context.Background(), fastRetries, srv.URL, "key", "audio/mpeg", []byte(`{}`))
+		assert.ErrorContains(t, err, "next retry in 2m0s exceeds 1s")
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("Retry-After is honored", func(t *testing.T) {
+		srv, _ := scriptedServer(t, "audio", http.Header{"Retry-After": {"1"}}, 429)
+		policy := fastRetries
+		policy.MaxElapsed = 5 * time.Second
+		start := time.Now()
+		res, err := elevenLabsRequest(context.Background(), policy, srv.URL, "key", "audio/mpeg", []byte(`{}`))
+		require.NoError(t, err)
+		res.Body.Close()
+		assert.GreaterOrEqual(t, time.Since(start), time.Second)
+	})
+
+	t.Run("cancellation stops waiting", func(t *testing.T) {
+		srv, _ := scriptedServer(t, "audio", http.Header{"Retry-After": {"30"}}, 429)
+		policy := fastRetries
+		policy.MaxElapsed = time.Minute
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := elevenLabsRequest(ctx, policy, srv.URL, "key", "audio/mpeg", []byte(`{}`))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestOpenAISpeechRetries(t *testing.T) {
+	srv, calls := scriptedServer(t, "audio", nil, http.StatusTooManyRequests, http.StatusBadGateway)
+	client := openai.NewClient(option.WithAPIKey("key"), option.WithBaseURL(srv.URL), option.WithMaxRetries(0))
+	res, err := newOpenAISpeech(context.Background(), fastRetries, client, openai.AudioSpeechNewParams{
+		Model: openai.SpeechModel(DefaultOpenAIModel),
+		Input: "hi",
+		Voice: openai.AudioSpeechNewParamsVoice(DefaultOpenAIVoice),
+	})
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestGoogleSpeechRetries(t *testing.T) {
+	srv, calls := scriptedServer(t, `{"candidates": [{"content": {"parts": [{"text": "ok"}]}}]}`, nil, http.StatusTooManyRequests)
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:      "key",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: srv.URL},
+	})
+	require.NoError(t, err)
+	res, err := generateGoogleSpeech(context.Background(), fastRetries, client, DefaultGoogleModel, genai.Text("hi"), nil)
+	require.NoError(t, err)
+	assert.Len(t, res.Candidates, 1)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestClassifyGoogleErrorRetryInfo(t *testing.T) {
+	err := classifyGoogleError(genai.APIError{Code: 429, Details: []map[string]any{
+		{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "7s"},
+	}})
+	var retryable *retryableError
+	require.ErrorAs(t, err, &retryable)
+	assert.Equal(t, 7*time.Second, retryable.retryAfter)
+
+	assert.NotErrorAs(t, classifyGoogleError(genai.APIError{Code: 400}), &retryable)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assert.Equal(t, 3*time.Second, parseRetryAfter("3"))
+	assert.Zero(t, parseRetryAfter(""))
+	assert.Zero(t, parseRetryAfter("soon"))
+	d := parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	assert.InDelta(t, time.Minute, d, float64(2*time.Second))
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := retryPolicy{BaseDelay: 100 * time.Millisecond, Jitter: 0.5}
+	for n, base := range []time.Duration{100, 200, 400} {
+		d := p.backoff(n + 1)
+		assert.GreaterOrEqual(t, d, base*time.Millisecond/2)
+		assert.LessOrEqual(t, d, base*time.Millisecond*3/2)
+	}
+}
@@ -33,7 +33,11 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/caarlos0/ctrlc"
@@ -46,6 +50,7 @@ import (
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
 	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/genai"
 )
@@ -57,8 +62,6 @@ var (
 	Version string
 	// Flag to suppress "Speaking:" output
 	suppressSpeakingOutput bool
-	// Global TTS mutex to prevent concurrent speech
-	ttsMutex sync.Mutex
 	// Flag to enable/disable sequential TTS (default: true)
 	sequentialTTS bool = true
 	// Speaker is initialized once; all streams resample to the initial rate
@@ -66,53 +69,65 @@ var (
 	speakerInitErr    error
 	speakerSampleRate beep.SampleRate
 	// Audio saving options
-	outputDir string // Directory to save audio files
-	noPlay    bool   // Skip playback when saving
+	outputDir  string // Directory to save audio files
+	noPlay     bool   // Skip playback when saving
+	saveFormat string // Format for saved audio ("" keeps the provider's native format)
+	// Write .srt/.vtt captions next to saved audio
+	writeCaptions bool
+	// Pronunciation lexicon file ("" uses ~/.config/mcp-tts/lexicon.yaml)
+	lexiconPath string
+	// Config profile selected at startup ("" uses the config file's `profile:`)
+	profileName string
+	// Silence trimming and spacing between queued utterances
+	trimSilenceEnabled bool          = true
+	silenceThreshold   float64       = DefaultSilenceThreshold
+	silenceMinDuration time.Duration = DefaultSilenceMinDuration
+	utteranceGap       time.Duration = DefaultUtteranceGap
+	lastUtteranceEnd   time.Time     // guarded by localSpeechQueue
 )
 
-// acquireTTSLock attempts to acquire the TTS mutex with context support.
+// acquireTTSLock waits for this call's turn to speak, then takes the global
+// cross-process lock. Higher priority calls waiting locally go first.
 // Returns a release function that should be deferred.
-func acquireTTSLock(ctx context.Context) (func(), error) {
+func acquireTTSLock(ctx context.Context, priority int) (release func(), err error) {
 	if !sequentialTTS {
 		return func() {}, nil
 	}
 
-	pid := os.Getpid()
-	log.Debug("Attempting to acquire local TTS mutex", "pid", pid)
-	acquired := make(chan struct{})
-
-	go func() {
-		ttsMutex.Lock()
-		log.Debug("Local TTS mutex acquired", "pid", pid)
-		close(acquired)
-	}()
+	ctx, span := startSpan(ctx, "lock", attribute.Int("tts.priority", priority))
+	defer func() { endSpan(span, err) }()
 
-	select {
-	case <-acquired:
-		log.Debug("Attempting to acquire global TTS lock", "pid", pid)
-		globalRelease, err := acquireGlobalTTSLock(ctx)
-		if err != nil {
-			log.Debug("Failed to acquire global lock, releasing local mutex", "pid", pid, "error", err)
-			ttsMutex.Unlock()
-			return nil, err
-		}
+	pid := os.Getpid()
+	start := time.Now()
+	log.Debug("Attempting to acquire local TTS queue", "pid", pid, "priority", priority)
+	if err := localSpeechQueue.acquire(ctx, priority); err != nil {
+		return nil, err
+	}
+	log.Debug("Local TTS queue acquired", "pid", pid)
+
+	log.Debug("Attempting to acquire global TTS lock", "pid", pid)
+	globalRelease, err := acquireGlobalTTSLock(ctx)
+	if err != nil {
+		log.Debug("Failed to acquire global lock, releasing local queue", "pid", pid, "error", err)
+		localSpeechQueue.release()
+		return nil, err
+	}
 
-		log.Debug("Both TTS locks acquired successfully", "pid", pid)
-		return func() {
-			log.Debug("Releasing both TTS locks", "pid", pid)
-			globalRelease()
-			ttsMutex.Unlock()
-			log.Debug("Both TTS locks released", "pid", pid)
-		}, nil
-
-	case <-ctx.Done():
-		select {
-		case <-acquired:
-			ttsMutex.Unlock()
-		default:
-		}
-		return nil, ctx.Err()
+	if err := waitForUtteranceGap(ctx, lastUtteranceEnd, utteranceGap); err != nil {
+		globalRelease()
+		localSpeechQueue.release()
+		return nil, err
 	}
+
+	log.Debug("Both TTS locks acquired successfully", "pid", pid)
+	ttsMetrics.lockWait.since(start)
+	return func() {
+		log.Debug("Releasing both TTS locks", "pid", pid)
+		lastUtteranceEnd = time.Now()
+		globalRelease()
+		localSpeechQueue.release()
+		log.Debug("Both TTS locks released", "pid", pid)
+	}, nil
 }
 
 // initSpeaker initializes the speaker once with the given sample rate.
@@ -236,19 +251,34 @@ func textResult(msg string) *mcp.CallToolResult {
 
 // Parameter types for tools with MCP schema descriptions for LLMs
 type SayTTSParams struct {
-	Text  string  `json:"text" mcp:"The text to speak aloud"`
-	Rate  *int    `json:"rate,omitempty" mcp:"Speech rate in words per minute (50-500, default: 200)"`
-	Voice *string `json:"voice,omitempty" mcp:"Voice to use for speech synthesis (e.g. 'Alex', 'Samantha', 'Victoria')"`
+	Text     string  `json:"text" mcp:"The text to speak aloud"`
+	Rate     *int    `json:"rate,omitempty" mcp:"Speech rate in words per minute (50-500, default: 200)"`
+	Voice    *string `json:"voice,omitempty" mcp:"Voice to use for speech synthesis (e.g. 'Alex', 'Samantha', 'Victoria')"`
+	Format   *string `json:"format,omitempty" mcp:"Saved audio format (aiff, wav; default: aiff)"`
+	Captions *bool   `json:"captions,omitempty" mcp:"Write SRT/WebVTT captions next to the saved audio"`
+	Markup   *string `json:"markup,omitempty" mcp:"Set to 'ssml' to interpret <break>, <emphasis>, <say-as> and <prosody rate> tags"`
+	Profile  *string `json:"profile,omitempty" mcp:"Named config profile supplying default voice and output settings"`
+	Category *string `json:"category,omitempty" mcp:"Kind of message (info, success, warning, error, summary, question); selects voice, style, chime and priority"`
 }
 
 type ElevenLabsTTSParams struct {
-	Text string `json:"text" mcp:"The text to convert to speech using ElevenLabs API"`
+	Text     string  `json:"text" mcp:"The text to convert to speech using ElevenLabs API"`
+	Format   *string `json:"format,omitempty" mcp:"Saved audio format (mp3, wav, pcm, opus; default: mp3)"`
+	Captions *bool   `json:"captions,omitempty" mcp:"Write SRT/WebVTT captions next to the saved audio"`
+	Markup   *string `json:"markup,omitempty" mcp:"Set to 'ssml' to interpret <break>, <emphasis>, <say-as> and <prosody rate> tags"`
+	Profile  *string `json:"profile,omitempty" mcp:"Named config profile supplying default voice and output settings"`
+	Category *string `json:"category,omitempty" mcp:"Kind of message (info, success, warning, error, summary, question); selects voice, style, chime and priority"`
 }
 
 type GoogleTTSParams struct {
-	Text  string  `json:"text" mcp:"The text to convert to speech using Google TTS"`
-	Voice *string `json:"voice,omitempty" mcp:"Voice name to use (e.g. 'Kore', 'Puck', 'Fenrir', etc. - see documentation for full list of 30 voices, default: 'Kore')"`
-	Model *string `json:"model,omitempty" mcp:"TTS model to use (gemini-3.1-flash-tts-preview, gemini-2.5-flash-preview-tts, gemini-2.5-pro-preview-tts, gemini-2.5-flash-lite-preview-tts; default: 'gemini-3.1-flash-tts-preview')"`
+	Text     string  `json:"text" mcp:"The text to convert to speech using Google TTS"`
+	Voice    *string `json:"voice,omitempty" mcp:"Voice name to use (e.g. 'Kore', 'Puck', 'Fenrir', etc. - see documentation for full list of 30 voices, default: 'Kore')"`
+	Model    *string `json:"model,omitempty" mcp:"TTS model to use (gemini-3.1-flash-tts-preview, gemini-2.5-flash-preview-tts, gemini-2.5-pro-preview-tts, gemini-2.5-flash-lite-preview-tts; default: 'gemini-3.1-flash-tts-preview')"`
+	Format   *string `json:"format,omitempty" mcp:"Saved audio format (wav, pcm; default: wav)"`
+	Captions *bool   `json:"captions,omitempty" mcp:"Write SRT/WebVTT captions next to the saved audio"`
+	Markup   *string `json:"markup,omitempty" mcp:"Set to 'ssml' to interpret <break>, <emphasis>, <say-as> and <prosody rate> tags"`
+	Profile  *string `json:"profile,omitempty" mcp:"Named config profile supplying default voice and output settings"`
+	Category *string `json:"category,omitempty" mcp:"Kind of message (info, success, warning, error, summary, question); selects voice, style, chime and priority"`
 }
 
 type OpenAITTSParams struct {
@@ -257,10 +287,27 @@ type OpenAITTSParams struct {
 	Model        *string  `json:"model,omitempty" mcp:"TTS model to use (gpt-4o-mini-tts-2025-12-15, gpt-4o-mini-tts, gpt-4o-audio-preview, tts-1, tts-1-hd; default: 'gpt-4o-mini-tts-2025-12-15')"`
 	Speed        *float64 `json:"speed,omitempty" mcp:"Speech speed (0.25-4.0, default: 1.0)"`
 	Instructions *string  `json:"instructions,omitempty" mcp:"Instructions for voice modulation and style"`
+	Format       *string  `json:"format,omitempty" mcp:"Saved audio format (mp3, wav, opus, flac, aac, pcm; default: mp3)"`
+	Captions     *bool    `json:"captions,omitempty" mcp:"Write SRT/WebVTT captions next to the saved audio"`
+	Markup       *string  `json:"markup,omitempty" mcp:"Set to 'ssml' to interpret <break>, <emphasis>, <say-as> and <prosody rate> tags"`
+	Profile      *string  `json:"profile,omitempty" mcp:"Named config profile supplying default voice and output settings"`
+	Category     *string  `json:"category,omitempty" mcp:"Kind of message (info, success, warning, error, summary, question); selects voice, style, chime and priority"`
+}
+
+type UsageParams struct {
+	Profile *string `json:"profile,omitempty" mcp:"Named config profile whose limits to report"`
 }
 
 type TTSParams struct {
-	Text string `json:"text" mcp:"The text to speak aloud"`
+	Text     string  `json:"text" mcp:"The text to speak aloud"`
+	Profile  *string `json:"profile,omitempty" mcp:"Named config profile supplying default voice and output settings"`
+	Category *string `json:"category,omitempty" mcp:"Kind of message (info, success, warning, error, summary, question); selects voice, style, chime and priority"`
+	// Settings are the provider settings chosen in the tts tool's form
+	Settings map[string]any `json:"-"`
+	// Output options shared by every provider tool
+	Format   *string `json:"-"`
+	Captions *bool   `json:"-"`
+	Markup   *string `json:"-"`
 }
 
 func init() {
@@ -283,6 +330,36 @@ func init() {
 	rootCmd.PersistentFlags().BoolVar(&sequentialTTS, "sequential-tts", true, "Enforce sequential TTS (prevent concurrent speech)")
 	rootCmd.PersistentFlags().StringVar(&outputDir, "output-dir", "", "Save audio files to directory (env: MCP_TTS_OUTPUT_DIR)")
 	rootCmd.PersistentFlags().BoolVar(&noPlay, "no-play", false, "Skip playback, only save (requires --output-dir)")
+	rootCmd.PersistentFlags().StringVar(&saveFormat, "save-format", "", "Format for saved audio: wav, mp3, opus, flac, aac, pcm, aiff (env: MCP_TTS_SAVE_FORMAT)")
+	rootCmd.PersistentFlags().BoolVar(&writeCaptions, "captions", false, "Write .srt and .vtt captions next to saved audio (env: MCP_TTS_CAPTIONS)")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Config profile to use, e.g. quiet-office (env: MCP_TTS_PROFILE)")
+	rootCmd.PersistentFlags().BoolVar(&projectVoices, "project-voices", true, "Give each project its own voice per message category when a call omits voice (env: MCP_TTS_PROJECT_VOICES)")
+	rootCmd.PersistentFlags().BoolVar(&condenseByDefault, "condense", false, "Summarize long text before speaking unless a call sets condense=false (env: MCP_TTS_CONDENSE)")
+	rootCmd.PersistentFlags().IntVar(&condenseWords, "condense-words", DefaultCondenseWords, "Word count condensed text aims for (env: MCP_TTS_CONDENSE_WORDS)")
+	rootCmd.PersistentFlags().StringVar(&ttsMode, "tts-mode", TTSModeRecommend, "What the tts tool does with the chosen provider: speak, or recommend a provider tool call (env: MCP_TTS_TTS_MODE)")
+	rootCmd.PersistentFlags().BoolVar(&persistPreferences, "persist-preferences", false, "Keep settings users ask to remember across restarts, per MCP client name (env: MCP_TTS_PERSIST_PREFERENCES)")
+	rootCmd.PersistentFlags().StringVar(&lexiconPath, "lexicon", "", "Pronunciation lexicon file (default: ~/.config/mcp-tts/lexicon.yaml) (env: MCP_TTS_LEXICON)")
+	rootCmd.PersistentFlags().StringVar(&redactMode, "redact", RedactModeRedact, "Handle secrets and personal data in text: redact, replace, refuse, off (env: MCP_TTS_REDACT)")
+	rootCmd.PersistentFlags().StringArrayVar(&redactPatterns, "redact-pattern", nil, "Additional regular expression to redact, repeatable (env: MCP_TTS_REDACT_PATTERNS, one per line)")
+	rootCmd.PersistentFlags().StringVar(&logTextMode, "log-text", LogTextNone, "How much spoken text to include in logs: none, truncated, full (env: MCP_TTS_LOG_TEXT)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", LogFormatText, "Log output format: text, json (env: MCP_TTS_LOG_FORMAT)")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "Write logs to this file instead of stderr (env: MCP_TTS_LOG_FILE)")
+	rootCmd.PersistentFlags().IntVar(&logMaxSizeMB, "log-max-size", 10, "Rotate the log file after this many megabytes")
+	rootCmd.PersistentFlags().IntVar(&logMaxBackups, "log-max-backups", 3, "Number of rotated log files to keep")
+	rootCmd.PersistentFlags().BoolVar(&trimSilenceEnabled, "trim-silence", true, "Trim leading/trailing silence before playback and saving (env: MCP_TTS_TRIM_SILENCE)")
+	rootCmd.PersistentFlags().Float64Var(&silenceThreshold, "silence-threshold", DefaultSilenceThreshold, "Amplitude (0-1) below which audio counts as silence (env: MCP_TTS_SILENCE_THRESHOLD)")
+	rootCmd.PersistentFlags().DurationVar(&silenceMinDuration, "silence-min-duration", DefaultSilenceMinDuration, "Shortest leading/trailing silence that gets trimmed (env: MCP_TTS_SILENCE_MIN_DURATION)")
+	rootCmd.PersistentFlags().DurationVar(&utteranceGap, "utterance-gap", DefaultUtteranceGap, "Pause inserted between consecutive queued utterances (env: MCP_TTS_UTTERANCE_GAP)")
+	rootCmd.PersistentFlags().IntVar(&maxChars, "max-chars", DefaultMaxChars, "Refuse text longer than this many characters, 0 for no limit (env: MCP_TTS_MAX_CHARS)")
+	rootCmd.PersistentFlags().DurationVar(&maxAudioDuration, "max-audio-duration", 0, "Stop playback after this long, e.g. 2m, 0 for no limit (env: MCP_TTS_MAX_AUDIO_DURATION)")
+	rootCmd.PersistentFlags().IntVar(&maxCallsPerMinute, "max-calls-per-minute", 0, "Speech calls allowed per MCP session per minute, 0 for no limit (env: MCP_TTS_MAX_CALLS_PER_MINUTE)")
+	rootCmd.PersistentFlags().StringArrayVar(&denyPhrases, "deny-phrase", nil, "Refuse text containing this phrase, repeatable (env: MCP_TTS_DENY_PHRASES, one per line)")
+	rootCmd.PersistentFlags().StringArrayVar(&allowPhrases, "allow-phrase", nil, "Phrase exempt from --deny-phrase matches, repeatable (env: MCP_TTS_ALLOW_PHRASES, one per line)")
+	rootCmd.PersistentFlags().IntVar(&confirmChars, "confirm-chars", 0, "Ask the user before speaking text longer than this many characters, 0 for never (env: MCP_TTS_CONFIRM_CHARS)")
+	rootCmd.PersistentFlags().Float64Var(&confirmCost, "confirm-cost", 0, "Ask the user before speaking text estimated to cost more than this many dollars, 0 for never (env: MCP_TTS_CONFIRM_COST)")
+	rootCmd.PersistentFlags().StringVar(&auditLogPath, "audit-log", "", "Append a hash-chained audit record of every tool call to this file (env: MCP_TTS_AUDIT_LOG)")
+	rootCmd.PersistentFlags().StringVar(&metricsAddr, "metrics-addr", "", "Serve Prometheus metrics on this address, e.g. localhost:9464 (env: MCP_TTS_METRICS_ADDR)")
+	rootCmd.PersistentFlags().StringVar(&usageLedgerPath, "usage-ledger", "", "Usage ledger file, or off (default: ~/.config/mcp-tts/usage.jsonl) (env: MCP_TTS_USAGE_LEDGER)")
 
 	// Check environment variable for suppressing output
 	if os.Getenv("MCP_TTS_SUPPRESS_SPEAKING_OUTPUT") == "true" {
@@ -303,6 +380,163 @@ func init() {
 	if os.Getenv("MCP_TTS_NO_PLAY") == "true" {
 		noPlay = true
 	}
+
+	// Check environment variable for saved audio format
+	if format := os.Getenv("MCP_TTS_SAVE_FORMAT"); format != "" && saveFormat == "" {
+		saveFormat = format
+	}
+
+	// Check environment variable for caption files
+	if os.Getenv("MCP_TTS_CAPTIONS") == "true" {
+		writeCaptions = true
+	}
+
+	// Check environment variable for the config profile
+	if name := os.Getenv("MCP_TTS_PROFILE"); name != "" && profileName == "" {
+		profileName = name
+	}
+
+	// Check environment variable for per-project voices
+	if os.Getenv("MCP_TTS_PROJECT_VOICES") == "false" {
+		projectVoices = false
+	}
+
+	// Check environment variables for condensing long text
+	if os.Getenv("MCP_TTS_CONDENSE") == "true" {
+		condenseByDefault = true
+	}
+	if v := os.Getenv("MCP_TTS_CONDENSE_WORDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			condenseWords = n
+		} else {
+			log.Warn("Invalid MCP_TTS_CONDENSE_WORDS, using default", "value", v, "error", err)
+		}
+	}
+
+	// Check environment variable for the tts tool mode
+	if mode := os.Getenv("MCP_TTS_TTS_MODE"); mode != "" {
+		ttsMode = mode
+	}
+
+	// Check environment variable for persisted preferences
+	if os.Getenv("MCP_TTS_PERSIST_PREFERENCES") == "true" {
+		persistPreferences = true
+	}
+
+	// Check environment variable for the pronunciation lexicon
+	if path := os.Getenv("MCP_TTS_LEXICON"); path != "" && lexiconPath == "" {
+		lexiconPath = path
+	}
+
+	// Check environment variable for the usage ledger
+	if path := os.Getenv("MCP_TTS_USAGE_LEDGER"); path != "" && usageLedgerPath == "" {
+		usageLedgerPath = path
+	}
+
+	// Check environment variable for the audit log
+	if path := os.Getenv("MCP_TTS_AUDIT_LOG"); path != "" && auditLogPath == "" {
+		auditLogPath = path
+	}
+
+	// Check environment variable for the metrics endpoint
+	if addr := os.Getenv("MCP_TTS_METRICS_ADDR"); addr != "" && metricsAddr == "" {
+		metricsAddr = addr
+	}
+
+	// Check environment variables for logging
+	if mode := os.Getenv("MCP_TTS_LOG_TEXT"); mode != "" {
+		logTextMode = mode
+	}
+	if format := os.Getenv("MCP_TTS_LOG_FORMAT"); format != "" {
+		logFormat = format
+	}
+	if path := os.Getenv("MCP_TTS_LOG_FILE"); path != "" && logFile == "" {
+		logFile = path
+	}
+
+	// Check environment variables for redaction
+	if mode := os.Getenv("MCP_TTS_REDACT"); mode != "" {
+		redactMode = mode
+	}
+	if patterns := os.Getenv("MCP_TTS_REDACT_PATTERNS"); patterns != "" {
+		for p := range strings.Lines(patterns) {
+			if p = strings.TrimSpace(p); p != "" {
+				redactPatterns = append(redactPatterns, p)
+			}
+		}
+	}
+
+	// Check environment variables for input limits
+	if v := os.Getenv("MCP_TTS_MAX_CHARS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			maxChars = n
+		} else {
+			log.Warn("Invalid MCP_TTS_MAX_CHARS, using default", "value", v, "error", err)
+		}
+	}
+	if v := os.Getenv("MCP_TTS_MAX_AUDIO_DURATION"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			maxAudioDuration = d
+		} else {
+			log.Warn("Invalid MCP_TTS_MAX_AUDIO_DURATION, using default", "value", v, "error", err)
+		}
+	}
+	if v := os.Getenv("MCP_TTS_MAX_CALLS_PER_MINUTE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			maxCallsPerMinute = n
+		} else {
+			log.Warn("Invalid MCP_TTS_MAX_CALLS_PER_MINUTE, using default", "value", v, "error", err)
+		}
+	}
+	for env, phrases := range map[string]*[]string{"MCP_TTS_DENY_PHRASES": &denyPhrases, "MCP_TTS_ALLOW_PHRASES": &allowPhrases} {
+		for p := range strings.Lines(os.Getenv(env)) {
+			if p = strings.TrimSpace(p); p != "" {
+				*phrases = append(*phrases, p)
+			}
+		}
+	}
+
+	// Check environment variables for confirmation thresholds
+	if v := os.Getenv("MCP_TTS_CONFIRM_CHARS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			confirmChars = n
+		} else {
+			log.Warn("Invalid MCP_TTS_CONFIRM_CHARS, using default", "value", v, "error", err)
+		}
+	}
+	if v := os.Getenv("MCP_TTS_CONFIRM_COST"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			confirmCost = f
+		} else {
+			log.Warn("Invalid MCP_TTS_CONFIRM_COST, using default", "value", v, "error", err)
+		}
+	}
+
+	// Check environment variables for silence trimming and utterance spacing
+	if os.Getenv("MCP_TTS_TRIM_SILENCE") == "false" {
+		trimSilenceEnabled = false
+	}
+	if v := os.Getenv("MCP_TTS_SILENCE_THRESHOLD"); v != "" {
+		if threshold, err := strconv.ParseFloat(v, 64); err == nil {
+			silenceThreshold = threshold
+		} else {
+			log.Warn("Invalid MCP_TTS_SILENCE_THRESHOLD, using default", "value", v, "error", err)
+		}
+	}
+	if v := os.Getenv("MCP_TTS_SILENCE_MIN_DURATION"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			silenceMinDuration = d
+		} else {
+			log.Warn("Invalid MCP_TTS_SILENCE_MIN_DURATION, using default", "value", v, "error", err)
+		}
+	}
+	if v := os.Getenv("MCP_TTS_UTTERANCE_GAP"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			utteranceGap = d
+		} else {
+			log.Warn("Invalid MCP_TTS_UTTERANCE_GAP, using default", "value", v, "error", err)
+		}
+	}
 }
 
 // rootCmd represents the base command when called without any subcommands
@@ -323,9 +557,13 @@ Requires appropriate API keys for cloud-based services.
 
 Designed to be used with the MCP (Model Context Protocol).`,
 	Args: cobra.NoArgs,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Config files fill in anything not set by flags or environment variables
+		return setupConfig(cmd.Root().PersistentFlags())
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if verbose {
-			log.SetLevel(log.DebugLevel)
+		if err := setupLogging(); err != nil {
+			return err
 		}
 
 		// Validate --no-play requires --output-dir
@@ -348,6 +586,55 @@ Designed to be used with the MCP (Model Context Protocol).`,
 			log.Debug("Audio saving enabled", "outputDir", outputDir, "noPlay", noPlay)
 		}
 
+		// Validate the saved audio format
+		format, err := resolveSaveFormat(nil)
+		if err != nil {
+			return fmt.Errorf("invalid --save-format: %w", err)
+		}
+		saveFormat = format
+		if !isPlayableFormat(saveFormat) && !noPlay {
+			return fmt.Errorf("--save-format %s requires --no-play", saveFormat)
+		}
+
+		if silenceThreshold < 0 || silenceThreshold > 1 {
+			return fmt.Errorf("--silence-threshold must be between 0 and 1")
+		}
+
+		if maxChars < 0 || maxAudioDuration < 0 || maxCallsPerMinute < 0 {
+			return fmt.Errorf("--max-chars, --max-audio-duration and --max-calls-per-minute must not be negative")
+		}
+		activePhraseFilter = newPhraseFilter(denyPhrases, allowPhrases)
+		if condenseWords <= 0 {
+			return fmt.Errorf("--condense-words must be positive")
+		}
+		if !slices.Contains(TTSModes, ttsMode) {
+			return fmt.Errorf("invalid --tts-mode %q (supported: %s)", ttsMode, strings.Join(TTSModes, ", "))
+		}
+		if confirmChars < 0 || confirmCost < 0 {
+			return fmt.Errorf("--confirm-chars and --confirm-cost must not be negative")
+		}
+
+		redactor, err := newRedactor(redactMode, redactPatterns)
+		if err != nil {
+			return fmt.Errorf("invalid redaction settings: %w", err)
+		}
+		activeRedactor = redactor
+
+		// Load the global and per-project pronunciation lexicons
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get working directory: %w", err)
+		}
+		lex, err := loadLexicon(lexiconFiles(cwd)...)
+		if err != nil {
+			return fmt.Errorf("invalid lexicon: %w", err)
+		}
+		activeLexicon = lex
+
+		// Record synthesis calls in the usage ledger and tool calls in the audit log
+		activeUsageLedger = newUsageLedger(usageLedgerPath)
+		activeAuditLog = newAuditLog(auditLogPath)
+
 		// Log sequential TTS status
 		if sequentialTTS {
 			log.Debug("Sequential TTS enabled - only one speech operation at a time")
@@ -394,9 +681,25 @@ Designed to be used with the MCP (Model Context Protocol).`,
 			WebsiteURL: "https://github.com/blacktop/mcp-tts",
 			Icons:      []mcp.Icon{serverIcon},
 		}
-		s := mcp.NewServer(impl, nil)
+		s := mcp.NewServer(impl, &mcp.ServerOptions{
+			RootsListChangedHandler: forgetSessionProject,
+			InitializedHandler: func(ctx context.Context, req *mcp.InitializedRequest) {
+				forgetPreferencesOnClose(ctx, req)
+				forgetSessionProjectOnClose(ctx, req)
+				forgetCallLimitOnClose(ctx, req)
+			},
+			CompletionHandler: completeArgument,
+		})
+		// Send warnings and errors to clients that ask for them with
+		// logging/setLevel, not just to stderr
+		forwardLogs(s)
+		// Record every tool call in the audit log, if enabled, including
+		// calls refused by the input limits. Text is condensed before the
+		// limits see it.
+		s.AddReceivingMiddleware(auditMiddleware, condenseMiddleware, safeguardMiddleware)
 
-		// Prompt functionality removed - focusing on tools with new SDK
+		// Add prompts for common announcements
+		addPrompts(s)
 
 		if runtime.GOOS == "darwin" {
 			// Add the "say_tts" tool with v1.2.0 features
@@ -414,26 +717,39 @@ Designed to be used with the MCP (Model Context Protocol).`,
 			}
 
 			// Add the say tool handler
-			mcp.AddTool(s, sayTool, func(ctx context.Context, req *mcp.CallToolRequest, input SayTTSParams) (*mcp.CallToolResult, any, error) {
+			addSpeechTool(s, sayTool, sayParams, func(ctx context.Context, req *mcp.CallToolRequest, input SayTTSParams) (*mcp.CallToolResult, any, error) {
 				select {
 				case <-ctx.Done():
 					return textResult("Request cancelled"), nil, nil
 				default:
 				}
 
-				log.Debug("Say tool called", "params", input)
+				var refused *mcp.CallToolResult
+				if input.Text, refused = redactText("text", input.Text); refused != nil {
+					return refused, nil, nil
+				}
+
+				log.Debug("Say tool called", "params", loggableParams(input))
 
 				text := input.Text
 				if text == "" {
 					return errorResult("Error: Empty text provided"), nil, nil
 				}
 
+				speech, result := prepareSpeech(ctx, req, ProviderSay, nil, input.ttsParams())
+				if result != nil {
+					return result, nil, nil
+				}
+				defer speech.release()
+				text, markup, profile, category, saveAs := speech.Text, speech.Markup, speech.Profile, speech.Category, speech.SaveAs
+
 				// Gather optional settings before taking the global speech lock so
 				// other sessions are not blocked while the user decides.
 				if input.Voice == nil && input.Rate == nil {
-					content, result, stop := maybeElicitContent(
+					content, result, stop := elicitSettings(
 						ctx,
 						req,
+						ProviderSay,
 						"elicit macOS Say settings",
 						"Configure macOS Say settings (or accept defaults):",
 						saySettingsSchema(),
@@ -444,19 +760,24 @@ Designed to be used with the MCP (Model Context Protocol).`,
 					applySaySettings(&input, content)
 				}
 
-				release, err := acquireTTSLock(ctx)
+				// Unset settings come from the category, then the configured defaults
+				defaults := providerDefaults(ProviderSay, profile)
+				category.applySay(&input, defaults)
+				fillSayParams(&input, defaults)
+
+				release, err := acquireTTSLock(ctx, category.priorityRank())
 				if err != nil {
 					log.Info("Request cancelled while waiting for TTS lock")
 					return textResult("Request cancelled while waiting for TTS"), nil, nil
 				}
 				defer release()
+				playChime(ctx, category.Chime)
 
-				args := []string{"--rate"}
+				rate := DefaultSayRate
 				if input.Rate != nil {
-					args = append(args, fmt.Sprintf("%d", *input.Rate))
-				} else {
-					args = append(args, fmt.Sprintf("%d", DefaultSayRate))
+					rate = *input.Rate
 				}
+				args := []string{"--rate", fmt.Sprintf("%d", rate)}
 
 				if input.Voice != nil && *input.Voice != "" {
 					voice := *input.Voice
@@ -481,7 +802,7 @@ Designed to be used with the MCP (Model Context Protocol).`,
 				dangerousChars := []rune{';', '&', '|', '<', '>', '`', '$', '(', ')', '{', '}', '[', ']', '\\', '\'', '"', '\n', '\r'}
 				for _, char := range dangerousChars {
 					if bytes.ContainsRune([]byte(text), char) {
-						log.Warn("Potentially dangerous character in text input", "char", string(char), "text", text)
+						log.Warn("Potentially dangerous character in text input", "char", string(char), "text", logText(text))
 					}
 				}
 
@@ -490,21 +811,40 @@ Designed to be used with the MCP (Model Context Protocol).`,
 				var savedPath string
 				willPlay := true
 				if shouldSave() {
-					filename := generateFilename(text, "aiff")
+					ext := FormatAIFF
+					if saveAs == FormatWAV {
+						ext = FormatWAV
+						// say picks the container from these flags, not the extension
+						args = append(args, "--file-format=WAVE", "--data-format=LEI16@22050")
+					}
+					filename := generateFilename(text, ext)
 					savedPath = filepath.Join(outputDir, filename)
 					args = append(args, "-o", savedPath)
 					willPlay = false // say -o does not play, only writes
 					log.Debug("Saving audio to file", "path", savedPath)
 				}
 
-				args = append(args, text)
+				args = append(args, sayInput(text, markup, rate))
 
-				log.Debug("Executing say command", "args", args)
+				// The spoken text is the last argument
+				log.Debug("Executing say command", "args", args[:len(args)-1], "text", logText(text))
 				sayCmd := exec.CommandContext(ctx, "/usr/bin/say", args...)
 				if err := sayCmd.Start(); err != nil {
 					log.Error("Failed to start say command", "error", err)
 					return errorResult(fmt.Sprintf("Error: Failed to start say command: %v", err)), nil, nil
 				}
+				if willPlay {
+					defer startPlayback(ctx, ProviderSay)()
+				}
+				// say plays as it runs, so stopping it ends playback
+				var stoppedAtLimit atomic.Bool
+				if willPlay && maxAudioDuration > 0 {
+					timer := time.AfterFunc(maxAudioDuration, func() {
+						stoppedAtLimit.Store(true)
+						sayCmd.Process.Kill()
+					})
+					defer timer.Stop()
+				}
 
 				done := make(chan error, 1)
 				go func() {
@@ -513,6 +853,10 @@ Designed to be used with the MCP (Model Context Protocol).`,
 
 				select {
 				case err := <-done:
+					if err != nil && stoppedAtLimit.Load() {
+						log.Warn("Playback stopped at the audio duration limit", "limit", maxAudioDuration)
+						err = nil
+					}
 					if err != nil {
 						if ctx.Err() == context.Canceled {
 							log.Info("Say command cancelled by user")
@@ -521,10 +865,16 @@ Designed to be used with the MCP (Model Context Protocol).`,
 						log.Error("Say command failed", "error", err)
 						return errorResult(fmt.Sprintf("Error: Say command failed: %v", err)), nil, nil
 					}
-					log.Info("Speaking text completed", "text", text)
+					log.Info("Speaking text completed", "text", logText(text))
+					speech.synthesized(ctx, req, "", estimateSpeechDuration(text, float64(rate)))
+					var captionPaths []string
+					if savedPath != "" && captionsEnabled(speech.Captions) {
+						cues := cuesFromText(text, estimateSpeechDuration(text, float64(rate)))
+						captionPaths = writeCaptionFiles(savedPath, cues)
+					}
 					// If we saved but didn't play, and user wants playback too, play the saved file
 					if savedPath != "" && shouldPlay() {
-						log.Debug("Playing saved AIFF file", "path", savedPath)
+						log.Debug("Playing saved audio file", "path", savedPath)
 						playCmd := exec.CommandContext(ctx, "afplay", savedPath)
 						if playErr := playCmd.Run(); playErr != nil {
 							log.Warn("Failed to play saved audio", "error", playErr)
@@ -532,7 +882,7 @@ Designed to be used with the MCP (Model Context Protocol).`,
 							willPlay = true
 						}
 					}
-					return textResult(formatSaveResult(text, savedPath, willPlay)), nil, nil
+					return textResult(formatSaveResult(text, savedPath, willPlay, captionPaths...)), nil, nil
 				case <-ctx.Done():
 					log.Info("Say command cancelled by user")
 					return textResult("Say command cancelled"), nil, nil
@@ -553,52 +903,66 @@ Designed to be used with the MCP (Model Context Protocol).`,
 			},
 		}
 
-		mcp.AddTool(s, elevenLabsTool, func(ctx context.Context, _ *mcp.CallToolRequest, input ElevenLabsTTSParams) (*mcp.CallToolResult, any, error) {
+		addSpeechTool(s, elevenLabsTool, elevenLabsParams, func(ctx context.Context, req *mcp.CallToolRequest, input ElevenLabsTTSParams) (*mcp.CallToolResult, any, error) {
 			select {
 			case <-ctx.Done():
 				return textResult("Request cancelled"), nil, nil
 			default:
 			}
 
-			release, err := acquireTTSLock(ctx)
-			if err != nil {
-				log.Info("Request cancelled while waiting for TTS lock")
-				return textResult("Request cancelled while waiting for TTS"), nil, nil
+			var refused *mcp.CallToolResult
+			if input.Text, refused = redactText("text", input.Text); refused != nil {
+				return refused, nil, nil
 			}
-			defer release()
 
-			log.Debug("ElevenLabs tool called", "params", input)
+			log.Debug("ElevenLabs tool called", "params", loggableParams(input))
 			text := input.Text
 			if text == "" {
 				return errorResult("Error: text must be a string"), nil, nil
 			}
 
-			voiceID := os.Getenv("ELEVENLABS_VOICE_ID")
-			if voiceID == "" {
-				voiceID = "1SM7GgM6IMuvQlz2BwM3"
-				log.Debug("Voice not specified, using default", "voiceID", voiceID)
-			}
-
-			modelID := os.Getenv("ELEVENLABS_MODEL_ID")
-			if modelID == "" {
-				modelID = "eleven_v3"
-				log.Debug("Model not specified, using default", "modelID", modelID)
-			}
-
 			apiKey := os.Getenv("ELEVENLABS_API_KEY")
 			if apiKey == "" {
 				log.Error("ELEVENLABS_API_KEY not set")
 				return errorResult("Error: ELEVENLABS_API_KEY is not set"), nil, nil
 			}
 
+			speech, result := prepareSpeech(ctx, req, ProviderElevenLabs, nil, input.ttsParams())
+			if result != nil {
+				return result, nil, nil
+			}
+			defer speech.release()
+			text, markup, profile, category, saveAs := speech.Text, speech.Markup, speech.Profile, speech.Category, speech.SaveAs
+
+			release, err := acquireTTSLock(ctx, category.priorityRank())
+			if err != nil {
+				log.Info("Request cancelled while waiting for TTS lock")
+				return textResult("Request cancelled while waiting for TTS"), nil, nil
+			}
+			defer release()
+			playChime(ctx, category.Chime)
+
+			// ELEVENLABS_VOICE_ID and ELEVENLABS_MODEL_ID override the config;
+			// a category voice overrides both
+			defaults := providerDefaults(ProviderElevenLabs, profile)
+			voiceID := defaults.Voice
+			if v := category.voice(ProviderElevenLabs); v != "" {
+				voiceID = v
+			}
+			modelID := defaults.Model
+			log.Debug("Using ElevenLabs settings", "voiceID", voiceID, "modelID", modelID, "profile", profile)
+
+			speechText := elevenLabsInput(text, markup, modelID)
+
 			shouldPlayNow := shouldPlay()
 			shouldSaveNow := shouldSave()
+			wantCaptions := captionsEnabled(speech.Captions)
 			noPlaySave := !shouldPlayNow && shouldSaveNow
 
-			// Buffer to capture MP3 data if saving is enabled
-			var mp3Buffer *bytes.Buffer
+			// Buffer to capture audio data if saving is enabled
+			var audioBuffer *bytes.Buffer
 			if shouldSaveNow {
-				mp3Buffer = &bytes.Buffer{}
+				audioBuffer = &bytes.Buffer{}
 			}
 
 			var pipeReader *io.PipeReader
@@ -612,6 +976,9 @@ Designed to be used with the MCP (Model Context Protocol).`,
 			// Channel to signal when audio playback is complete
 			audioComplete := make(chan error, 1)
 
+			// Collects character alignment when captions use the timestamped endpoint
+			var timestamped *timestampedAudioReader
+
 			g, ctx := errgroup.WithContext(ctx)
 			reqCtx, cancelReq := context.WithCancel(ctx)
 			defer cancelReq()
@@ -636,11 +1003,17 @@ Designed to be used with the MCP (Model Context Protocol).`,
 					defer pipeWriter.Close()
 				}
 
-				url := fmt.Sprintf("https://api.elevenlabs.io/v1/text-to-speech/%s/stream", voiceID)
+				endpoint := "stream"
+				if wantCaptions {
+					// Same audio, wrapped in JSON chunks with character timings
+					endpoint = "stream/with-timestamps"
+				}
+				url := fmt.Sprintf("%s/v1/text-to-speech/%s/%s?output_format=%s", providerBaseURL(ProviderElevenLabs), voiceID, endpoint, elevenLabsOutputFormat(saveAs))
 
 				params := ElevenLabsParams{
-					Text:    text,
-					ModelID: modelID,
+					Text:                            speechText,
+					ModelID:                         modelID,
+					PronunciationDictionaryLocators: activeLexicon.elevenLabsLocators(),
 					VoiceSettings: SynthesisOptions{
 						Stability:       0.5, // Must be 0.0 (Creative), 0.5 (Natural), or 1.0 (Robust)
 						SimilarityBoost: 0.75,
@@ -660,51 +1033,44 @@ Designed to be used with the MCP (Model Context Protocol).`,
 					"url", url,
 					"voice", voiceID,
 					"model", modelID,
-					"text", text,
-					"params", params,
+					"text", logText(text),
+					"params", loggableParams(params),
 				)
 
-				req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, url, bytes.NewBuffer(b))
-				if err != nil {
-					log.Error("Failed to create request", "error", err)
-					statusValidated <- fmt.Errorf("failed to create request: %v", err)
-					return fmt.Errorf("failed to create request: %v", err)
+				accept := "audio/mpeg"
+				if wantCaptions {
+					accept = "application/json"
+				} else if saveAs == FormatPCM || saveAs == FormatOpus {
+					accept = "*/*"
 				}
 
-				req.Header.Set("xi-api-key", apiKey)
-				req.Header.Set("Content-Type", "application/json")
-				req.Header.Set("accept", "audio/mpeg")
-
-				safeLog("Sending HTTP request", req)
-				res, err := http.DefaultClient.Do(req)
+				// Rate limits and temporary errors are retried before any audio is read
+				synthesisStart := time.Now()
+				spanCtx, span := startSpan(reqCtx, "provider request", attribute.String("tts.provider", "elevenlabs"), attribute.String("tts.model", modelID))
+				res, err := elevenLabsRequest(spanCtx, defaults.Retry, url, apiKey, accept, b)
+				endSpan(span, err)
 				if err != nil {
 					log.Error("Failed to send request", "error", err)
-					statusValidated <- fmt.Errorf("failed to send request: %v", err)
-					return fmt.Errorf("failed to send request: %v", err)
+					statusValidated <- err
+					return err
 				}
 				defer res.Body.Close()
 
-				if res.StatusCode != http.StatusOK {
-					log.Error("Request failed", "status", res.Status, "statusCode", res.StatusCode)
-					// Read the error response body for more details
-					body, readErr := io.ReadAll(res.Body)
-					errMsg := fmt.Errorf("ElevenLabs API error: status %d %s", res.StatusCode, res.Status)
-					if readErr == nil && len(body) > 0 {
-						log.Error("Error response body", "body", string(body))
-						errMsg = fmt.Errorf("ElevenLabs API error (status %d): %s", res.StatusCode, string(body))
-					}
-					statusValidated <- errMsg
-					return errMsg
-				}
-
 				// HTTP status is OK, signal success and proceed with streaming
+				ttsMetrics.synthesis.since(synthesisStart, providerKey(ProviderElevenLabs))
 				statusValidated <- nil
 
+				var body io.Reader = res.Body
+				if wantCaptions {
+					timestamped = newTimestampedAudioReader(res.Body)
+					body = timestamped
+				}
+
 				if noPlaySave {
 					log.Debug("Copying response body to buffer")
-					var reader io.Reader = res.Body
-					if mp3Buffer != nil {
-						reader = io.TeeReader(res.Body, mp3Buffer)
+					reader := body
+					if audioBuffer != nil {
+						reader = io.TeeReader(body, audioBuffer)
 					}
 					bytesWritten, err := io.Copy(io.Discard, reader)
 					log.Debug("Response body copied", "bytes", bytesWritten)
@@ -716,12 +1082,12 @@ Designed to be used with the MCP (Model Context Protocol).`,
 				if pipeWriter == nil {
 					return fmt.Errorf("missing pipe writer for playback")
 				}
-				if mp3Buffer != nil {
+				if audioBuffer != nil {
 					// Use TeeReader to capture MP3 data while streaming
-					tee := io.TeeReader(res.Body, mp3Buffer)
+					tee := io.TeeReader(body, audioBuffer)
 					bytesWritten, err = io.Copy(pipeWriter, tee)
 				} else {
-					bytesWritten, err = io.Copy(pipeWriter, res.Body)
+					bytesWritten, err = io.Copy(pipeWriter, body)
 				}
 				log.Debug("Response body copied", "bytes", bytesWritten)
 				return err
@@ -734,6 +1100,7 @@ Designed to be used with the MCP (Model Context Protocol).`,
 					return errorResult(fmt.Sprintf("Error: %v", err)), nil, nil
 				}
 				log.Debug("HTTP status validated successfully, proceeding to decode")
+				speech.synthesized(ctx, req, modelID, estimateSpeechDuration(text, defaultWordsPerMinute))
 			case <-ctx.Done():
 				log.Error("Context cancelled while waiting for HTTP status validation")
 				return errorResult("Error: Request cancelled"), nil, nil
@@ -742,25 +1109,33 @@ Designed to be used with the MCP (Model Context Protocol).`,
 			// Handle no-play mode: buffer stream and save without playback
 			if noPlaySave {
 				log.Debug("No-play mode: buffering stream for save")
-				// Wait for HTTP goroutine to complete (it will copy to mp3Buffer)
+				// Wait for HTTP goroutine to complete (it will copy to audioBuffer)
 				if err := g.Wait(); err != nil && err != context.Canceled {
 					log.Error("Error occurred during streaming", "error", err)
 					return errorResult(fmt.Sprintf("Error: %v", err)), nil, nil
 				}
-				// Save the MP3 file
-				savedPath, saveErr := saveMP3(mp3Buffer.Bytes(), text)
+				// Save the audio file
+				_, saveSpan := startSpan(ctx, "save")
+				savedPath, saveErr := saveMP3Audio(audioBuffer.Bytes(), saveAs, text)
+				endSpan(saveSpan, saveErr)
 				if saveErr != nil {
-					log.Error("Failed to save MP3 file", "error", saveErr)
+					log.Error("Failed to save audio file", "error", saveErr)
 					return errorResult(fmt.Sprintf("Error saving audio: %v", saveErr)), nil, nil
 				}
 				log.Info("Audio saved", "path", savedPath)
-				return textResult(formatSaveResult(text, savedPath, false)), nil, nil
+				var captionPaths []string
+				if wantCaptions {
+					captionPaths = writeCaptionFiles(savedPath, elevenLabsCues(text, timestamped, audioBuffer.Bytes(), saveAs))
+				}
+				return textResult(formatSaveResult(text, savedPath, false, captionPaths...)), nil, nil
 			}
 
 			// Start audio playback in a separate goroutine with cancellation support
 			g.Go(func() error {
 				log.Debug("Decoding MP3 stream")
+				_, decodeSpan := startSpan(ctx, "decode")
 				streamer, format, err := mp3.Decode(pipeReader)
+				endSpan(decodeSpan, err)
 				if err != nil {
 					log.Error("Failed to decode response", "error", err)
 					stopStreaming(err)
@@ -776,15 +1151,16 @@ Designed to be used with the MCP (Model Context Protocol).`,
 					audioComplete <- fmt.Errorf("failed to initialize speaker: %v", err)
 					return fmt.Errorf("failed to initialize speaker: %v", err)
 				}
-				playback := resampleToSpeaker(streamer, format.SampleRate)
+				playback := resampleToSpeaker(limitPlayback(maybeTrimSilence(streamer, format.SampleRate), format.SampleRate), format.SampleRate)
 				done := make(chan bool, 1)
 
 				// Play audio with callback
+				defer startPlayback(ctx, ProviderElevenLabs)()
 				speaker.Play(beep.Seq(playback, beep.Callback(func() {
 					done <- true
 				})))
 
-				log.Info("Speaking text via ElevenLabs", "text", text)
+				log.Info("Speaking text via ElevenLabs", "text", logText(text))
 
 				// Wait for either completion or cancellation
 				select {
@@ -830,20 +1206,26 @@ Designed to be used with the MCP (Model Context Protocol).`,
 				return errorResult(fmt.Sprintf("Error: %v", err)), nil, nil
 			}
 
-			// Save the MP3 file if enabled
+			// Save the audio file if enabled
 			var savedPath string
-			if shouldSave() && mp3Buffer != nil {
+			var captionPaths []string
+			if shouldSave() && audioBuffer != nil {
 				var saveErr error
-				savedPath, saveErr = saveMP3(mp3Buffer.Bytes(), text)
+				_, saveSpan := startSpan(ctx, "save")
+				savedPath, saveErr = saveMP3Audio(audioBuffer.Bytes(), saveAs, text)
+				endSpan(saveSpan, saveErr)
 				if saveErr != nil {
-					log.Error("Failed to save MP3 file", "error", saveErr)
+					log.Error("Failed to save audio file", "error", saveErr)
 					// Don't fail the request, just log the error
 				} else {
 					log.Info("Audio saved", "path", savedPath)
+					if wantCaptions {
+						captionPaths = writeCaptionFiles(savedPath, elevenLabsCues(text, timestamped, audioBuffer.Bytes(), saveAs))
+					}
 				}
 			}
 
-			return textResult(formatSaveResult(text, savedPath, true)), nil, nil
+			return textResult(formatSaveResult(text, savedPath, true, captionPaths...)), nil, nil
 		})
 
 		// Add Google TTS tool
@@ -860,25 +1242,47 @@ Designed to be used with the MCP (Model Context Protocol).`,
 			},
 		}
 
-		mcp.AddTool(s, googleTTSTool, func(ctx context.Context, req *mcp.CallToolRequest, input GoogleTTSParams) (*mcp.CallToolResult, any, error) {
+		addSpeechTool(s, googleTTSTool, googleParams, func(ctx context.Context, req *mcp.CallToolRequest, input GoogleTTSParams) (*mcp.CallToolResult, any, error) {
 			select {
 			case <-ctx.Done():
 				return textResult("Request cancelled"), nil, nil
 			default:
 			}
 
-			log.Debug("Google TTS tool called", "params", input)
+			var refused *mcp.CallToolResult
+			if input.Text, refused = redactText("text", input.Text); refused != nil {
+				return refused, nil, nil
+			}
+
+			log.Debug("Google TTS tool called", "params", loggableParams(input))
 			text := input.Text
 			if text == "" {
 				return errorResult("Error: Empty text provided"), nil, nil
 			}
 
+			apiKey := os.Getenv("GOOGLE_AI_API_KEY")
+			if apiKey == "" {
+				apiKey = os.Getenv("GEMINI_API_KEY")
+			}
+			if apiKey == "" {
+				log.Error("GOOGLE_AI_API_KEY or GEMINI_API_KEY not set")
+				return errorResult("Error: GOOGLE_AI_API_KEY or GEMINI_API_KEY is not set"), nil, nil
+			}
+
+			speech, result := prepareSpeech(ctx, req, ProviderGoogle, input.Model, input.ttsParams())
+			if result != nil {
+				return result, nil, nil
+			}
+			defer speech.release()
+			text, markup, profile, category, saveAs := speech.Text, speech.Markup, speech.Profile, speech.Category, speech.SaveAs
+
 			// Gather optional settings before taking the global speech lock so
 			// other sessions are not blocked while the user decides.
 			if input.Voice == nil && input.Model == nil {
-				content, result, stop := maybeElicitContent(
+				content, result, stop := elicitSettings(
 					ctx,
 					req,
+					ProviderGoogle,
 					"elicit Google TTS settings",
 					"Configure Google TTS settings (or accept defaults):",
 					googleSettingsSchema(),
@@ -889,12 +1293,18 @@ Designed to be used with the MCP (Model Context Protocol).`,
 				applyGoogleSettings(&input, content)
 			}
 
-			release, err := acquireTTSLock(ctx)
+			// Unset settings come from the category, the project's voice, then the configured defaults
+			category.applyGoogle(&input)
+			applyProjectVoice(ctx, req, ProviderGoogle, profile, input.Category, &input.Voice)
+			fillGoogleParams(&input, providerDefaults(ProviderGoogle, profile))
+
+			release, err := acquireTTSLock(ctx, category.priorityRank())
 			if err != nil {
 				log.Info("Request cancelled while waiting for TTS lock")
 				return textResult("Request cancelled while waiting for TTS"), nil, nil
 			}
 			defer release()
+			playChime(ctx, category.Chime)
 
 			voice := DefaultGoogleVoice
 			if input.Voice != nil && *input.Voice != "" {
@@ -906,15 +1316,6 @@ Designed to be used with the MCP (Model Context Protocol).`,
 				model = *input.Model
 			}
 
-			apiKey := os.Getenv("GOOGLE_AI_API_KEY")
-			if apiKey == "" {
-				apiKey = os.Getenv("GEMINI_API_KEY")
-			}
-			if apiKey == "" {
-				log.Error("GOOGLE_AI_API_KEY or GEMINI_API_KEY not set")
-				return errorResult("Error: GOOGLE_AI_API_KEY or GEMINI_API_KEY is not set"), nil, nil
-			}
-
 			client, err := genai.NewClient(ctx, &genai.ClientConfig{
 				APIKey:  apiKey,
 				Backend: genai.BackendGeminiAPI,
@@ -927,15 +1328,17 @@ Designed to be used with the MCP (Model Context Protocol).`,
 			log.Debug("Generating TTS audio",
 				"model", model,
 				"voice", voice,
-				"text", text,
+				"text", logText(text),
 			)
 
 			// Generate TTS audio using the dedicated TTS models
 			content := []*genai.Content{
-				genai.NewContentFromText(text, genai.RoleUser),
+				genai.NewContentFromText(googleInput(text, markup, category.Style), genai.RoleUser),
 			}
 
-			response, err := client.Models.GenerateContent(ctx, model, content, &genai.GenerateContentConfig{
+			synthesisStart := time.Now()
+			spanCtx, span := startSpan(ctx, "provider request", attribute.String("tts.provider", "google"), attribute.String("tts.model", model))
+			response, err := generateGoogleSpeech(spanCtx, providerDefaults(ProviderGoogle, profile).Retry, client, model, content, &genai.GenerateContentConfig{
 				ResponseModalities: []string{"AUDIO"},
 				SpeechConfig: &genai.SpeechConfig{
 					VoiceConfig: &genai.VoiceConfig{
@@ -945,6 +1348,7 @@ Designed to be used with the MCP (Model Context Protocol).`,
 					},
 				},
 			})
+			endSpan(span, err)
 			if err != nil {
 				log.Error("Failed to generate TTS audio", "error", err)
 				return errorResult(fmt.Sprintf("Error: Failed to generate TTS audio: %v", err)), nil, nil
@@ -961,28 +1365,41 @@ Designed to be used with the MCP (Model Context Protocol).`,
 				return errorResult("Error: No audio data received from Google TTS"), nil, nil
 			}
 
-			audioData := part.InlineData.Data
+			const googleTTSSampleRate = 24000
+
+			audioData := maybeTrimPCMSilence(part.InlineData.Data, googleTTSSampleRate)
 			totalSamples := len(audioData) / 2 // 16-bit samples = 2 bytes each
 			log.Info("Playing TTS audio via beep speaker", "bytes", len(audioData), "samples", totalSamples)
-
-			const googleTTSSampleRate = 24000
+			ttsMetrics.synthesis.since(synthesisStart, providerKey(ProviderGoogle))
+			speech.synthesized(ctx, req, model, pcmDuration(part.InlineData.Data, googleTTSSampleRate))
 
 			// Save WAV file if enabled (do this before playback so file is ready even if cancelled)
 			var savedPath string
+			var captionPaths []string
 			if shouldSave() {
 				var saveErr error
-				savedPath, saveErr = saveWAV(audioData, googleTTSSampleRate, text)
+				_, saveSpan := startSpan(ctx, "save")
+				if saveAs == FormatPCM {
+					savedPath, saveErr = saveAudio(audioData, FormatPCM, text)
+				} else {
+					savedPath, saveErr = saveWAV(audioData, googleTTSSampleRate, text)
+				}
+				endSpan(saveSpan, saveErr)
 				if saveErr != nil {
-					log.Error("Failed to save WAV file", "error", saveErr)
+					log.Error("Failed to save audio file", "error", saveErr)
 					// Don't fail the request, just log the error
 				} else {
 					log.Info("Audio saved", "path", savedPath)
+					if captionsEnabled(speech.Captions) {
+						cues := cuesFromText(text, pcmDuration(audioData, googleTTSSampleRate))
+						captionPaths = writeCaptionFiles(savedPath, cues)
+					}
 				}
 			}
 
 			// Handle no-play mode: just save and return
 			if !shouldPlay() {
-				return textResult(formatSaveResult(text, savedPath, false)), nil, nil
+				return textResult(formatSaveResult(text, savedPath, false, captionPaths...)), nil, nil
 			}
 
 			pcmStream := &PCMStream{
@@ -995,23 +1412,24 @@ Designed to be used with the MCP (Model Context Protocol).`,
 				log.Error("Failed to initialize speaker", "error", err)
 				return errorResult(fmt.Sprintf("Error: Failed to initialize speaker: %v", err)), nil, nil
 			}
-			playback := resampleToSpeaker(pcmStream, pcmStream.sampleRate)
+			playback := resampleToSpeaker(limitPlayback(pcmStream, pcmStream.sampleRate), pcmStream.sampleRate)
 
 			progress := newProgressReporter(ctx, req, totalSamples, googleTTSSampleRate)
 			progress.start(func() int { return pcmStream.position })
 			defer progress.stop()
 
 			done := make(chan bool)
+			defer startPlayback(ctx, ProviderGoogle)()
 			speaker.Play(beep.Seq(playback, beep.Callback(func() {
 				done <- true
 			})))
 
-			log.Info("Speaking via Google TTS", "text", text, "voice", voice, "model", model)
+			log.Info("Speaking via Google TTS", "text", logText(text), "voice", voice, "model", model)
 
 			select {
 			case <-done:
 				log.Debug("Google TTS audio playback completed normally")
-				return textResult(formatSaveResult(text, savedPath, true)), nil, nil
+				return textResult(formatSaveResult(text, savedPath, true, captionPaths...)), nil, nil
 			case <-ctx.Done():
 				log.Debug("Context cancelled, stopping Google TTS audio playback")
 				speaker.Clear()
@@ -1034,25 +1452,49 @@ Designed to be used with the MCP (Model Context Protocol).`,
 			},
 		}
 
-		mcp.AddTool(s, openaiTTSTool, func(ctx context.Context, req *mcp.CallToolRequest, input OpenAITTSParams) (*mcp.CallToolResult, any, error) {
+		addSpeechTool(s, openaiTTSTool, openAIParams, func(ctx context.Context, req *mcp.CallToolRequest, input OpenAITTSParams) (*mcp.CallToolResult, any, error) {
 			select {
 			case <-ctx.Done():
 				return textResult("Request cancelled"), nil, nil
 			default:
 			}
 
-			log.Debug("OpenAI TTS tool called", "params", input)
+			var refused *mcp.CallToolResult
+			if input.Text, refused = redactText("text", input.Text); refused != nil {
+				return refused, nil, nil
+			}
+			if input.Instructions != nil {
+				if *input.Instructions, refused = redactText("instructions", *input.Instructions); refused != nil {
+					return refused, nil, nil
+				}
+			}
+
+			log.Debug("OpenAI TTS tool called", "params", loggableParams(input))
 			text := input.Text
 			if text == "" {
 				return errorResult("Error: Empty text provided"), nil, nil
 			}
 
+			apiKey := os.Getenv("OPENAI_API_KEY")
+			if apiKey == "" {
+				log.Error("OPENAI_API_KEY not set")
+				return errorResult("Error: OPENAI_API_KEY is not set"), nil, nil
+			}
+
+			speech, result := prepareSpeech(ctx, req, ProviderOpenAI, input.Model, input.ttsParams())
+			if result != nil {
+				return result, nil, nil
+			}
+			defer speech.release()
+			text, markup, profile, category, saveAs := speech.Text, speech.Markup, speech.Profile, speech.Category, speech.SaveAs
+
 			// Gather optional settings before taking the global speech lock so
 			// other sessions are not blocked while the user decides.
 			if input.Voice == nil && input.Model == nil && input.Speed == nil {
-				content, result, stop := maybeElicitContent(
+				content, result, stop := elicitSettings(
 					ctx,
 					req,
+					ProviderOpenAI,
 					"elicit OpenAI TTS settings",
 					"Configure OpenAI TTS settings (or accept defaults):",
 					openAISettingsSchema(),
@@ -1063,12 +1505,19 @@ Designed to be used with the MCP (Model Context Protocol).`,
 				applyOpenAISettings(&input, content)
 			}
 
-			release, err := acquireTTSLock(ctx)
+			// Unset settings come from the category, the project's voice, then the configured defaults
+			defaults := providerDefaults(ProviderOpenAI, profile)
+			category.applyOpenAI(&input, defaults)
+			applyProjectVoice(ctx, req, ProviderOpenAI, profile, input.Category, &input.Voice)
+			fillOpenAIParams(&input, defaults)
+
+			release, err := acquireTTSLock(ctx, category.priorityRank())
 			if err != nil {
 				log.Info("Request cancelled while waiting for TTS lock")
 				return textResult("Request cancelled while waiting for TTS"), nil, nil
 			}
 			defer release()
+			playChime(ctx, category.Chime)
 
 			voice := DefaultOpenAIVoice
 			if input.Voice != nil && *input.Voice != "" {
@@ -1092,23 +1541,22 @@ Designed to be used with the MCP (Model Context Protocol).`,
 			instructions := ""
 			if input.Instructions != nil && *input.Instructions != "" {
 				instructions = *input.Instructions
-			} else {
-				instructions = os.Getenv("OPENAI_TTS_INSTRUCTIONS")
 			}
 
-			if len(instructions) > 1000 {
-				log.Warn("Instructions are very long, may exceed API limits", "length", len(instructions))
+			speechText, hints := openAIInput(text, markup)
+			// tts-1 models do not accept instructions
+			if hints != "" && !strings.HasPrefix(model, "tts-1") {
+				instructions = strings.TrimSpace(instructions + " " + hints)
 			}
 
-			apiKey := os.Getenv("OPENAI_API_KEY")
-			if apiKey == "" {
-				log.Error("OPENAI_API_KEY not set")
-				return errorResult("Error: OPENAI_API_KEY is not set"), nil, nil
+			if len(instructions) > 1000 {
+				log.Warn("Instructions are very long, may exceed API limits", "length", len(instructions))
 			}
 
-			client := openai.NewClient(option.WithAPIKey(apiKey))
+			// Retries follow the configured policy instead of the SDK's
+			client := openai.NewClient(option.WithAPIKey(apiKey), option.WithMaxRetries(0))
 
-			logFields := []any{"model", model, "voice", voice, "speed", speed, "text", text}
+			logFields := []any{"model", model, "voice", voice, "speed", speed, "text", logText(text)}
 			if instructions != "" {
 				logFields = append(logFields, "instructions", instructions)
 			}
@@ -1116,7 +1564,7 @@ Designed to be used with the MCP (Model Context Protocol).`,
 
 			reqParams := openai.AudioSpeechNewParams{
 				Model: openai.SpeechModel(model),
-				Input: text,
+				Input: speechText,
 				Voice: openai.AudioSpeechNewParamsVoice(voice),
 			}
 			if speed != 1.0 {
@@ -1125,9 +1573,15 @@ Designed to be used with the MCP (Model Context Protocol).`,
 			if instructions != "" {
 				reqParams.Instructions = openai.String(instructions)
 			}
+			if responseFormat := openAIResponseFormat(saveAs); responseFormat != openai.AudioSpeechNewParamsResponseFormatMP3 {
+				reqParams.ResponseFormat = responseFormat
+			}
 
-			response, err := client.Audio.Speech.New(ctx, reqParams)
+			synthesisStart := time.Now()
+			spanCtx, span := startSpan(ctx, "provider request", attribute.String("tts.provider", "openai"), attribute.String("tts.model", model))
+			response, err := newOpenAISpeech(spanCtx, providerDefaults(ProviderOpenAI, profile).Retry, client, reqParams)
 			if err != nil {
+				endSpan(span, err)
 				log.Error("Failed to generate OpenAI TTS audio", "error", err)
 				return errorResult(fmt.Sprintf("Error: Failed to generate TTS audio: %v", err)), nil, nil
 			}
@@ -1135,32 +1589,56 @@ Designed to be used with the MCP (Model Context Protocol).`,
 
 			// Buffer the response body so we can both save and decode it
 			audioData, err := io.ReadAll(response.Body)
+			endSpan(span, err)
 			if err != nil {
 				log.Error("Failed to read OpenAI TTS response", "error", err)
 				return errorResult(fmt.Sprintf("Error: Failed to read response: %v", err)), nil, nil
 			}
 			log.Debug("OpenAI TTS audio data received", "bytes", len(audioData))
+			ttsMetrics.synthesis.since(synthesisStart, providerKey(ProviderOpenAI))
+			audioLength := estimateSpeechDuration(text, defaultWordsPerMinute*speed)
+			if isPlayableFormat(saveAs) {
+				if d := mp3Duration(audioData); d > 0 {
+					audioLength = d
+				}
+			}
+			speech.synthesized(ctx, req, model, audioLength)
 
-			// Save MP3 file if enabled (do this before playback)
+			// Save audio file if enabled (do this before playback)
 			var savedPath string
+			var captionPaths []string
 			if shouldSave() {
 				var saveErr error
-				savedPath, saveErr = saveMP3(audioData, text)
+				_, saveSpan := startSpan(ctx, "save")
+				savedPath, saveErr = saveMP3Audio(audioData, saveAs, text)
+				endSpan(saveSpan, saveErr)
 				if saveErr != nil {
-					log.Error("Failed to save MP3 file", "error", saveErr)
+					log.Error("Failed to save audio file", "error", saveErr)
 					// Don't fail the request, just log the error
 				} else {
 					log.Info("Audio saved", "path", savedPath)
+					if captionsEnabled(speech.Captions) {
+						var duration time.Duration
+						if isPlayableFormat(saveAs) {
+							duration = mp3Duration(audioData)
+						}
+						if duration <= 0 {
+							duration = estimateSpeechDuration(text, defaultWordsPerMinute*speed)
+						}
+						captionPaths = writeCaptionFiles(savedPath, transcodedCues(cuesFromText(text, duration), audioData, saveAs))
+					}
 				}
 			}
 
 			// Handle no-play mode: just save and return
 			if !shouldPlay() {
-				return textResult(formatSaveResult(text, savedPath, false)), nil, nil
+				return textResult(formatSaveResult(text, savedPath, false, captionPaths...)), nil, nil
 			}
 
 			log.Debug("Decoding MP3 stream from OpenAI")
+			_, decodeSpan := startSpan(ctx, "decode")
 			streamer, format, err := mp3.Decode(io.NopCloser(bytes.NewReader(audioData)))
+			endSpan(decodeSpan, err)
 			if err != nil {
 				log.Error("Failed to decode OpenAI TTS response", "error", err)
 				return errorResult(fmt.Sprintf("Error: Failed to decode response: %v", err)), nil, nil
@@ -1173,18 +1651,19 @@ Designed to be used with the MCP (Model Context Protocol).`,
 				log.Error("Failed to initialize speaker", "error", err)
 				return errorResult(fmt.Sprintf("Error: Failed to initialize speaker: %v", err)), nil, nil
 			}
-			playback := resampleToSpeaker(streamer, format.SampleRate)
+			playback := resampleToSpeaker(limitPlayback(maybeTrimSilence(streamer, format.SampleRate), format.SampleRate), format.SampleRate)
 
 			progress := newProgressReporter(ctx, req, totalSamples, int(format.SampleRate))
 			progress.start(func() int { return streamer.Position() })
 			defer progress.stop()
 
 			done := make(chan bool)
+			defer startPlayback(ctx, ProviderOpenAI)()
 			speaker.Play(beep.Seq(playback, beep.Callback(func() {
 				done <- true
 			})))
 
-			logFields = []any{"text", text, "voice", voice, "model", model, "speed", speed}
+			logFields = []any{"text", logText(text), "voice", voice, "model", model, "speed", speed}
 			if instructions != "" {
 				logFields = append(logFields, "instructions", instructions)
 			}
@@ -1193,7 +1672,7 @@ Designed to be used with the MCP (Model Context Protocol).`,
 			select {
 			case <-done:
 				log.Debug("OpenAI TTS audio playback completed normally")
-				return textResult(formatSaveResult(text, savedPath, true)), nil, nil
+				return textResult(formatSaveResult(text, savedPath, true, captionPaths...)), nil, nil
 			case <-ctx.Done():
 				log.Debug("Context cancelled, stopping OpenAI TTS audio playback")
 				speaker.Clear()
@@ -1204,10 +1683,9 @@ Designed to be used with the MCP (Model Context Protocol).`,
 
 		// Add interactive TTS tool that uses elicitation to choose provider
 		ttsTool := &mcp.Tool{
-			Name:  "tts",
-			Title: "Interactive TTS",
-			Description: "Selects a TTS provider and voice settings interactively, " +
-				"then returns a recommendation to call the chosen provider tool.",
+			Name:        "tts",
+			Title:       "Interactive TTS",
+			Description: ttsToolDescription(),
 			InputSchema: buildTTSSchema(),
 			Annotations: &mcp.ToolAnnotations{
 				Title:          "Interactive Text-to-Speech",
@@ -1220,10 +1698,21 @@ Designed to be used with the MCP (Model Context Protocol).`,
 			req *mcp.CallToolRequest,
 			input TTSParams,
 		) (*mcp.CallToolResult, any, error) {
-			text := input.Text
+			text, refused := redactText("text", input.Text)
+			if refused != nil {
+				return refused, nil, nil
+			}
 			if text == "" {
 				return errorResult("Error: Empty text provided"), nil, nil
 			}
+			profile, err := callProfile(input.Profile)
+			if err != nil {
+				return errorResult(fmt.Sprintf("Error: %v", err)), nil, nil
+			}
+			if _, err := categoryFor(input.Category, profile); err != nil {
+				return errorResult(fmt.Sprintf("Error: %v", err)), nil, nil
+			}
+			call := TTSParams{Text: text, Profile: input.Profile, Category: input.Category}
 
 			providers := availableProviders()
 			if len(providers) == 0 {
@@ -1232,8 +1721,11 @@ Designed to be used with the MCP (Model Context Protocol).`,
 
 			if !canElicit(req) {
 				p := providers[0]
+				if ttsMode == TTSModeSpeak {
+					return dispatchSpeech(ctx, req, p.ID, call, nil)
+				}
 				return textResult(buildProviderRecommendation(
-					p.ID, p.Name, providerRecommendationArgs(p.ID, text, nil),
+					p.ID, p.Name, ttsRecommendationArgs(p.ID, call, withProjectVoice(ctx, req, p.ID, profile, input.Category, nil)),
 				)), nil, nil
 			}
 
@@ -1259,9 +1751,10 @@ Designed to be used with the MCP (Model Context Protocol).`,
 
 			var settingsContent map[string]any
 			if settingsSchema := settingsSchemaForProvider(provider.ID); settingsSchema != nil {
-				content, result, stop := maybeElicitContent(
+				content, result, stop := elicitSettings(
 					ctx,
 					req,
+					provider.ID,
 					"elicit TTS voice settings",
 					"Configure voice settings (or accept defaults):",
 					settingsSchema,
@@ -1272,18 +1765,79 @@ Designed to be used with the MCP (Model Context Protocol).`,
 				settingsContent = content
 			}
 
+			if ttsMode == TTSModeSpeak {
+				return dispatchSpeech(ctx, req, provider.ID, call, settingsContent)
+			}
 			return textResult(buildProviderRecommendation(
 				provider.ID,
 				provider.Name,
-				providerRecommendationArgs(provider.ID, text, settingsContent),
+				ttsRecommendationArgs(provider.ID, call, withProjectVoice(ctx, req, provider.ID, profile, input.Category, settingsContent)),
 			)), nil, nil
 		})
 
+		// Add the usage tool reporting rate limits and remaining budgets
+		usageTool := &mcp.Tool{
+			Name:        "tts_usage",
+			Title:       "TTS Usage",
+			Description: "Reports each provider's remaining character budget for today and this month, and its rate limits",
+			InputSchema: buildUsageSchema(),
+			Annotations: &mcp.ToolAnnotations{
+				Title:        "TTS Usage and Budgets",
+				ReadOnlyHint: true,
+			},
+		}
+		mcp.AddTool(s, usageTool, func(ctx context.Context, req *mcp.CallToolRequest, input UsageParams) (*mcp.CallToolResult, any, error) {
+			profile, err := callProfile(input.Profile)
+			if err != nil {
+				return errorResult(fmt.Sprintf("Error: %v", err)), nil, nil
+			}
+			return textResult(usageReport(profile)), nil, nil
+		})
+
+		// Add the tool that forgets settings users asked to remember
+		mcp.AddTool(s, &mcp.Tool{
+			Name:        "tts_reset_preferences",
+			Title:       "Reset TTS Preferences",
+			Description: "Forgets the voice settings the user asked to remember, so the next speech call asks for them again",
+			Annotations: &mcp.ToolAnnotations{
+				Title:          "Reset Remembered TTS Settings",
+				IdempotentHint: true,
+			},
+		}, resetPreferences)
+
+		// Expose the usage ledger summary as a resource
+		s.AddResource(&mcp.Resource{
+			URI:         usageResourceURI,
+			Name:        "usage",
+			Title:       "TTS Usage and Cost",
+			Description: "Speech usage and estimated cost over the last 30 days, by provider, project, client and day",
+			MIMEType:    "application/json",
+		}, readUsageResource)
+
 		log.Info("Starting MCP server", "name", "mcp-tts", "version", Version)
 		// Start the server using stdin/stdout
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
+		if metricsAddr != "" {
+			if err := serveMetrics(ctx, metricsAddr); err != nil {
+				return err
+			}
+		}
+
+		// Export traces when the OTEL_* environment variables ask for it
+		shutdownTracing, err := setupTracing(ctx)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdownTracing(flushCtx); err != nil {
+				log.Warn("Failed to flush traces", "error", err)
+			}
+		}()
+
 		if err := ctrlc.Default.Run(ctx, func() error {
 			if err := s.Run(ctx, &mcp.StdioTransport{}); err != nil {
 				return fmt.Errorf("failed to serve MCP: %v", err)
@@ -1303,8 +1857,10 @@ Designed to be used with the MCP (Model Context Protocol).`,
 
 func safeLog(message string, req *http.Request) {
 	reqCopy := req.Clone(context.Background())
-	if _, exists := reqCopy.Header["Xi-Api-Key"]; exists {
-		reqCopy.Header["Xi-Api-Key"] = []string{"******"} // Mask password
+	for _, header := range maskedHeaders {
+		if _, exists := reqCopy.Header[header]; exists {
+			reqCopy.Header[header] = []string{"******"} // Mask credentials
+		}
 	}
 	log.With(reqCopy).Debug(message)
 }
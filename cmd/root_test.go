@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -33,6 +34,15 @@ func intPtr(i int) *int {
 	return &i
 }
 
+// writeTestFile writes content to dir/name, creating dir, and returns the path.
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	fpath := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(fpath, []byte(content), 0644))
+	return fpath
+}
+
 func float64Ptr(f float64) *float64 {
 	return &f
 }
@@ -0,0 +1,215 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/log"
+	"github.com/gopxl/beep/v2"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// DefaultMaxChars caps the text of a single call, so runaway text is refused
+// before it is spoken. Providers may accept less: OpenAI speech input is
+// capped at 4096 characters.
+const DefaultMaxChars = 5000
+
+var (
+	// Input limits, 0 for none
+	maxChars          int
+	maxAudioDuration  time.Duration
+	maxCallsPerMinute int
+	// Phrases refused in spoken text, and exceptions to them
+	denyPhrases  []string
+	allowPhrases []string
+
+	activePhraseFilter = &phraseFilter{}
+	// sessionCallLimiter throttles speech calls per MCP session
+	sessionCallLimiter = newRateLimiter()
+)
+
+// phraseFilter refuses text containing a denied phrase. Allowed phrases are
+// exceptions: a denied phrase inside an allowed one is fine, e.g. allowing
+// "kill the process" while denying "kill". Matching ignores case.
+type phraseFilter struct {
+	deny  []string
+	allow []string
+}
+
+func newPhraseFilter(deny, allow []string) *phraseFilter {
+	f := &phraseFilter{}
+	for _, p := range deny {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			f.deny = append(f.deny, p)
+		}
+	}
+	for _, p := range allow {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			f.allow = append(f.allow, p)
+		}
+	}
+	return f
+}
+
+// denied returns the first denied phrase in text, or "".
+func (f *phraseFilter) denied(text string) string {
+	if f == nil || len(f.deny) == 0 {
+		return ""
+	}
+	text = strings.ToLower(text)
+	for _, p := range f.allow {
+		text = strings.ReplaceAll(text, p, "\x00")
+	}
+	for _, p := range f.deny {
+		if strings.Contains(text, p) {
+			return p
+		}
+	}
+	return ""
+}
+
+// takeCall takes one call from a session's per-minute bucket and returns how
+// long to wait when the bucket is empty.
+func (r *rateLimiter) takeCall(session *mcp.ServerSession, perMinute int) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b := r.bucket(session, perMinute, r.now())
+	if wait := b.wait(1); wait > 0 {
+		return wait
+	}
+	b.take(1)
+	return 0
+}
+
+// forgetSession drops a session's call bucket.
+func (r *rateLimiter) forgetSession(session *mcp.ServerSession) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.buckets, session)
+}
+
+// forgetCallLimitOnClose drops a session's call bucket once it ends.
+func forgetCallLimitOnClose(_ context.Context, req *mcp.InitializedRequest) {
+	limiter := sessionCallLimiter
+	go func() {
+		req.Session.Wait()
+		limiter.forgetSession(req.Session)
+	}()
+}
+
+// callText returns the text argument of a tool call, if any.
+func callText(req *mcp.CallToolRequest) (string, bool) {
+	if req.Params == nil || len(req.Params.Arguments) == 0 {
+		return "", false
+	}
+	var args struct {
+		Text *string `json:"text"`
+	}
+	if err := json.Unmarshal(req.Params.Arguments, &args); err != nil || args.Text == nil {
+		return "", false
+	}
+	return *args.Text, true
+}
+
+// checkSafeguards applies the input limits to a speech call before any
+// provider sees it. It returns a non-nil result when the call is refused.
+func checkSafeguards(req *mcp.CallToolRequest) *mcp.CallToolResult {
+	text, ok := callText(req)
+	if !ok {
+		return nil
+	}
+	if n := utf8.RuneCountInString(text); maxChars > 0 && n > maxChars {
+		log.Warn("Text over the character limit", "tool", req.Params.Name, "chars", n, "limit", maxChars)
+		return errorResult(fmt.Sprintf("Error: text is %d characters, over the limit of %d; shorten or summarize it and try again", n, maxChars))
+	}
+	if phrase := activePhraseFilter.denied(text); phrase != "" {
+		log.Warn("Text contains a denied phrase", "tool", req.Params.Name)
+		return errorResult(fmt.Sprintf("Error: text contains the denied phrase %q; refusing to speak it", phrase))
+	}
+	if maxCallsPerMinute > 0 && req.Session != nil {
+		if wait := sessionCallLimiter.takeCall(req.Session, maxCallsPerMinute); wait > 0 {
+			log.Warn("Session call limit reached", "tool", req.Params.Name, "limit", maxCallsPerMinute)
+			return errorResult(fmt.Sprintf("Error: Too many speech calls: this session may make %d per minute, try again in %s",
+				maxCallsPerMinute, wait.Truncate(time.Second)+time.Second))
+		}
+	}
+	return nil
+}
+
+// safeguardMiddleware enforces the input limits on every tool call.
+func safeguardMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		if callReq, ok := req.(*mcp.CallToolRequest); ok && method == "tools/call" {
+			if result := checkSafeguards(callReq); result != nil {
+				return result, nil
+			}
+		}
+		return next(ctx, method, req)
+	}
+}
+
+// limitPlayback stops a stream once it has played --max-audio-duration.
+func limitPlayback(streamer beep.Streamer, sampleRate beep.SampleRate) beep.Streamer {
+	if maxAudioDuration <= 0 {
+		return streamer
+	}
+	return &playbackLimit{Streamer: streamer, remaining: sampleRate.N(maxAudioDuration)}
+}
+
+type playbackLimit struct {
+	beep.Streamer
+	remaining int
+	stopped   bool
+}
+
+func (p *playbackLimit) Stream(samples [][2]float64) (int, bool) {
+	if p.remaining <= 0 {
+		if !p.stopped {
+			p.stopped = true
+			log.Warn("Playback stopped at the audio duration limit", "limit", maxAudioDuration)
+		}
+		return 0, false
+	}
+	n, ok := p.Streamer.Stream(samples[:min(len(samples), p.remaining)])
+	p.remaining -= n
+	return n, ok
+}
+
+// textSchemaProperty describes a text argument, hinting the character limit
+// so models shorten long text themselves.
+func textSchemaProperty(description string) map[string]any {
+	property := map[string]any{
+		"type":        "string",
+		"description": description,
+	}
+	if maxChars > 0 {
+		property["maxLength"] = maxChars
+		property["description"] = fmt.Sprintf("%s (at most %d characters)", description, maxChars)
+	}
+	return property
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useTestSafeguards sets the input limits for a test.
+func useTestSafeguards(t *testing.T, chars, callsPerMinute int, deny, allow []string) {
+	t.Helper()
+	origChars, origCalls, origFilter, origLimiter := maxChars, maxCallsPerMinute, activePhraseFilter, sessionCallLimiter
+	t.Cleanup(func() {
+		maxChars, maxCallsPerMinute, activePhraseFilter, sessionCallLimiter = origChars, origCalls, origFilter, origLimiter
+	})
+	maxChars, maxCallsPerMinute = chars, callsPerMinute
+	activePhraseFilter = newPhraseFilter(deny, allow)
+	sessionCallLimiter = newRateLimiter()
+}
+
+func TestPhraseFilter(t *testing.T) {
+	f := newPhraseFilter([]string{"Kill", " drop table ", ""}, []string{"kill the process"})
+	assert.Equal(t, "kill", f.denied("I will KILL it"))
+	assert.Equal(t, "drop table", f.denied("then DROP TABLE users"))
+	assert.Empty(t, f.denied("Kill the process when the build hangs"), "allowed phrases are exceptions")
+	assert.Equal(t, "kill", f.denied("kill the process, then kill the shell"))
+	assert.Empty(t, f.denied("all good"))
+
+	var none *phraseFilter
+	assert.Empty(t, none.denied("kill"))
+}
+
+func callRequest(t *testing.T, session *mcp.ServerSession, args map[string]any) *mcp.CallToolRequest {
+	t.Helper()
+	raw, err := json.Marshal(args)
+	require.NoError(t, err)
+	return &mcp.CallToolRequest{Session: session, Params: &mcp.CallToolParamsRaw{Name: "openai_tts", Arguments: raw}}
+}
+
+func TestCheckSafeguards(t *testing.T) {
+	useTestSafeguards(t, 20, 0, []string{"password"}, nil)
+
+	assert.Nil(t, checkSafeguards(callRequest(t, nil, map[string]any{"text": "short and sweet"})))
+	assert.Nil(t, checkSafeguards(callRequest(t, nil, map[string]any{"profile": "quiet"})), "calls without text are not checked")
+	assert.Nil(t, checkSafeguards(callRequest(t, nil, map[string]any{"text": strings.Repeat("é", 20)})), "characters are counted as runes")
+
+	result := checkSafeguards(callRequest(t, nil, map[string]any{"text": strings.Repeat("a", 21)}))
+	require.NotNil(t, result)
+	assert.True(t, result.IsError)
+	assert.Equal(t, "Error: text is 21 characters, over the limit of 20; shorten or summarize it and try again", resultText(result))
+
+	result = checkSafeguards(callRequest(t, nil, map[string]any{"text": "the Password is"}))
+	require.NotNil(t, result)
+	assert.Equal(t, `Error: text contains the denied phrase "password"; refusing to speak it`, resultText(result))
+
+	t.Run("no character limit", func(t *testing.T) {
+		maxChars = 0
+		assert.Nil(t, checkSafeguards(callRequest(t, nil, map[string]any{"text": strings.Repeat("a", 100000)})))
+	})
+}
+
+func TestSafeguardMiddleware(t *testing.T) {
+	useTestSafeguards(t, 10, 2, nil, nil)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	sessionCallLimiter.now = func() time.Time { return now }
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "mcp-tts"}, &mcp.ServerOptions{InitializedHandler: forgetCallLimitOnClose})
+	server.AddReceivingMiddleware(safeguardMiddleware)
+	var spoken int
+	mcp.AddTool(server, &mcp.Tool{Name: "openai_tts"}, func(ctx context.Context, req *mcp.CallToolRequest, input TTSParams) (*mcp.CallToolResult, any, error) {
+		spoken++
+		return textResult("Speaking: " + input.Text), nil, nil
+	})
+	connect := func() *mcp.ClientSession {
+		clientTransport, serverTransport := mcp.NewInMemoryTransports()
+		_, err := server.Connect(context.Background(), serverTransport, nil)
+		require.NoError(t, err)
+		session, err := mcp.NewClient(&mcp.Implementation{Name: "test-client"}, nil).Connect(context.Background(), clientTransport, nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { session.Close() })
+		return session
+	}
+	speakText := func(session *mcp.ClientSession, text string) *mcp.CallToolResult {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: "openai_tts", Arguments: map[string]any{"text": text}})
+		require.NoError(t, err)
+		return result
+	}
+	speak := func(session *mcp.ClientSession) *mcp.CallToolResult {
+		return speakText(session, "hi")
+	}
+
+	first := connect()
+	assert.True(t, speakText(first, "far too long to speak").IsError)
+	assert.False(t, speak(first).IsError, "refused calls leave the allowance alone")
+	assert.False(t, speak(first).IsError)
+	refused := speak(first)
+	assert.True(t, refused.IsError)
+	assert.Equal(t, "Error: Too many speech calls: this session may make 2 per minute, try again in 31s", resultText(refused))
+	assert.Equal(t, 2, spoken, "refused calls never reach the handler")
+
+	second := connect()
+	assert.False(t, speak(second).IsError, "each session has its own limit")
+	now = now.Add(time.Minute)
+	assert.False(t, speak(first).IsError)
+
+	require.NoError(t, second.Close())
+	assert.Eventually(t, func() bool {
+		sessionCallLimiter.mu.Lock()
+		defer sessionCallLimiter.mu.Unlock()
+		return len(sessionCallLimiter.buckets) == 1
+	}, time.Second, 10*time.Millisecond, "closed sessions' buckets are dropped")
+}
+
+func TestLimitPlayback(t *testing.T) {
+	origDuration := maxAudioDuration
+	defer func() { maxAudioDuration = origDuration }()
+	const rate = beep.SampleRate(1000)
+	samples := func(streamer beep.Streamer) int {
+		var total int
+		buf := make([][2]float64, 64)
+		for {
+			n, ok := streamer.Stream(buf)
+			total += n
+			if !ok {
+				return total
+			}
+		}
+	}
+
+	maxAudioDuration = 0
+	assert.Equal(t, 3000, samples(limitPlayback(beep.Silence(3000), rate)))
+
+	maxAudioDuration = 2 * time.Second
+	assert.Equal(t, 2000, samples(limitPlayback(beep.Silence(3000), rate)), "playback stops at the limit")
+	assert.Equal(t, 500, samples(limitPlayback(beep.Silence(500), rate)), "shorter audio plays in full")
+}
+
+func TestTextSchemaMaxLength(t *testing.T) {
+	useTestSafeguards(t, 300, 0, nil, nil)
+	var schema struct {
+		Properties struct {
+			Text map[string]any `json:"text"`
+		} `json:"properties"`
+	}
+	for name, build := range map[string]func() json.RawMessage{
+		"say":        buildSayTTSSchema,
+		"elevenlabs": buildElevenLabsTTSSchema,
+		"google":     buildGoogleTTSSchema,
+		"openai":     buildOpenAITTSSchema,
+		"tts":        buildTTSSchema,
+	} {
+		require.NoError(t, json.Unmarshal(build(), &schema), name)
+		assert.Equal(t, 300.0, schema.Properties.Text["maxLength"], name)
+		assert.Contains(t, schema.Properties.Text["description"], "(at most 300 characters)", name)
+	}
+
+	maxChars = 0
+	assert.NotContains(t, textSchemaProperty("The text"), "maxLength")
+}
@@ -14,10 +14,8 @@ func buildSayTTSSchema() json.RawMessage {
 	schema := map[string]any{
 		"type": "object",
 		"properties": map[string]any{
-			"text": map[string]any{
-				"type":        "string",
-				"description": "The text to speak aloud",
-			},
+			"text":     textSchemaProperty("The text to speak aloud"),
+			"condense": condenseSchemaProperty(),
 			"rate": map[string]any{
 				"type":        "integer",
 				"description": "Speech rate in words per minute. RECOMMENDED: 200-250 for natural speech. Only increase to 275-300 if user explicitly requests faster speech. Do NOT set above 300 unless specifically asked. (default: 200)",
@@ -35,6 +33,15 @@ func buildSayTTSSchema() json.RawMessage {
 					"Evan (Enhanced)",
 				}, // NOTE: these need to be downloaded to be available
 			},
+			"format": map[string]any{
+				"type":        "string",
+				"description": "Saved audio format when audio saving is enabled (default: aiff)",
+				"enum":        providerSaveFormats[ProviderSay],
+			},
+			"captions": captionsSchemaProperty(),
+			"markup":   markupSchemaProperty(),
+			"profile":  profileSchemaProperty(),
+			"category": categorySchemaProperty(),
 		},
 		"required": []string{"text"},
 	}
@@ -50,10 +57,17 @@ func buildElevenLabsTTSSchema() json.RawMessage {
 	schema := map[string]any{
 		"type": "object",
 		"properties": map[string]any{
-			"text": map[string]any{
+			"text":     textSchemaProperty("The text to convert to speech using ElevenLabs API"),
+			"condense": condenseSchemaProperty(),
+			"format": map[string]any{
 				"type":        "string",
-				"description": "The text to convert to speech using ElevenLabs API",
+				"description": "Saved audio format when audio saving is enabled (default: mp3). pcm and opus require --no-play",
+				"enum":        providerSaveFormats[ProviderElevenLabs],
 			},
+			"captions": captionsSchemaProperty(),
+			"markup":   markupSchemaProperty(),
+			"profile":  profileSchemaProperty(),
+			"category": categorySchemaProperty(),
 		},
 		"required": []string{"text"},
 	}
@@ -68,10 +82,8 @@ func buildGoogleTTSSchema() json.RawMessage {
 	schema := map[string]any{
 		"type": "object",
 		"properties": map[string]any{
-			"text": map[string]any{
-				"type":        "string",
-				"description": "The text to convert to speech using Google TTS",
-			},
+			"text":     textSchemaProperty("The text to convert to speech using Google TTS"),
+			"condense": condenseSchemaProperty(),
 			"voice": map[string]any{
 				"type":        "string",
 				"description": "Voice name to use (default: 'Kore')",
@@ -82,6 +94,15 @@ func buildGoogleTTSSchema() json.RawMessage {
 				"description": "TTS model to use (default: 'gemini-3.1-flash-tts-preview')",
 				"enum":        GoogleModels,
 			},
+			"format": map[string]any{
+				"type":        "string",
+				"description": "Saved audio format when audio saving is enabled (default: wav). pcm requires --no-play",
+				"enum":        providerSaveFormats[ProviderGoogle],
+			},
+			"captions": captionsSchemaProperty(),
+			"markup":   markupSchemaProperty(),
+			"profile":  profileSchemaProperty(),
+			"category": categorySchemaProperty(),
 		},
 		"required": []string{"text"},
 	}
@@ -96,10 +117,8 @@ func buildOpenAITTSSchema() json.RawMessage {
 	schema := map[string]any{
 		"type": "object",
 		"properties": map[string]any{
-			"text": map[string]any{
-				"type":        "string",
-				"description": "The text to convert to speech using OpenAI TTS",
-			},
+			"text":     textSchemaProperty("The text to convert to speech using OpenAI TTS"),
+			"condense": condenseSchemaProperty(),
 			"voice": map[string]any{
 				"type":        "string",
 				"description": "Voice to use (alloy, ash, ballad, coral, echo, fable, nova, onyx, sage, shimmer, verse; default: 'alloy')",
@@ -120,6 +139,15 @@ func buildOpenAITTSSchema() json.RawMessage {
 				"type":        "string",
 				"description": "Instructions for voice modulation and style",
 			},
+			"format": map[string]any{
+				"type":        "string",
+				"description": "Saved audio format when audio saving is enabled (default: mp3). opus, flac, aac and pcm require --no-play",
+				"enum":        providerSaveFormats[ProviderOpenAI],
+			},
+			"captions": captionsSchemaProperty(),
+			"markup":   markupSchemaProperty(),
+			"profile":  profileSchemaProperty(),
+			"category": categorySchemaProperty(),
 		},
 		"required": []string{"text"},
 	}
@@ -129,3 +157,56 @@ func buildOpenAITTSSchema() json.RawMessage {
 	}
 	return data
 }
+
+func buildUsageSchema() json.RawMessage {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"profile": profileSchemaProperty(),
+		},
+	}
+	data, err := json.Marshal(schema)
+	if err != nil {
+		panic(fmt.Sprintf("failed to marshal tts_usage schema: %v", err))
+	}
+	return data
+}
+
+// profileSchemaProperty describes the profile argument, listing the profiles
+// defined in the config files.
+func profileSchemaProperty() map[string]any {
+	property := map[string]any{
+		"type":        "string",
+		"description": "Named config profile supplying default voice and output settings. Leave unset unless the user asks for a profile.",
+	}
+	if names := activeConfig.ProfileNames(); len(names) > 0 {
+		property["enum"] = names
+	}
+	return property
+}
+
+// captionsSchemaProperty describes the captions argument.
+func captionsSchemaProperty() map[string]any {
+	return map[string]any{
+		"type":        "boolean",
+		"description": "Write .srt and .vtt captions next to the saved audio (requires audio saving; default: --captions)",
+	}
+}
+
+// markupSchemaProperty describes the markup argument.
+func markupSchemaProperty() map[string]any {
+	return map[string]any{
+		"type":        "string",
+		"description": "Set to 'ssml' to interpret <break time=\"500ms\"/>, <emphasis>, <say-as interpret-as=\"characters\"> and <prosody rate=\"slow\"> tags; unsupported features degrade to plain speech (default: none)",
+		"enum":        MarkupModes,
+	}
+}
+
+// categorySchemaProperty describes the category argument.
+func categorySchemaProperty() map[string]any {
+	return map[string]any{
+		"type":        "string",
+		"description": "Kind of message: info, success, warning, error, summary or question. The server picks the voice, speaking style, chime and queue priority for it; leave voice unset to use the project's voice for this kind of message",
+		"enum":        Categories,
+	}
+}
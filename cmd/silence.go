@@ -0,0 +1,184 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+)
+
+// Default silence detection settings. The threshold is a linear amplitude
+// (0.01 ≈ -40 dBFS); runs of silence shorter than the minimum are kept.
+const (
+	DefaultSilenceThreshold   = 0.01
+	DefaultSilenceMinDuration = 150 * time.Millisecond
+	DefaultUtteranceGap       = 250 * time.Millisecond
+)
+
+// isSilent reports whether both channels of a sample are below threshold.
+func isSilent(sample [2]float64, threshold float64) bool {
+	return math.Abs(sample[0]) < threshold && math.Abs(sample[1]) < threshold
+}
+
+// silenceTrimmer wraps a streamer and drops leading and trailing silence
+// while streaming. Silent runs are held back until the next audible sample
+// (emitted as-is) or the end of the stream (dropped), so only the tail of
+// the stream needs to be buffered.
+type silenceTrimmer struct {
+	s         beep.Streamer
+	threshold float64
+	minRun    int
+	started   bool
+	done      bool
+	pending   [][2]float64
+	ready     [][2]float64
+	buf       [][2]float64
+}
+
+// trimSilence returns a streamer that skips leading and trailing silence
+// longer than minDuration at the given sample rate.
+func trimSilence(s beep.Streamer, sr beep.SampleRate, threshold float64, minDuration time.Duration) beep.Streamer {
+	return &silenceTrimmer{
+		s:         s,
+		threshold: threshold,
+		minRun:    sr.N(minDuration),
+		buf:       make([][2]float64, 512),
+	}
+}
+
+func (t *silenceTrimmer) Stream(samples [][2]float64) (n int, ok bool) {
+	for len(t.ready) < len(samples) && !t.done {
+		sn, sok := t.s.Stream(t.buf)
+		for _, sample := range t.buf[:sn] {
+			if isSilent(sample, t.threshold) {
+				t.pending = append(t.pending, sample)
+				continue
+			}
+			// Short silences are part of the speech; only long leading runs are dropped.
+			if t.started || len(t.pending) < t.minRun {
+				t.ready = append(t.ready, t.pending...)
+			}
+			t.pending = t.pending[:0]
+			t.started = true
+			t.ready = append(t.ready, sample)
+		}
+		if !sok {
+			t.done = true
+			if t.started && len(t.pending) < t.minRun {
+				t.ready = append(t.ready, t.pending...)
+			}
+			t.pending = nil
+		}
+	}
+
+	if len(t.ready) == 0 {
+		return 0, false
+	}
+	n = copy(samples, t.ready)
+	t.ready = t.ready[n:]
+	return n, true
+}
+
+func (t *silenceTrimmer) Err() error {
+	return t.s.Err()
+}
+
+// trimPCMSilence trims leading and trailing silence from 16-bit little-endian
+// mono PCM. Silent runs shorter than minDuration are kept.
+func trimPCMSilence(data []byte, sr beep.SampleRate, threshold float64, minDuration time.Duration) []byte {
+	total := len(data) / 2
+	if total == 0 {
+		return data
+	}
+	limit := threshold * 32768
+	start, end := silenceBounds(total, sr.N(minDuration), func(i int) bool {
+		sample := int16(data[2*i]) | int16(data[2*i+1])<<8
+		return math.Abs(float64(sample)) >= limit
+	})
+	return data[2*start : 2*end]
+}
+
+// silenceBounds returns the [start, end) range of samples that trimming
+// keeps: leading and trailing silent runs of at least minRun samples are
+// dropped, and an entirely silent stream keeps nothing.
+func silenceBounds(total, minRun int, audible func(i int) bool) (start, end int) {
+	first := 0
+	for first < total && !audible(first) {
+		first++
+	}
+	if first == total {
+		// Entirely silent: nothing worth playing or saving
+		return 0, 0
+	}
+	last := total - 1
+	for last > first && !audible(last) {
+		last--
+	}
+
+	start, end = 0, total
+	if first >= minRun {
+		start = first
+	}
+	if total-1-last >= minRun {
+		end = last + 1
+	}
+	return start, end
+}
+
+// maybeTrimSilence applies trimSilence when silence trimming is enabled.
+func maybeTrimSilence(s beep.Streamer, sr beep.SampleRate) beep.Streamer {
+	if !trimSilenceEnabled {
+		return s
+	}
+	return trimSilence(s, sr, silenceThreshold, silenceMinDuration)
+}
+
+// maybeTrimPCMSilence applies trimPCMSilence when silence trimming is enabled.
+func maybeTrimPCMSilence(data []byte, sr beep.SampleRate) []byte {
+	if !trimSilenceEnabled {
+		return data
+	}
+	return trimPCMSilence(data, sr, silenceThreshold, silenceMinDuration)
+}
+
+// waitForUtteranceGap blocks until gap has elapsed since the previous
+// utterance finished, so queued announcements do not run together.
+func waitForUtteranceGap(ctx context.Context, lastEnd time.Time, gap time.Duration) error {
+	if lastEnd.IsZero() || gap <= 0 {
+		return nil
+	}
+	wait := gap - time.Since(lastEnd)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/gopxl/beep/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSampleRate = beep.SampleRate(24000)
+
+// synthPCM builds 16-bit mono PCM: leading silence, a constant tone, trailing silence.
+func synthPCM(lead, tone, tail time.Duration) []byte {
+	n := func(d time.Duration) int { return testSampleRate.N(d) }
+	data := make([]byte, 2*(n(lead)+n(tone)+n(tail)))
+	for i := n(lead); i < n(lead)+n(tone); i++ {
+		binary.LittleEndian.PutUint16(data[2*i:], uint16(int16(8000)))
+	}
+	return data
+}
+
+func streamAll(s beep.Streamer) [][2]float64 {
+	var out [][2]float64
+	buf := make([][2]float64, 100)
+	for {
+		n, ok := s.Stream(buf)
+		out = append(out, buf[:n]...)
+		if !ok {
+			return out
+		}
+	}
+}
+
+func TestTrimSilenceStreamer(t *testing.T) {
+	tests := []struct {
+		name       string
+		lead, tail time.Duration
+		want       time.Duration
+	}{
+		{"trims long head and tail", 500 * time.Millisecond, 800 * time.Millisecond, 200 * time.Millisecond},
+		{"keeps short head", 100 * time.Millisecond, 500 * time.Millisecond, 300 * time.Millisecond},
+		{"keeps short tail", 400 * time.Millisecond, 50 * time.Millisecond, 250 * time.Millisecond},
+		{"no silence", 0, 0, 200 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pcm := &PCMStream{data: synthPCM(tt.lead, 200*time.Millisecond, tt.tail), sampleRate: testSampleRate}
+			out := streamAll(trimSilence(pcm, testSampleRate, DefaultSilenceThreshold, DefaultSilenceMinDuration))
+			assert.Equal(t, testSampleRate.N(tt.want), len(out))
+		})
+	}
+}
+
+func TestTrimSilenceKeepsInteriorPauses(t *testing.T) {
+	speech := synthPCM(0, 100*time.Millisecond, 0)
+	pause := synthPCM(400*time.Millisecond, 0, 0)
+	data := append(append(append([]byte{}, speech...), pause...), speech...)
+
+	pcm := &PCMStream{data: data, sampleRate: testSampleRate}
+	out := streamAll(trimSilence(pcm, testSampleRate, DefaultSilenceThreshold, DefaultSilenceMinDuration))
+	assert.Equal(t, len(data)/2, len(out))
+}
+
+func TestTrimSilenceAllSilent(t *testing.T) {
+	pcm := &PCMStream{data: synthPCM(time.Second, 0, 0), sampleRate: testSampleRate}
+	out := streamAll(trimSilence(pcm, testSampleRate, DefaultSilenceThreshold, DefaultSilenceMinDuration))
+	assert.Empty(t, out)
+}
+
+func TestTrimPCMSilence(t *testing.T) {
+	data := synthPCM(600*time.Millisecond, 200*time.Millisecond, 300*time.Millisecond)
+	trimmed := trimPCMSilence(data, testSampleRate, DefaultSilenceThreshold, DefaultSilenceMinDuration)
+	assert.Equal(t, testSampleRate.N(200*time.Millisecond), len(trimmed)/2)
+
+	short := synthPCM(50*time.Millisecond, 200*time.Millisecond, 50*time.Millisecond)
+	assert.Equal(t, short, trimPCMSilence(short, testSampleRate, DefaultSilenceThreshold, DefaultSilenceMinDuration))
+
+	assert.Empty(t, trimPCMSilence(synthPCM(time.Second, 0, 0), testSampleRate, DefaultSilenceThreshold, DefaultSilenceMinDuration))
+}
+
+func TestMaybeTrimSilenceDisabled(t *testing.T) {
+	orig := trimSilenceEnabled
+	defer func() { trimSilenceEnabled = orig }()
+	trimSilenceEnabled = false
+
+	data := synthPCM(500*time.Millisecond, 100*time.Millisecond, 0)
+	assert.Equal(t, data, maybeTrimPCMSilence(data, testSampleRate))
+
+	pcm := &PCMStream{data: data, sampleRate: testSampleRate}
+	assert.Same(t, pcm, maybeTrimSilence(pcm, testSampleRate))
+}
+
+func TestWaitForUtteranceGap(t *testing.T) {
+	t.Run("no previous utterance", func(t *testing.T) {
+		start := time.Now()
+		require.NoError(t, waitForUtteranceGap(context.Background(), time.Time{}, time.Second))
+		assert.Less(t, time.Since(start), 50*time.Millisecond)
+	})
+
+	t.Run("waits out the remaining gap", func(t *testing.T) {
+		start := time.Now()
+		require.NoError(t, waitForUtteranceGap(context.Background(), start, 100*time.Millisecond))
+		assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	})
+
+	t.Run("gap already elapsed", func(t *testing.T) {
+		start := time.Now()
+		require.NoError(t, waitForUtteranceGap(context.Background(), start.Add(-time.Second), 100*time.Millisecond))
+		assert.Less(t, time.Since(start), 50*time.Millisecond)
+	})
+
+	t.Run("cancellation interrupts the wait", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := waitForUtteranceGap(ctx, time.Now(), time.Second)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,105 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// speechQueue serializes speech within this process. When several calls
+// wait, the highest priority goes next; equal priorities keep arrival order.
+type speechQueue struct {
+	mu      sync.Mutex
+	held    bool
+	seq     uint64
+	waiters []*speechWaiter
+}
+
+type speechWaiter struct {
+	priority int
+	seq      uint64
+	ready    chan struct{}
+}
+
+// localSpeechQueue guards local speech; the global lock directory guards other processes.
+var localSpeechQueue = &speechQueue{}
+
+// acquire waits for the turn of a call with the given priority.
+func (q *speechQueue) acquire(ctx context.Context, priority int) error {
+	q.mu.Lock()
+	if !q.held {
+		q.held = true
+		q.mu.Unlock()
+		return nil
+	}
+	q.seq++
+	w := &speechWaiter{priority: priority, seq: q.seq, ready: make(chan struct{})}
+	q.waiters = append(q.waiters, w)
+	q.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if i := slices.Index(q.waiters, w); i >= 0 {
+			q.waiters = slices.Delete(q.waiters, i, i+1)
+			return ctx.Err()
+		}
+		// The turn was handed over while cancelling; pass it on.
+		q.releaseLocked()
+		return ctx.Err()
+	}
+}
+
+// release hands the turn to the next waiter, if any.
+func (q *speechQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.releaseLocked()
+}
+
+func (q *speechQueue) releaseLocked() {
+	if len(q.waiters) == 0 {
+		q.held = false
+		return
+	}
+	next := 0
+	for i, w := range q.waiters {
+		if best := q.waiters[next]; w.priority > best.priority || (w.priority == best.priority && w.seq < best.seq) {
+			next = i
+		}
+	}
+	w := q.waiters[next]
+	q.waiters = slices.Delete(q.waiters, next, next+1)
+	close(w.ready)
+}
+
+// depth returns the number of calls waiting for their turn.
+func (q *speechQueue) depth() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.waiters)
+}
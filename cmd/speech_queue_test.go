@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitForDepth waits until n calls are queued.
+func waitForDepth(t *testing.T, q *speechQueue, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool { return q.depth() == n }, time.Second, time.Millisecond)
+}
+
+func TestSpeechQueuePriority(t *testing.T) {
+	q := &speechQueue{}
+	require.NoError(t, q.acquire(context.Background(), 1))
+
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	enqueue := func(name string, priority int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, q.acquire(context.Background(), priority))
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			q.release()
+		}()
+	}
+
+	enqueue("normal-1", 1)
+	waitForDepth(t, q, 1)
+	enqueue("low", 0)
+	waitForDepth(t, q, 2)
+	enqueue("high", 2)
+	waitForDepth(t, q, 3)
+	enqueue("normal-2", 1)
+	waitForDepth(t, q, 4)
+
+	q.release()
+	wg.Wait()
+	assert.Equal(t, []string{"high", "normal-1", "normal-2", "low"}, order)
+	assert.False(t, q.held)
+}
+
+func TestSpeechQueueCancellation(t *testing.T) {
+	q := &speechQueue{}
+	require.NoError(t, q.acquire(context.Background(), 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() { errc <- q.acquire(ctx, 2) }()
+	waitForDepth(t, q, 1)
+	cancel()
+	assert.ErrorIs(t, <-errc, context.Canceled)
+	assert.Zero(t, q.depth())
+
+	q.release()
+	assert.False(t, q.held, "a cancelled waiter does not keep the queue")
+	require.NoError(t, q.acquire(context.Background(), 1))
+}
@@ -0,0 +1,115 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// preparedSpeech holds the settings every provider resolves the same way
+// before it synthesizes anything.
+type preparedSpeech struct {
+	Provider string
+	Text     string        // text without markup, for display, filenames and captions
+	Markup   *speechMarkup // nil for plain text
+	Profile  string
+	Category categorySettings
+	SaveAs   string // saved audio format, "" for the provider's native format
+	Captions *bool
+	limits   *limitReservation
+}
+
+// prepareSpeech resolves the profile, category, saved format and markup of a
+// call, reserves its share of the provider's rate limits and budgets and asks
+// the user to confirm it if needed. When the call ends here it returns the
+// result to send instead: an argument error, a refusal, the answer of a
+// fallback provider or a declined confirmation. model is the requested model,
+// if any. Callers release the prepared speech when they are done and report
+// audio with synthesized.
+func prepareSpeech(ctx context.Context, req *mcp.CallToolRequest, providerID string, model *string, input TTSParams) (*preparedSpeech, *mcp.CallToolResult) {
+	profile, err := callProfile(input.Profile)
+	if err != nil {
+		return nil, errorResult(fmt.Sprintf("Error: %v", err))
+	}
+	applyProfileOutput(profile, &input.Format, &input.Captions)
+	category, err := categoryFor(input.Category, profile)
+	if err != nil {
+		return nil, errorResult(fmt.Sprintf("Error: %v", err))
+	}
+
+	saveAs, err := saveFormatFor(providerID, input.Format)
+	if err != nil {
+		return nil, errorResult(fmt.Sprintf("Error: %v", err))
+	}
+
+	text := input.Text
+	markup, err := parseMarkup(text, input.Markup)
+	if err != nil {
+		return nil, errorResult(fmt.Sprintf("Error: %v", err))
+	}
+	if markup != nil {
+		// Display, filenames and captions use the text without markup
+		text = markup.Text()
+		if text == "" {
+			return nil, errorResult("Error: Empty text provided")
+		}
+	}
+
+	// Rate limits and budgets may refuse the call or hand it to a fallback provider
+	reservation, result := admitSpeech(ctx, req, providerID, profile, input)
+	if result != nil {
+		return nil, result
+	}
+
+	// Long, expensive or sensitive calls wait for the user's go-ahead
+	if result, stop := confirmSpeech(ctx, req, providerID, model, profile, category, text); stop {
+		reservation.release()
+		return nil, result
+	}
+
+	return &preparedSpeech{
+		Provider: providerID,
+		Text:     text,
+		Markup:   markup,
+		Profile:  profile,
+		Category: category,
+		SaveAs:   saveAs,
+		Captions: input.Captions,
+		limits:   reservation,
+	}, nil
+}
+
+// synthesized records a call the provider produced audio for in the usage
+// ledger and charges it to the provider's budgets.
+func (s *preparedSpeech) synthesized(ctx context.Context, req *mcp.CallToolRequest, model string, audio time.Duration) {
+	s.limits.commit(ctx)
+	recordSynthesis(ctx, req, s.Provider, model, s.Profile, s.Text, audio)
+}
+
+// release gives back the limits reserved for a call that ended without audio.
+func (s *preparedSpeech) release() {
+	s.limits.release()
+}
@@ -0,0 +1,66 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import "strings"
+
+// The text sent to a provider is built in stages: lexicon substitutions are
+// applied to the plain text (or each markup segment), then markup is
+// translated into the provider's own syntax.
+
+// sayInput prepares text for the macOS say command.
+func sayInput(text string, markup *speechMarkup, rate int) string {
+	if markup == nil {
+		return activeLexicon.apply(text, respell)
+	}
+	return markup.withLexicon(activeLexicon, respell).renderSay(rate)
+}
+
+// elevenLabsInput prepares text for an ElevenLabs model.
+func elevenLabsInput(text string, markup *speechMarkup, modelID string) string {
+	pronounce := elevenLabsPronunciation(modelID)
+	if markup == nil {
+		return activeLexicon.apply(text, pronounce)
+	}
+	return markup.withLexicon(activeLexicon, pronounce).renderElevenLabs(modelID)
+}
+
+// googleInput prepares a Gemini TTS prompt. style is a natural-language
+// direction such as a category's speaking style.
+func googleInput(text string, markup *speechMarkup, style string) string {
+	hints := strings.TrimSpace(style + " " + activeLexicon.ipaHints(text))
+	if markup == nil {
+		return googleStylePrompt(hints, activeLexicon.apply(text, respell))
+	}
+	return markup.withLexicon(activeLexicon, respell).renderGoogle(hints)
+}
+
+// openAIInput prepares OpenAI input text and style instructions to append
+// to the request's instructions.
+func openAIInput(text string, markup *speechMarkup) (input, instructions string) {
+	hints := activeLexicon.ipaHints(text)
+	if markup == nil {
+		return activeLexicon.apply(text, respell), hints
+	}
+	input, markupHints := markup.withLexicon(activeLexicon, respell).renderOpenAI()
+	return input, strings.TrimSpace(markupHints + " " + hints)
+}
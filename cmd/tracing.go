@@ -0,0 +1,182 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/blacktop/mcp-tts"
+
+// tracingEnabled reports whether the standard OTEL_* environment variables
+// ask for traces to be exported over OTLP/HTTP. Settings meant for another
+// exporter or protocol leave tracing off with a warning.
+func tracingEnabled() bool {
+	if strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") {
+		return false
+	}
+	switch exporter := os.Getenv("OTEL_TRACES_EXPORTER"); exporter {
+	case "none":
+		return false
+	case "":
+		if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+			return false
+		}
+	case "otlp":
+	default:
+		log.Warn("Unsupported OTEL_TRACES_EXPORTER, tracing disabled", "exporter", exporter, "supported", "otlp")
+		return false
+	}
+	if protocol := otlpProtocol(); protocol != "http/protobuf" {
+		log.Warn("Unsupported OTLP protocol, tracing disabled", "protocol", protocol, "supported", "http/protobuf")
+		return false
+	}
+	return true
+}
+
+// otlpProtocol returns the OTLP protocol selected for traces.
+func otlpProtocol() string {
+	for _, env := range []string{"OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "OTEL_EXPORTER_OTLP_PROTOCOL"} {
+		if v := os.Getenv(env); v != "" {
+			return v
+		}
+	}
+	return "http/protobuf"
+}
+
+// setupTracing installs an OTLP trace exporter when tracing is enabled. The
+// exporter, sampler and batching read their settings from OTEL_* variables.
+// The returned function flushes pending spans.
+func setupTracing(ctx context.Context) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if !tracingEnabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			attribute.String("service.name", "mcp-tts"),
+			attribute.String("service.version", Version),
+		),
+		// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES win over the defaults
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTEL_RESOURCE_ATTRIBUTES: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	log.Debug("OpenTelemetry tracing enabled")
+	return provider.Shutdown, nil
+}
+
+// startSpan starts a span of the speech pipeline.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.GetTracerProvider().Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan ends a span, marking it failed when err is set.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// metaCarrier reads W3C trace context (traceparent, tracestate, baggage)
+// from a request's _meta.
+type metaCarrier mcp.Meta
+
+func (c metaCarrier) Get(key string) string {
+	v, _ := c[key].(string)
+	return v
+}
+
+func (c metaCarrier) Set(key, value string) { c[key] = value }
+
+func (c metaCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// traceContextFromMeta continues the client's trace when the tool call
+// carries trace context in _meta. Calls made within a traced call keep
+// their parent.
+func traceContextFromMeta(ctx context.Context, req *mcp.CallToolRequest) context.Context {
+	if trace.SpanContextFromContext(ctx).IsValid() || req == nil || req.Params == nil || req.Params.Meta == nil {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, metaCarrier(req.Params.Meta))
+}
+
+// startToolSpan starts the span covering a whole tool call.
+func startToolSpan(ctx context.Context, req *mcp.CallToolRequest, toolName string) (context.Context, trace.Span) {
+	ctx = traceContextFromMeta(ctx, req)
+	return otel.GetTracerProvider().Tracer(tracerName).Start(ctx, "tools/call "+toolName,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("mcp.method.name", "tools/call"),
+			attribute.String("gen_ai.tool.name", toolName),
+			attribute.String("tts.provider", providerKey(toolName)),
+		),
+	)
+}
+
+// endToolSpan ends a tool call span, marking error results as failed.
+func endToolSpan(span trace.Span, result *mcp.CallToolResult, err error) {
+	if err == nil && result != nil && result.IsError {
+		span.SetStatus(codes.Error, resultText(result))
+	}
+	endSpan(span, err)
+}
+
+// resultText returns the text of a tool result's first content block.
+func resultText(result *mcp.CallToolResult) string {
+	if len(result.Content) > 0 {
+		if text, ok := result.Content[0].(*mcp.TextContent); ok {
+			return text.Text
+		}
+	}
+	return ""
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// collectorStandIn receives OTLP/HTTP trace exports like a collector would.
+type collectorStandIn struct {
+	mu    sync.Mutex
+	spans []*tracepb.Span
+}
+
+func (c *collectorStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/traces" {
+		http.NotFound(w, r)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req collectortrace.ExportTraceServiceRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			c.spans = append(c.spans, ss.Spans...)
+		}
+	}
+	c.mu.Unlock()
+	data, _ := proto.Marshal(&collectortrace.ExportTraceServiceResponse{})
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.Write(data)
+}
+
+func (c *collectorStandIn) span(t *testing.T, name string) *tracepb.Span {
+	t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range c.spans {
+		if s.Name == name {
+			return s
+		}
+	}
+	require.Failf(t, "span not exported", "no %q span", name)
+	return nil
+}
+
+func TestTracingEnabled(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want bool
+	}{
+		{"off by default", nil, false},
+		{"endpoint", map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://localhost:4318"}, true},
+		{"traces endpoint", map[string]string{"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "http://localhost:4318/v1/traces"}, true},
+		{"otlp exporter", map[string]string{"OTEL_TRACES_EXPORTER": "otlp"}, true},
+		{"none exporter", map[string]string{"OTEL_TRACES_EXPORTER": "none", "OTEL_EXPORTER_OTLP_ENDPOINT": "http://localhost:4318"}, false},
+		{"unsupported exporter", map[string]string{"OTEL_TRACES_EXPORTER": "zipkin"}, false},
+		{"sdk disabled", map[string]string{"OTEL_SDK_DISABLED": "true", "OTEL_TRACES_EXPORTER": "otlp"}, false},
+		{"grpc protocol", map[string]string{"OTEL_TRACES_EXPORTER": "otlp", "OTEL_EXPORTER_OTLP_PROTOCOL": "grpc"}, false},
+		{"traces protocol wins", map[string]string{"OTEL_TRACES_EXPORTER": "otlp", "OTEL_EXPORTER_OTLP_PROTOCOL": "grpc", "OTEL_EXPORTER_OTLP_TRACES_PROTOCOL": "http/protobuf"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, env := range []string{"OTEL_SDK_DISABLED", "OTEL_TRACES_EXPORTER", "OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "OTEL_EXPORTER_OTLP_PROTOCOL", "OTEL_EXPORTER_OTLP_TRACES_PROTOCOL"} {
+				t.Setenv(env, tt.env[env])
+			}
+			assert.Equal(t, tt.want, tracingEnabled())
+		})
+	}
+}
+
+func TestTracingExport(t *testing.T) {
+	useTestMetrics(t)
+	collector := &collectorStandIn{}
+	srv := httptest.NewServer(collector)
+	defer srv.Close()
+	t.Setenv("OTEL_SDK_DISABLED", "")
+	t.Setenv("OTEL_TRACES_EXPORTER", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", srv.URL)
+	t.Setenv("OTEL_SERVICE_NAME", "")
+	origPropagator := otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(origPropagator)
+	})
+
+	ctx := context.Background()
+	shutdown, err := setupTracing(ctx)
+	require.NoError(t, err)
+
+	handler := instrumentSpeech(ProviderOpenAI, func(ctx context.Context, req *mcp.CallToolRequest, input TTSParams) (*mcp.CallToolResult, any, error) {
+		elicitForm(ctx, nil, "pick a voice", nil)
+		_, span := startSpan(ctx, "provider request")
+		endSpan(span, nil)
+		startPlayback(ctx, ProviderOpenAI)()
+		return errorResult("Error: speaker unavailable"), nil, nil
+	})
+	req := &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{
+		Name: "openai_tts",
+		Meta: mcp.Meta{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+	}}
+	_, _, err = handler(ctx, req, TTSParams{Text: "hello"})
+	require.NoError(t, err)
+	require.NoError(t, shutdown(ctx))
+
+	root := collector.span(t, "tools/call openai_tts")
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", hex.EncodeToString(root.TraceId), "the client's trace is continued")
+	assert.Equal(t, "00f067aa0ba902b7", hex.EncodeToString(root.ParentSpanId))
+	assert.Equal(t, tracepb.Span_SPAN_KIND_SERVER, root.Kind)
+	assert.Equal(t, tracepb.Status_STATUS_CODE_ERROR, root.Status.Code)
+	assert.Equal(t, "Error: speaker unavailable", root.Status.Message)
+
+	for _, name := range []string{"elicitation", "provider request", "playback"} {
+		span := collector.span(t, name)
+		assert.Equal(t, root.TraceId, span.TraceId, name)
+		assert.Equal(t, root.SpanId, span.ParentSpanId, name)
+	}
+	var outcome string
+	for _, attr := range collector.span(t, "elicitation").Attributes {
+		if attr.Key == "elicitation.outcome" {
+			outcome = attr.Value.GetStringValue()
+		}
+	}
+	assert.Equal(t, "unavailable", outcome)
+}
@@ -0,0 +1,399 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/log"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/spf13/cobra"
+)
+
+const (
+	usageLedgerFile = "usage.jsonl"
+	// UsageLedgerOff disables the usage ledger.
+	UsageLedgerOff = "off"
+	// usageResourceURI is the MCP resource exposing the usage summary.
+	usageResourceURI = "mcp-tts://usage"
+	// usageResourceWindow is how far back the MCP resource summarizes.
+	usageResourceWindow = 30 * 24 * time.Hour
+)
+
+// Usage report groupings.
+const (
+	UsageByProvider = "provider"
+	UsageByProject  = "project"
+	UsageByClient   = "client"
+	UsageByDay      = "day"
+)
+
+var UsageGroupings = []string{UsageByProvider, UsageByProject, UsageByClient, UsageByDay}
+
+// usageLedgerPath is the --usage-ledger flag: "" for the default file, "off" to disable.
+var usageLedgerPath string
+
+// modelPrice estimates what a provider charges. Providers bill by input
+// characters, by audio length, or both.
+type modelPrice struct {
+	PerMillionChars float64 `yaml:"per_million_chars,omitempty" json:"per_million_chars,omitempty"`
+	PerMinute       float64 `yaml:"per_minute,omitempty" json:"per_minute,omitempty"`
+}
+
+// cost returns the estimated price in US dollars.
+func (p modelPrice) cost(chars int, audio time.Duration) float64 {
+	return float64(chars)/1e6*p.PerMillionChars + audio.Minutes()*p.PerMinute
+}
+
+// builtinPrices are rough list prices in US dollars, keyed by provider and
+// model ("*" for any model). Override them with `prices` in the config file.
+var builtinPrices = map[string]map[string]modelPrice{
+	"elevenlabs": {"*": {PerMillionChars: 300}},
+	"google": {
+		"*":                          {PerMinute: 0.015},
+		"gemini-2.5-pro-preview-tts": {PerMinute: 0.03},
+	},
+	"openai": {
+		"*":        {PerMinute: 0.015},
+		"tts-1":    {PerMillionChars: 15},
+		"tts-1-hd": {PerMillionChars: 30},
+	},
+}
+
+// price returns the configured price for a provider's model. An exact model
+// match in any layer beats a "*" entry.
+func (c *appConfig) price(providerID, model, profile string) modelPrice {
+	key := providerKey(providerID)
+	layers := c.settingLayers(profile)
+	for _, m := range []string{model, "*"} {
+		for _, layer := range slices.Backward(layers) {
+			if p, ok := layer.File.Prices[key][m]; ok {
+				return p
+			}
+		}
+		if p, ok := builtinPrices[key][m]; ok {
+			return p
+		}
+	}
+	return modelPrice{}
+}
+
+// usageRecord is one line of the usage ledger.
+type usageRecord struct {
+	Time         time.Time `json:"time"`
+	Provider     string    `json:"provider"`
+	Model        string    `json:"model,omitempty"`
+	Characters   int       `json:"characters"`
+	AudioSeconds float64   `json:"audio_seconds"`
+	Cost         float64   `json:"cost"`
+	Project      string    `json:"project,omitempty"`
+	Client       string    `json:"client,omitempty"`
+}
+
+// usageLedger appends usage records to a JSONL file.
+type usageLedger struct {
+	mu   sync.Mutex
+	path string
+}
+
+// activeUsageLedger records synthesis calls; nil when disabled.
+var activeUsageLedger *usageLedger
+
+// newUsageLedger opens the ledger selected by --usage-ledger.
+func newUsageLedger(path string) *usageLedger {
+	switch path {
+	case UsageLedgerOff:
+		return nil
+	case "":
+		dir, err := mcpTTSConfigDir()
+		if err != nil {
+			return nil
+		}
+		path = filepath.Join(dir, usageLedgerFile)
+	}
+	return &usageLedger{path: path}
+}
+
+// append writes one record. Each record is a single write to an O_APPEND
+// file, so concurrent servers do not interleave lines.
+func (l *usageLedger) append(rec usageRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// read returns the records at or after since. Unreadable lines are skipped.
+func (l *usageLedger) read(since time.Time) ([]usageRecord, error) {
+	f, err := os.Open(l.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []usageRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec usageRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			log.Debug("Skipping malformed usage record", "path", l.path, "error", err)
+			continue
+		}
+		if !rec.Time.Before(since) {
+			records = append(records, rec)
+		}
+	}
+	return records, scanner.Err()
+}
+
+// sessionClient names the MCP client of a session, e.g. "claude-code 1.0.3".
+func sessionClient(req *mcp.CallToolRequest) string {
+	if req == nil || req.Session == nil {
+		return ""
+	}
+	params := req.Session.InitializeParams()
+	if params == nil || params.ClientInfo == nil {
+		return ""
+	}
+	return strings.TrimSpace(params.ClientInfo.Name + " " + params.ClientInfo.Version)
+}
+
+// recordSynthesis adds a completed synthesis request to the usage ledger.
+// It runs once the provider has produced audio, so failed requests are not
+// counted, while calls cancelled during playback are.
+func recordSynthesis(ctx context.Context, req *mcp.CallToolRequest, providerID, model, profile, text string, audio time.Duration) {
+	if activeUsageLedger == nil {
+		return
+	}
+	var session *mcp.ServerSession
+	if req != nil {
+		session = req.Session
+	}
+	chars := utf8.RuneCountInString(text)
+	rec := usageRecord{
+		Time:         time.Now().UTC(),
+		Provider:     providerKey(providerID),
+		Model:        model,
+		Characters:   chars,
+		AudioSeconds: audio.Seconds(),
+		Cost:         activeConfig.price(providerID, model, profile).cost(chars, audio),
+		Project:      sessionProject(ctx, session),
+		Client:       sessionClient(req),
+	}
+	if err := activeUsageLedger.append(rec); err != nil {
+		log.Warn("Failed to record usage", "path", activeUsageLedger.path, "error", err)
+	}
+}
+
+// usageRow totals the records sharing a grouping key.
+type usageRow struct {
+	Key          string  `json:"key"`
+	Calls        int     `json:"calls"`
+	Characters   int     `json:"characters"`
+	AudioSeconds float64 `json:"audio_seconds"`
+	Cost         float64 `json:"cost"`
+}
+
+func (r *usageRow) add(rec usageRecord) {
+	r.Calls++
+	r.Characters += rec.Characters
+	r.AudioSeconds += rec.AudioSeconds
+	r.Cost += rec.Cost
+}
+
+// summarizeUsage groups records by provider, project, client or local day.
+// Rows are sorted by key, days oldest first.
+func summarizeUsage(records []usageRecord, by string) []usageRow {
+	rows := make(map[string]*usageRow)
+	for _, rec := range records {
+		var key string
+		switch by {
+		case UsageByProvider:
+			key = rec.Provider
+		case UsageByProject:
+			key = rec.Project
+		case UsageByClient:
+			key = rec.Client
+		case UsageByDay:
+			key = rec.Time.Local().Format(time.DateOnly)
+		}
+		if key == "" {
+			key = "unknown"
+		}
+		row := rows[key]
+		if row == nil {
+			row = &usageRow{Key: key}
+			rows[key] = row
+		}
+		row.add(rec)
+	}
+	summary := make([]usageRow, 0, len(rows))
+	for _, row := range rows {
+		summary = append(summary, *row)
+	}
+	slices.SortFunc(summary, func(a, b usageRow) int { return strings.Compare(a.Key, b.Key) })
+	return summary
+}
+
+// parseSince reads a --since value: a number of days ("7d"), a Go duration
+// ("12h") or a date ("2026-01-31").
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q (use e.g. 7d, 12h or 2026-01-31)", value)
+}
+
+// writeUsage prints a usage summary as a table with a total line.
+func writeUsage(w io.Writer, rows []usageRow, by string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\tCALLS\tCHARACTERS\tAUDIO\tEST. COST\n", strings.ToUpper(by))
+	var total usageRow
+	line := func(r usageRow) {
+		audio := time.Duration(r.AudioSeconds * float64(time.Second)).Round(time.Second)
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t$%.2f\n", r.Key, r.Calls, r.Characters, audio, r.Cost)
+	}
+	for _, r := range rows {
+		line(r)
+		total.Calls += r.Calls
+		total.Characters += r.Characters
+		total.AudioSeconds += r.AudioSeconds
+		total.Cost += r.Cost
+	}
+	total.Key = "TOTAL"
+	line(total)
+	return tw.Flush()
+}
+
+// usageSummary is the content of the usage MCP resource.
+type usageSummary struct {
+	Since      time.Time  `json:"since"`
+	Ledger     string     `json:"ledger"`
+	ByProvider []usageRow `json:"by_provider"`
+	ByProject  []usageRow `json:"by_project"`
+	ByClient   []usageRow `json:"by_client"`
+	ByDay      []usageRow `json:"by_day"`
+}
+
+// readUsageResource serves the last 30 days of usage as JSON.
+func readUsageResource(_ context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	if activeUsageLedger == nil {
+		return nil, fmt.Errorf("usage ledger is disabled (--usage-ledger %s)", UsageLedgerOff)
+	}
+	since := time.Now().Add(-usageResourceWindow)
+	records, err := activeUsageLedger.read(since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+	data, err := json.MarshalIndent(usageSummary{
+		Since:      since.UTC(),
+		Ledger:     activeUsageLedger.path,
+		ByProvider: summarizeUsage(records, UsageByProvider),
+		ByProject:  summarizeUsage(records, UsageByProject),
+		ByClient:   summarizeUsage(records, UsageByClient),
+		ByDay:      summarizeUsage(records, UsageByDay),
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{{
+		URI:      req.Params.URI,
+		MIMEType: "application/json",
+		Text:     string(data),
+	}}}, nil
+}
+
+var (
+	usageSince string
+	usageBy    string
+)
+
+// usageCmd summarizes the usage ledger.
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Summarize speech usage and estimated cost",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !slices.Contains(UsageGroupings, usageBy) {
+			return fmt.Errorf("invalid --by %q (supported: %s)", usageBy, strings.Join(UsageGroupings, ", "))
+		}
+		since, err := parseSince(usageSince, time.Now())
+		if err != nil {
+			return err
+		}
+		ledger := newUsageLedger(usageLedgerPath)
+		if ledger == nil {
+			return fmt.Errorf("usage ledger is disabled")
+		}
+		records, err := ledger.read(since)
+		if err != nil {
+			return fmt.Errorf("failed to read usage ledger: %w", err)
+		}
+		return writeUsage(cmd.OutOrStdout(), summarizeUsage(records, usageBy), usageBy)
+	},
+}
+
+func init() {
+	usageCmd.Flags().StringVar(&usageSince, "since", "30d", "Only count usage after this: days (7d), a duration (12h) or a date (2026-01-31)")
+	usageCmd.Flags().StringVar(&usageBy, "by", UsageByProvider, "Group by: provider, project, client, day")
+	rootCmd.AddCommand(usageCmd)
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useTestLedger points the usage ledger at a temporary file.
+func useTestLedger(t *testing.T) *usageLedger {
+	t.Helper()
+	orig := activeUsageLedger
+	t.Cleanup(func() { activeUsageLedger = orig })
+	activeUsageLedger = newUsageLedger(filepath.Join(t.TempDir(), usageLedgerFile))
+	return activeUsageLedger
+}
+
+func TestPrice(t *testing.T) {
+	useTestLimits(t, `
+prices:
+  openai:
+    tts-1: {per_million_chars: 20}
+profiles:
+  cheap:
+    prices:
+      openai:
+        "*": {per_minute: 0.01}
+`)
+
+	assert.Equal(t, modelPrice{PerMillionChars: 20}, activeConfig.price(ProviderOpenAI, "tts-1", ""), "config overrides the builtin price")
+	assert.Equal(t, modelPrice{PerMillionChars: 30}, activeConfig.price(ProviderOpenAI, "tts-1-hd", ""))
+	assert.Equal(t, modelPrice{PerMinute: 0.015}, activeConfig.price(ProviderOpenAI, "gpt-4o-mini-tts", ""))
+	assert.Equal(t, modelPrice{PerMinute: 0.01}, activeConfig.price(ProviderOpenAI, "gpt-4o-mini-tts", "cheap"))
+	assert.Equal(t, modelPrice{PerMillionChars: 30}, activeConfig.price(ProviderOpenAI, "tts-1-hd", "cheap"), "an exact model beats a profile's wildcard")
+	assert.Equal(t, modelPrice{}, activeConfig.price(ProviderSay, "", ""), "say is free")
+
+	assert.InDelta(t, 0.003, modelPrice{PerMillionChars: 300}.cost(10, time.Minute), 1e-9)
+	assert.InDelta(t, 0.0075, modelPrice{PerMinute: 0.015}.cost(10, 30*time.Second), 1e-9)
+
+	t.Run("negative prices are rejected", func(t *testing.T) {
+		_, err := loadConfig(writeTestFile(t, t.TempDir(), configFileName, "prices:\n  openai:\n    tts-1: {per_minute: -1}\n"), "")
+		assert.ErrorContains(t, err, "prices openai tts-1: prices must not be negative")
+	})
+}
+
+func TestRecordSynthesis(t *testing.T) {
+	useTestLimits(t, "")
+	ledger := useTestLedger(t)
+
+	recordSynthesis(context.Background(), nil, ProviderElevenLabs, "eleven_turbo_v2_5", "", "héllo", 2*time.Second)
+	recordSynthesis(context.Background(), nil, ProviderGoogle, DefaultGoogleModel, "", "hello world", time.Minute)
+
+	records, err := ledger.read(time.Time{})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "elevenlabs", records[0].Provider)
+	assert.Equal(t, 5, records[0].Characters, "characters are counted as runes")
+	assert.InDelta(t, 0.0015, records[0].Cost, 1e-9)
+	assert.Equal(t, "google", records[1].Provider)
+	assert.InDelta(t, 60.0, records[1].AudioSeconds, 1e-9)
+	assert.InDelta(t, 0.015, records[1].Cost, 1e-9)
+
+	t.Run("disabled ledger records nothing", func(t *testing.T) {
+		activeUsageLedger = newUsageLedger(UsageLedgerOff)
+		assert.Nil(t, activeUsageLedger)
+		recordSynthesis(context.Background(), nil, ProviderOpenAI, "tts-1", "", "hi", time.Second)
+	})
+}
+
+func TestUsageLedgerRead(t *testing.T) {
+	ledger := &usageLedger{path: filepath.Join(t.TempDir(), usageLedgerFile)}
+
+	records, err := ledger.read(time.Time{})
+	require.NoError(t, err, "a missing ledger is empty")
+	assert.Empty(t, records)
+
+	old := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, ledger.append(usageRecord{Time: old, Provider: "openai"}))
+	f, err := os.OpenFile(ledger.path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString("{not json\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	require.NoError(t, ledger.append(usageRecord{Time: old.Add(48 * time.Hour), Provider: "google"}))
+
+	records, err = ledger.read(old.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, records, 1, "old and malformed records are skipped")
+	assert.Equal(t, "google", records[0].Provider)
+}
+
+func TestSummarizeUsage(t *testing.T) {
+	day := time.Date(2026, 3, 2, 12, 0, 0, 0, time.Local)
+	records := []usageRecord{
+		{Time: day, Provider: "openai", Characters: 100, AudioSeconds: 6, Cost: 0.5, Project: "api", Client: "claude-code 1.0"},
+		{Time: day, Provider: "google", Characters: 50, AudioSeconds: 3, Cost: 0.25, Project: "web"},
+		{Time: day.AddDate(0, 0, 1), Provider: "openai", Characters: 10, AudioSeconds: 1, Cost: 0.25, Project: "api", Client: "claude-code 1.0"},
+	}
+
+	assert.Equal(t, []usageRow{
+		{Key: "google", Calls: 1, Characters: 50, AudioSeconds: 3, Cost: 0.25},
+		{Key: "openai", Calls: 2, Characters: 110, AudioSeconds: 7, Cost: 0.75},
+	}, summarizeUsage(records, UsageByProvider))
+	assert.Equal(t, []string{"claude-code 1.0", "unknown"}, rowKeys(summarizeUsage(records, UsageByClient)))
+	assert.Equal(t, []string{"api", "web"}, rowKeys(summarizeUsage(records, UsageByProject)))
+	assert.Equal(t, []string{"2026-03-02", "2026-03-03"}, rowKeys(summarizeUsage(records, UsageByDay)))
+
+	var out bytes.Buffer
+	require.NoError(t, writeUsage(&out, summarizeUsage(records, UsageByProvider), UsageByProvider))
+	assert.Contains(t, out.String(), "PROVIDER")
+	assert.Regexp(t, `openai\s+2\s+110\s+7s\s+\$0\.75`, out.String())
+	assert.Regexp(t, `TOTAL\s+3\s+160\s+10s\s+\$1\.00`, out.String())
+}
+
+func rowKeys(rows []usageRow) []string {
+	keys := make([]string, len(rows))
+	for i, r := range rows {
+		keys[i] = r.Key
+	}
+	return keys
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.Local)
+	for value, want := range map[string]time.Time{
+		"7d":         now.AddDate(0, 0, -7),
+		"0d":         now,
+		"12h":        now.Add(-12 * time.Hour),
+		"2026-03-01": time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local),
+	} {
+		got, err := parseSince(value, now)
+		require.NoError(t, err, value)
+		assert.True(t, want.Equal(got), "%s: got %s, want %s", value, got, want)
+	}
+	for _, value := range []string{"", "week", "-3d", "-1h"} {
+		_, err := parseSince(value, now)
+		assert.ErrorContains(t, err, "invalid --since", value)
+	}
+}
+
+func TestUsageCommand(t *testing.T) {
+	origPath, origSince, origBy := usageLedgerPath, usageSince, usageBy
+	defer func() { usageLedgerPath, usageSince, usageBy = origPath, origSince, origBy }()
+	usageLedgerPath = filepath.Join(t.TempDir(), usageLedgerFile)
+	ledger := newUsageLedger(usageLedgerPath)
+	require.NoError(t, ledger.append(usageRecord{Time: time.Now(), Provider: "openai", Characters: 42, Project: "mcp-tts"}))
+	require.NoError(t, ledger.append(usageRecord{Time: time.Now().AddDate(0, 0, -10), Provider: "google", Characters: 7}))
+
+	var out bytes.Buffer
+	usageCmd.SetOut(&out)
+	defer usageCmd.SetOut(nil)
+	usageSince, usageBy = "7d", UsageByProject
+	require.NoError(t, usageCmd.RunE(usageCmd, nil))
+	assert.Regexp(t, `mcp-tts\s+1\s+42`, out.String())
+	assert.NotContains(t, out.String(), "unknown", "usage before --since is left out")
+
+	usageBy = "model"
+	assert.ErrorContains(t, usageCmd.RunE(usageCmd, nil), `invalid --by "model"`)
+
+	usageBy, usageLedgerPath = UsageByProvider, UsageLedgerOff
+	assert.ErrorContains(t, usageCmd.RunE(usageCmd, nil), "usage ledger is disabled")
+}
+
+func TestUsageResource(t *testing.T) {
+	ledger := useTestLedger(t)
+	require.NoError(t, ledger.append(usageRecord{Time: time.Now(), Provider: "openai", Characters: 42, Cost: 0.1, Client: "claude-code 1.0"}))
+	require.NoError(t, ledger.append(usageRecord{Time: time.Now().AddDate(0, 0, -40), Provider: "google"}))
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "mcp-tts"}, nil)
+	server.AddResource(&mcp.Resource{URI: usageResourceURI, Name: "usage", MIMEType: "application/json"}, readUsageResource)
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	ctx := context.Background()
+	_, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "test-client"}, nil).Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	result, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: usageResourceURI})
+	require.NoError(t, err)
+	require.Len(t, result.Contents, 1)
+	var summary usageSummary
+	require.NoError(t, json.Unmarshal([]byte(result.Contents[0].Text), &summary))
+	assert.Equal(t, []usageRow{{Key: "openai", Calls: 1, Characters: 42, Cost: 0.1}}, summary.ByProvider, "only the last 30 days are summarized")
+	assert.Equal(t, "claude-code 1.0", summary.ByClient[0].Key)
+}
@@ -0,0 +1,364 @@
+/*
+Copyright © 2025 blacktop
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Voice roles. Each project gets one voice per role so listeners can tell
+// projects and kinds of announcement apart from another room.
+const (
+	voiceRolePlanning = "planning"
+	voiceRoleIssue    = "issue"
+	voiceRoleSummary  = "summary"
+)
+
+// voiceRoles lists the roles assigned for every project.
+var voiceRoles = []string{voiceRolePlanning, voiceRoleIssue, voiceRoleSummary}
+
+// categoryVoiceRoles maps message categories to the voice role they use.
+// Calls without a category use the info role.
+var categoryVoiceRoles = map[string]string{
+	CategoryInfo:     voiceRolePlanning,
+	CategoryQuestion: voiceRolePlanning,
+	CategoryWarning:  voiceRoleIssue,
+	CategoryError:    voiceRoleIssue,
+	CategorySuccess:  voiceRoleSummary,
+	CategorySummary:  voiceRoleSummary,
+}
+
+// voiceAssignmentsFile stores the voices assigned to each project, in the user config directory.
+const voiceAssignmentsFile = "voices.json"
+
+// rootsTimeout bounds the roots/list request made to find a session's project.
+const rootsTimeout = 2 * time.Second
+
+// pooledVoice is a voice that can be assigned to a project.
+type pooledVoice struct {
+	Voice string `json:"voice"`
+}
+
+// voicePools are the voices suited to each role, per provider. Providers
+// without a pool (say, ElevenLabs) keep their configured default voice.
+var voicePools = map[string]map[string][]pooledVoice{
+	ProviderGoogle: {
+		voiceRolePlanning: {{Voice: "Kore"}, {Voice: "Charon"}, {Voice: "Zephyr"}, {Voice: "Orus"}, {Voice: "Puck"}, {Voice: "Fenrir"}, {Voice: "Umbriel"}, {Voice: "Gacrux"}},
+		voiceRoleIssue:    {{Voice: "Aoede"}, {Voice: "Schedar"}, {Voice: "Algenib"}, {Voice: "Alnilam"}, {Voice: "Rasalgethi"}, {Voice: "Sadachbia"}, {Voice: "Achernar"}, {Voice: "Despina"}},
+		voiceRoleSummary:  {{Voice: "Pulcherrima"}, {Voice: "Vindemiatrix"}, {Voice: "Callirrhoe"}, {Voice: "Leda"}, {Voice: "Sulafat"}, {Voice: "Iapetus"}, {Voice: "Achird"}, {Voice: "Laomedeia"}},
+	},
+	ProviderOpenAI: {
+		voiceRolePlanning: {{Voice: "sage"}, {Voice: "onyx"}, {Voice: "echo"}},
+		voiceRoleIssue:    {{Voice: "coral"}, {Voice: "ash"}, {Voice: "ballad"}},
+		voiceRoleSummary:  {{Voice: "nova"}, {Voice: "shimmer"}, {Voice: "alloy"}},
+	},
+}
+
+// Flag to assign each project its own voices (default: true)
+var projectVoices = true
+
+// projectVoiceSet is the voices assigned to one project.
+type projectVoiceSet struct {
+	Name       string                            `json:"name"`
+	AssignedAt time.Time                         `json:"assigned_at"`
+	Voices     map[string]map[string]pooledVoice `json:"voices"` // provider -> role -> voice
+}
+
+// voiceAssignments is the persisted voices.json.
+type voiceAssignments struct {
+	Projects map[string]*projectVoiceSet `json:"projects"`
+}
+
+// voiceStore persists project voice assignments. Assignments are made
+// under a directory lock next to the file, so servers starting in different
+// projects at once see each other's voices.
+type voiceStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// activeVoiceStore is used by the tool handlers; nil when no config directory is available.
+var activeVoiceStore = newVoiceStore()
+
+func newVoiceStore() *voiceStore {
+	dir, err := mcpTTSConfigDir()
+	if err != nil {
+		return nil
+	}
+	return &voiceStore{path: filepath.Join(dir, voiceAssignmentsFile)}
+}
+
+func (s *voiceStore) load() (*voiceAssignments, error) {
+	assignments := &voiceAssignments{Projects: make(map[string]*projectVoiceSet)}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return assignments, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, assignments); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.path, err)
+	}
+	if assignments.Projects == nil {
+		assignments.Projects = make(map[string]*projectVoiceSet)
+	}
+	return assignments, nil
+}
+
+// save writes the assignments atomically so concurrent servers never read a partial file.
+func (s *voiceStore) save(assignments *voiceAssignments) error {
+	data, err := json.MarshalIndent(assignments, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data)
+}
+
+// writeFileAtomic replaces path via a temporary file and rename, creating
+// the parent directory if needed.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// voiceFor returns the project's voice for a provider and role, assigning
+// and persisting voices for every role on first use.
+func (s *voiceStore) voiceFor(ctx context.Context, project, providerID, role string) (pooledVoice, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var voice pooledVoice
+	err := withFileLock(ctx, s.path, func() error {
+		assignments, err := s.load()
+		if err != nil {
+			return err
+		}
+		key := providerKey(providerID)
+		set := assignments.Projects[project]
+		if set != nil {
+			if v, ok := set.Voices[key][role]; ok {
+				voice = v
+				return nil
+			}
+		} else {
+			set = &projectVoiceSet{Name: filepath.Base(project), AssignedAt: time.Now().UTC()}
+			assignments.Projects[project] = set
+		}
+		if set.Voices == nil {
+			set.Voices = make(map[string]map[string]pooledVoice)
+		}
+		voices := make(map[string]pooledVoice)
+		for _, r := range voiceRoles {
+			voices[r] = pickVoice(assignments, project, key, r, voicePools[providerID][r])
+		}
+		set.Voices[key] = voices
+		if err := s.save(assignments); err != nil {
+			return err
+		}
+		log.Debug("Assigned project voices", "project", project, "provider", key, "voices", voices)
+		voice = voices[role]
+		return nil
+	})
+	return voice, err
+}
+
+// pickVoice chooses a voice from pool starting at a position derived from the
+// project path, skipping voices other projects already use for the role.
+// When every voice is taken the derived one is shared.
+func pickVoice(assignments *voiceAssignments, project, key, role string, pool []pooledVoice) pooledVoice {
+	used := make(map[string]bool)
+	for path, set := range assignments.Projects {
+		if path != project {
+			used[set.Voices[key][role].Voice] = true
+		}
+	}
+	h := fnv.New32a()
+	h.Write([]byte(project + "\x00" + role))
+	start := int(h.Sum32() % uint32(len(pool)))
+	for i := range pool {
+		if voice := pool[(start+i)%len(pool)]; !used[voice.Voice] {
+			return voice
+		}
+	}
+	return pool[start]
+}
+
+// sessionProjects caches the project root reported by each session's client.
+var sessionProjects sync.Map // *mcp.ServerSession -> string
+
+// forgetSessionProject drops the cached project when the client's roots change.
+func forgetSessionProject(_ context.Context, req *mcp.RootsListChangedRequest) {
+	sessionProjects.Delete(req.Session)
+}
+
+// forgetSessionProjectOnClose drops a session's cached project once it ends.
+func forgetSessionProjectOnClose(_ context.Context, req *mcp.InitializedRequest) {
+	go func() {
+		req.Session.Wait()
+		sessionProjects.Delete(req.Session)
+	}()
+}
+
+// sessionProject returns the project directory for a session: its first
+// file:// root when the client supports roots, otherwise the working directory.
+func sessionProject(ctx context.Context, session *mcp.ServerSession) string {
+	if session != nil {
+		if dir, ok := sessionProjects.Load(session); ok {
+			return dir.(string)
+		}
+		if dir := rootsProject(ctx, session); dir != "" {
+			sessionProjects.Store(session, dir)
+			return dir
+		}
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	return cwd
+}
+
+// rootsProject asks the client for its roots and returns the first local directory.
+func rootsProject(ctx context.Context, session *mcp.ServerSession) string {
+	if dirs := sessionRoots(ctx, session); len(dirs) > 0 {
+		return dirs[0]
+	}
+	return ""
+}
+
+// sessionRoots asks the client for its roots and returns the local directories.
+func sessionRoots(ctx context.Context, session *mcp.ServerSession) []string {
+	if session == nil {
+		return nil
+	}
+	params := session.InitializeParams()
+	if params == nil || params.Capabilities == nil || params.Capabilities.RootsV2 == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, rootsTimeout)
+	defer cancel()
+	result, err := session.ListRoots(ctx, nil)
+	if err != nil {
+		log.Debug("Failed to list client roots", "error", err)
+		return nil
+	}
+	var dirs []string
+	for _, root := range result.Roots {
+		if dir := rootDir(root.URI); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// rootDir converts a file:// root URI to a local path.
+func rootDir(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" || u.Path == "" {
+		return ""
+	}
+	return filepath.Clean(filepath.FromSlash(u.Path))
+}
+
+// projectVoice returns the project's voice for a call that omitted voice.
+// Voices set in a config file or environment variable take precedence.
+func projectVoice(ctx context.Context, req *mcp.CallToolRequest, providerID, profile string, category *string) (pooledVoice, bool) {
+	role := categoryVoiceRoles[CategoryInfo]
+	if category != nil && *category != "" {
+		role = categoryVoiceRoles[*category]
+	}
+	if !projectVoices || activeVoiceStore == nil || voicePools[providerID] == nil {
+		return pooledVoice{}, false
+	}
+	if _, sources := activeConfig.providerSettings(providerID, profile); sources["voice"] != sourceDefault {
+		return pooledVoice{}, false
+	}
+	var session *mcp.ServerSession
+	if req != nil {
+		session = req.Session
+	}
+	project := sessionProject(ctx, session)
+	if project == "" {
+		return pooledVoice{}, false
+	}
+	voice, err := activeVoiceStore.voiceFor(ctx, project, providerID, role)
+	if err != nil {
+		log.Warn("Failed to assign project voice", "project", project, "error", err)
+		return pooledVoice{}, false
+	}
+	return voice, true
+}
+
+// applyProjectVoice fills an omitted voice from the project's voices.
+func applyProjectVoice(ctx context.Context, req *mcp.CallToolRequest, providerID, profile string, category *string, voice **string) {
+	if *voice != nil {
+		return
+	}
+	if assigned, ok := projectVoice(ctx, req, providerID, profile, category); ok {
+		*voice = &assigned.Voice
+	}
+}
+
+// withProjectVoice adds the project's voice to elicited settings that left it
+// unset, so tts recommendations carry the project voice.
+func withProjectVoice(ctx context.Context, req *mcp.CallToolRequest, providerID, profile string, category *string, content map[string]any) map[string]any {
+	if elicitString(content, "voice") != "" {
+		return content
+	}
+	assigned, ok := projectVoice(ctx, req, providerID, profile, category)
+	if !ok {
+		return content
+	}
+	merged := make(map[string]any, len(content)+1)
+	maps.Copy(merged, content)
+	merged["voice"] = assigned.Voice
+	return merged
+}
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func useTestVoiceStore(t *testing.T) *voiceStore {
+	t.Helper()
+	oldStore, oldConfig, oldEnabled := activeVoiceStore, activeConfig, projectVoices
+	t.Cleanup(func() {
+		activeVoiceStore, activeConfig, projectVoices = oldStore, oldConfig, oldEnabled
+	})
+	activeVoiceStore = &voiceStore{path: filepath.Join(t.TempDir(), voiceAssignmentsFile)}
+	activeConfig = &appConfig{}
+	projectVoices = true
+	return activeVoiceStore
+}
+
+func TestVoiceStoreAssignments(t *testing.T) {
+	store := useTestVoiceStore(t)
+
+	planning, err := store.voiceFor(context.Background(), "/src/alpha", ProviderGoogle, voiceRolePlanning)
+	require.NoError(t, err)
+	assert.Contains(t, voicePools[ProviderGoogle][voiceRolePlanning], planning)
+
+	again, err := store.voiceFor(context.Background(), "/src/alpha", ProviderGoogle, voiceRolePlanning)
+	require.NoError(t, err)
+	assert.Equal(t, planning, again, "assignments are stable")
+
+	saved, err := store.load()
+	require.NoError(t, err)
+	require.Contains(t, saved.Projects, "/src/alpha")
+	assert.Equal(t, "alpha", saved.Projects["/src/alpha"].Name)
+	assert.Len(t, saved.Projects["/src/alpha"].Voices["google"], len(voiceRoles), "every role is assigned at once")
+
+	t.Run("projects get distinct voices while the pool lasts", func(t *testing.T) {
+		seen := map[string]bool{planning.Voice: true}
+		for _, project := range []string{"/src/beta", "/src/gamma", "/src/delta"} {
+			voice, err := store.voiceFor(context.Background(), project, ProviderGoogle, voiceRolePlanning)
+			require.NoError(t, err)
+			assert.False(t, seen[voice.Voice], "voice %s reused for %s", voice.Voice, project)
+			seen[voice.Voice] = true
+		}
+	})
+
+	t.Run("assignment is deterministic", func(t *testing.T) {
+		other := &voiceStore{path: filepath.Join(t.TempDir(), voiceAssignmentsFile)}
+		voice, err := other.voiceFor(context.Background(), "/src/alpha", ProviderGoogle, voiceRolePlanning)
+		require.NoError(t, err)
+		assert.Equal(t, planning, voice)
+	})
+
+	t.Run("roles are assigned per provider", func(t *testing.T) {
+		voice, err := store.voiceFor(context.Background(), "/src/alpha", ProviderOpenAI, voiceRoleIssue)
+		require.NoError(t, err)
+		assert.Contains(t, voicePools[ProviderOpenAI][voiceRoleIssue], voice)
+	})
+
+	t.Run("servers sharing the file keep each other's assignments", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), voiceAssignmentsFile)
+		stores := []*voiceStore{{path: path}, {path: path}}
+		projects := []string{"/src/one", "/src/two", "/src/three", "/src/four"}
+
+		var wg sync.WaitGroup
+		for i, project := range projects {
+			wg.Go(func() {
+				_, err := stores[i%len(stores)].voiceFor(context.Background(), project, ProviderGoogle, voiceRolePlanning)
+				assert.NoError(t, err)
+			})
+		}
+		wg.Wait()
+
+		saved, err := stores[0].load()
+		require.NoError(t, err)
+		seen := map[string]bool{}
+		for _, project := range projects {
+			require.Contains(t, saved.Projects, project)
+			voice := saved.Projects[project].Voices["google"][voiceRolePlanning].Voice
+			assert.False(t, seen[voice], "voice %s reused for %s", voice, project)
+			seen[voice] = true
+		}
+	})
+}
+
+func TestPickVoiceSharesWhenPoolExhausted(t *testing.T) {
+	pool := []pooledVoice{{Voice: "a"}, {Voice: "b"}}
+	assignments := &voiceAssignments{Projects: map[string]*projectVoiceSet{
+		"/x": {Voices: map[string]map[string]pooledVoice{"google": {voiceRolePlanning: {Voice: "a"}}}},
+		"/y": {Voices: map[string]map[string]pooledVoice{"google": {voiceRolePlanning: {Voice: "b"}}}},
+	}}
+	voice := pickVoice(assignments, "/z", "google", voiceRolePlanning, pool)
+	assert.Contains(t, pool, voice)
+}
+
+func TestApplyProjectVoice(t *testing.T) {
+	useTestVoiceStore(t)
+	ctx := context.Background()
+
+	var voice *string
+	applyProjectVoice(ctx, nil, ProviderOpenAI, "", stringPtr(CategorySuccess), &voice)
+	require.NotNil(t, voice)
+	assert.Contains(t, voicePools[ProviderOpenAI][voiceRoleSummary], pooledVoice{Voice: *voice})
+
+	var summary *string
+	applyProjectVoice(ctx, nil, ProviderOpenAI, "", stringPtr(CategorySummary), &summary)
+	assert.Equal(t, *voice, *summary, "categories sharing a role share the voice")
+
+	t.Run("explicit voices are kept", func(t *testing.T) {
+		voice := stringPtr("echo")
+		applyProjectVoice(ctx, nil, ProviderOpenAI, "", stringPtr(CategoryError), &voice)
+		assert.Equal(t, "echo", *voice)
+	})
+
+	t.Run("calls without a category use the info voice", func(t *testing.T) {
+		var voice, info *string
+		applyProjectVoice(ctx, nil, ProviderOpenAI, "", nil, &voice)
+		applyProjectVoice(ctx, nil, ProviderOpenAI, "", stringPtr(CategoryInfo), &info)
+		require.NotNil(t, voice)
+		assert.Equal(t, *info, *voice)
+	})
+
+	t.Run("providers without a pool are skipped", func(t *testing.T) {
+		var voice *string
+		applyProjectVoice(ctx, nil, ProviderSay, "", stringPtr(CategoryError), &voice)
+		assert.Nil(t, voice)
+	})
+
+	t.Run("configured voices win", func(t *testing.T) {
+		dir := t.TempDir()
+		cfg, err := loadConfig(writeTestFile(t, dir, configFileName, "providers:\n  google:\n    voice: Puck\n"), "")
+		require.NoError(t, err)
+		activeConfig = cfg
+		defer func() { activeConfig = &appConfig{} }()
+
+		var voice *string
+		applyProjectVoice(ctx, nil, ProviderGoogle, "", stringPtr(CategoryError), &voice)
+		assert.Nil(t, voice)
+	})
+
+	t.Run("disabled by flag", func(t *testing.T) {
+		projectVoices = false
+		defer func() { projectVoices = true }()
+		var voice *string
+		applyProjectVoice(ctx, nil, ProviderGoogle, "", stringPtr(CategoryError), &voice)
+		assert.Nil(t, voice)
+	})
+}
+
+func TestWithProjectVoice(t *testing.T) {
+	useTestVoiceStore(t)
+
+	content := withProjectVoice(context.Background(), nil, ProviderOpenAI, "", stringPtr(CategoryError), nil)
+	require.Contains(t, content, "voice")
+	args := providerRecommendationArgs(ProviderOpenAI, "hello", content)
+	assert.Equal(t, content["voice"], args["voice"])
+
+	chosen := map[string]any{"voice": "echo"}
+	assert.Equal(t, chosen, withProjectVoice(context.Background(), nil, ProviderOpenAI, "", stringPtr(CategoryError), chosen))
+}
+
+func TestSessionProjectFallsBackToWorkingDirectory(t *testing.T) {
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+	assert.Equal(t, cwd, sessionProject(context.Background(), nil))
+}
+
+func TestRootDir(t *testing.T) {
+	assert.Equal(t, filepath.FromSlash("/Users/me/src/app"), rootDir("file:///Users/me/src/app/"))
+	assert.Equal(t, filepath.FromSlash("/tmp/my project"), rootDir("file:///tmp/my%20project"))
+	assert.Empty(t, rootDir("https://example.com/repo"))
+}
+
+func TestSessionProjectForgottenOnClose(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "mcp-tts"}, &mcp.ServerOptions{InitializedHandler: forgetSessionProjectOnClose})
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(context.Background(), serverTransport, nil)
+	require.NoError(t, err)
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client"}, nil)
+	dir := t.TempDir()
+	client.AddRoots(&mcp.Root{URI: "file://" + filepath.ToSlash(dir)})
+	session, err := client.Connect(context.Background(), clientTransport, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, dir, sessionProject(context.Background(), serverSession))
+	_, cached := sessionProjects.Load(serverSession)
+	require.True(t, cached)
+
+	require.NoError(t, session.Close())
+	assert.Eventually(t, func() bool {
+		_, cached := sessionProjects.Load(serverSession)
+		return !cached
+	}, time.Second, 10*time.Millisecond)
+}
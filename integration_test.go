@@ -560,6 +560,8 @@ func interactiveTTSTextResult(t *testing.T, replies []elicitationReply) string {
 
 	t.Setenv("GOOGLE_AI_API_KEY", "test-google-api-key")
 	t.Setenv("OPENAI_API_KEY", "test-openai-api-key")
+	// Recommend the stock default voices rather than this checkout's project voice
+	t.Setenv("MCP_TTS_PROJECT_VOICES", "false")
 
 	initID, initParams, toolCallID, name, args := interactiveTTSToolCallFromFixture(t)
 